@@ -0,0 +1,84 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// defaultFixtures 埋め込みのフィクスチャ（福岡の駅・神社・寺・公園・博物館・飲食店など）
+//
+//go:embed fixtures/places.json
+var defaultFixtures []byte
+
+// fixturePlace フィクスチャの場所
+type fixturePlace struct {
+	PlaceID          string          `json:"place_id"`
+	Name             string          `json:"name"`
+	Aliases          []string        `json:"aliases,omitempty"` // Text Searchで一致させる別名（英語名など）
+	Lat              float64         `json:"lat"`
+	Lng              float64         `json:"lng"`
+	Types            []string        `json:"types"`
+	Rating           float64         `json:"rating"`
+	UserRatingsTotal int             `json:"user_ratings_total"`
+	Address          string          `json:"address"`
+	Reviews          []fixtureReview `json:"reviews,omitempty"`
+	Hours            *fixtureHours   `json:"hours,omitempty"` // 省略した場合は営業時間の情報なし
+}
+
+// fixtureReview フィクスチャのレビュー
+type fixtureReview struct {
+	Text   string `json:"text"`
+	Rating int    `json:"rating"`
+}
+
+// fixtureHours フィクスチャの営業時間（定休日以外は毎日同じ時間）
+// closeが"2400"以上の場合は翌日の閉店時刻を表す（"2530"は翌日1:30）
+type fixtureHours struct {
+	AlwaysOpen bool   `json:"always_open,omitempty"`
+	Open       string `json:"open,omitempty"`        // "HHMM"形式
+	Close      string `json:"close,omitempty"`       // "HHMM"形式
+	ClosedDays []int  `json:"closed_days,omitempty"` // 定休日（0=日曜日）
+}
+
+// photoReference 場所の写真参照（place_idから決まる）
+func (p *fixturePlace) photoReference() string {
+	return "photo_" + p.PlaceID
+}
+
+// loadPlaces フィクスチャを読み込む（pathが空の場合は埋め込みのフィクスチャ）
+func loadPlaces(path string) ([]fixturePlace, error) {
+	data := defaultFixtures
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read fixtures: %w", err)
+		}
+	}
+
+	var places []fixturePlace
+	if err := json.Unmarshal(data, &places); err != nil {
+		return nil, fmt.Errorf("failed to parse fixtures: %w", err)
+	}
+
+	seen := make(map[string]bool, len(places))
+	for i, p := range places {
+		if p.PlaceID == "" || p.Name == "" {
+			return nil, fmt.Errorf("fixture %d: place_id and name are required", i)
+		}
+		if seen[p.PlaceID] {
+			return nil, fmt.Errorf("fixture %d: duplicate place_id: %s", i, p.PlaceID)
+		}
+		seen[p.PlaceID] = true
+		if h := p.Hours; h != nil && !h.AlwaysOpen {
+			if _, err := parseHHMM(h.Open); err != nil {
+				return nil, fmt.Errorf("fixture %s: %w", p.PlaceID, err)
+			}
+			if _, err := parseHHMM(h.Close); err != nil {
+				return nil, fmt.Errorf("fixture %s: %w", p.PlaceID, err)
+			}
+		}
+	}
+	return places, nil
+}
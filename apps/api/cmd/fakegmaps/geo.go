@@ -0,0 +1,33 @@
+package main
+
+import (
+	"math"
+)
+
+// roadFactor 直線距離に対する道のりの比率
+const roadFactor = 1.3
+
+// travelSpeeds 移動手段ごとの平均速度（m/s）
+var travelSpeeds = map[string]float64{
+	"DRIVE":       30 / 3.6,
+	"TWO_WHEELER": 27 / 3.6,
+	"BICYCLE":     15 / 3.6,
+	"WALK":        4.8 / 3.6,
+	"TRANSIT":     22 / 3.6,
+}
+
+// haversine 2点間の直線距離（メートル）
+func haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadius = 6371000.0
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLng := (lng2 - lng1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return earthRadius * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// travel 2点間の道のり（メートル）と所要時間（秒）を直線距離から見積もる
+func travel(lat1, lng1, lat2, lng2 float64, travelMode string) (distanceMeters int, seconds int) {
+	distance := haversine(lat1, lng1, lat2, lng2) * roadFactor
+	return int(math.Round(distance)), int(math.Ceil(distance / travelSpeeds[travelMode]))
+}
@@ -0,0 +1,84 @@
+// Command fakegmaps Google Maps Platform（Places API・Routes API）のローカルの偽サーバー
+//
+// 福岡の場所のフィクスチャから Text Search・Nearby Search・Place Details・Place Photo に応答し、
+// computeRoutes・computeRouteMatrix は直線距離（ハバーサイン距離）から距離・所要時間を計算する。
+// 経由地順の最適化もこのサーバー内で行うため、APIキーやネットワークなしでAPIサーバー全体を動かせる。
+//
+// APIサーバー側では次のように設定する（GOOGLE_MAPS_API_KEYは空でなければ任意の値でよい）。
+//
+//	GOOGLE_MAPS_API_KEY=fake
+//	GOOGLE_PLACES_BASE_URL=http://localhost:8090/maps/api/place
+//	GOOGLE_ROUTES_BASE_URL=http://localhost:8090
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+func main() {
+	// 待ち受けるアドレス（FAKEGMAPS_ADDR、デフォルト: :8090）
+	addr := os.Getenv("FAKEGMAPS_ADDR")
+	if addr == "" {
+		addr = ":8090"
+	}
+
+	// フィクスチャのパス（FAKEGMAPS_FIXTURES、未設定の場合は埋め込みのフィクスチャ）
+	places, err := loadPlaces(os.Getenv("FAKEGMAPS_FIXTURES"))
+	if err != nil {
+		log.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	// Nearby Searchの1ページの件数（FAKEGMAPS_PAGE_SIZE、デフォルト: 20）
+	// 小さくするとフィクスチャが少なくてもページングを確認できる
+	pageSize := maxPageSize
+	if v := os.Getenv("FAKEGMAPS_PAGE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxPageSize {
+			log.Fatalf("FAKEGMAPS_PAGE_SIZE must be an integer between 1 and %d: %s", maxPageSize, v)
+		}
+		pageSize = n
+	}
+
+	// next_page_tokenが有効になるまでの時間（FAKEGMAPS_PAGE_TOKEN_DELAY、デフォルト: 0s）
+	// Googleと同様に、有効になる前のトークンにはINVALID_REQUESTを返す
+	var tokenDelay time.Duration
+	if v := os.Getenv("FAKEGMAPS_PAGE_TOKEN_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Fatalf("FAKEGMAPS_PAGE_TOKEN_DELAY must be a non-negative duration (e.g. \"2s\"): %s", v)
+		}
+		tokenDelay = d
+	}
+
+	s := newServer(places, pageSize, tokenDelay)
+
+	log.Printf("Fake Google Maps server starting on %s (%d places)", addr, len(places))
+	if err := http.ListenAndServe(addr, logRequests(s.handler())); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
+
+// handler Places API・Routes APIのエンドポイントを登録したハンドラーを返す
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/maps/api/place/textsearch/json", s.textSearch)
+	mux.HandleFunc("/maps/api/place/nearbysearch/json", s.nearbySearch)
+	mux.HandleFunc("/maps/api/place/details/json", s.placeDetails)
+	mux.HandleFunc("/maps/api/place/photo", s.placePhoto)
+	mux.HandleFunc("/directions/v2:computeRoutes", s.computeRoutes)
+	mux.HandleFunc("/distanceMatrix/v2:computeRouteMatrix", s.computeRouteMatrix)
+	return mux
+}
+
+// logRequests リクエストごとにメソッド・パス・処理時間をログに出力する
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		log.Printf("%s %s (%s)", r.Method, r.URL.Path, time.Since(start).Round(time.Microsecond))
+	})
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fukuoka-ai-api/infra/service"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tickingClock 呼び出すたびに1秒進む時計（トークンの発行から有効になるまでをリクエスト数で制御する）
+type tickingClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *tickingClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(time.Second)
+	return c.now
+}
+
+func TestNearbySearchPagination(t *testing.T) {
+	t.Setenv("GOOGLE_MAPS_API_KEY", "fake")
+	places, err := loadPlaces("")
+	if err != nil {
+		t.Fatalf("loadPlaces: %v", err)
+	}
+
+	// 埋め込みのフィクスチャのtourist_attractionは21件
+	tests := []struct {
+		name              string
+		pageSize          int
+		tokenDelay        time.Duration // 偽サーバーの時計は1リクエストごとに1秒進む
+		pagination        service.NearbyPaginationConfig
+		maxPages          int
+		want              int
+		wantTokenRequests int
+		wantErr           bool
+	}{
+		{
+			name:              "指定したページ数までnext_page_tokenをたどる",
+			pageSize:          5,
+			pagination:        service.NearbyPaginationConfig{DefaultPages: 1, MaxPages: 3},
+			maxPages:          3,
+			want:              15,
+			wantTokenRequests: 2,
+		},
+		{
+			name:              "ページ数の指定がない場合は既定値",
+			pageSize:          5,
+			pagination:        service.NearbyPaginationConfig{DefaultPages: 2, MaxPages: 3},
+			want:              10,
+			wantTokenRequests: 1,
+		},
+		{
+			name:              "設定の上限を超えるページ数は上限まで",
+			pageSize:          5,
+			pagination:        service.NearbyPaginationConfig{DefaultPages: 1, MaxPages: 2},
+			maxPages:          10,
+			want:              10,
+			wantTokenRequests: 1,
+		},
+		{
+			name:              "next_page_tokenがなくなったら止める",
+			pageSize:          20,
+			pagination:        service.NearbyPaginationConfig{DefaultPages: 1, MaxPages: 3},
+			maxPages:          3,
+			want:              21,
+			wantTokenRequests: 1,
+		},
+		{
+			name:              "有効になる前のトークン（INVALID_REQUEST）は待ち直す",
+			pageSize:          5,
+			tokenDelay:        2 * time.Second,
+			pagination:        service.NearbyPaginationConfig{DefaultPages: 1, MaxPages: 3},
+			maxPages:          3,
+			want:              15,
+			wantTokenRequests: 4,
+		},
+		{
+			name:              "待ち直しても有効にならない場合はエラー",
+			pageSize:          5,
+			tokenDelay:        10 * time.Second,
+			pagination:        service.NearbyPaginationConfig{DefaultPages: 1, MaxPages: 3},
+			maxPages:          3,
+			wantTokenRequests: 3,
+			wantErr:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(places, tt.pageSize, tt.tokenDelay)
+			clock := &tickingClock{now: time.Date(2025, 4, 5, 10, 0, 0, 0, time.UTC)}
+			s.now = clock.Now
+
+			var mu sync.Mutex
+			tokenRequests := 0
+			handler := s.handler()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Has("pagetoken") {
+					mu.Lock()
+					tokenRequests++
+					mu.Unlock()
+				}
+				handler.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			upstream := service.NewUpstream("places", "", time.Second, service.ResilienceConfig{MaxAttempts: 1, FailureThreshold: 10, OpenDuration: time.Second}, nil)
+			svc := service.NewNearbySearchService(upstream, server.URL+"/maps/api/place", 1, tt.pagination, nil)
+			results, err := svc.SearchNearby(context.Background(), 33.59, 130.40, maxNearbyRadius, []string{"tourist_attraction"}, tt.maxPages)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SearchNearby = %d results, want error", len(results))
+				}
+			} else if err != nil {
+				t.Fatalf("SearchNearby: %v", err)
+			}
+			if len(results) != tt.want {
+				t.Errorf("len(results) = %d, want %d", len(results), tt.want)
+			}
+			seen := make(map[string]bool)
+			for _, r := range results {
+				if seen[r.PlaceID] {
+					t.Errorf("duplicate place %s", r.PlaceID)
+				}
+				seen[r.PlaceID] = true
+			}
+			if tokenRequests != tt.wantTokenRequests {
+				t.Errorf("pagetoken requests = %d, want %d", tokenRequests, tt.wantTokenRequests)
+			}
+		})
+	}
+
+	// 待ち直しの途中でキャンセルされた場合はキャンセルのエラーを返す
+	s := newServer(places, 5, time.Hour)
+	server := httptest.NewServer(s.handler())
+	defer server.Close()
+	upstream := service.NewUpstream("places", "", time.Second, service.ResilienceConfig{MaxAttempts: 1, FailureThreshold: 10, OpenDuration: time.Second}, nil)
+	svc := service.NewNearbySearchService(upstream, server.URL+"/maps/api/place", 1, service.NearbyPaginationConfig{DefaultPages: 1, MaxPages: 3, TokenDelay: time.Hour}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := svc.SearchNearby(ctx, 33.59, 130.40, maxNearbyRadius, []string{"tourist_attraction"}, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SearchNearby error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
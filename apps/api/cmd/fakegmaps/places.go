@@ -0,0 +1,513 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPageSize Nearby Searchの1ページの最大件数（Googleと同じ）
+const maxPageSize = 20
+
+// maxNearbyResults Nearby Searchで取得できる最大件数（3ページ分、Googleと同じ）
+const maxNearbyResults = 60
+
+// maxNearbyRadius Nearby Searchの半径の上限（メートル）
+const maxNearbyRadius = 50000
+
+// server 偽サーバーの状態
+type server struct {
+	places     []fixturePlace
+	byID       map[string]*fixturePlace
+	byPhoto    map[string]*fixturePlace
+	pageSize   int
+	tokenDelay time.Duration
+	now        func() time.Time
+}
+
+// newServer 新しいserverを作成
+func newServer(places []fixturePlace, pageSize int, tokenDelay time.Duration) *server {
+	s := &server{
+		places:     places,
+		byID:       make(map[string]*fixturePlace, len(places)),
+		byPhoto:    make(map[string]*fixturePlace, len(places)),
+		pageSize:   pageSize,
+		tokenDelay: tokenDelay,
+		now:        time.Now,
+	}
+	for i := range places {
+		s.byID[places[i].PlaceID] = &places[i]
+		s.byPhoto[places[i].photoReference()] = &places[i]
+	}
+	return s
+}
+
+// placesLocation Places APIのレスポンスの座標
+type placesLocation struct {
+	Location struct {
+		Lat float64 `json:"lat"`
+		Lng float64 `json:"lng"`
+	} `json:"location"`
+}
+
+// placesPhoto Places APIのレスポンスの写真
+type placesPhoto struct {
+	PhotoReference string `json:"photo_reference"`
+	Width          int    `json:"width"`
+	Height         int    `json:"height"`
+}
+
+// placesResult Text Search・Nearby Searchの結果の1件
+type placesResult struct {
+	PlaceID          string         `json:"place_id"`
+	Name             string         `json:"name"`
+	Geometry         placesLocation `json:"geometry"`
+	FormattedAddress string         `json:"formatted_address,omitempty"` // Text Searchのみ
+	Vicinity         string         `json:"vicinity,omitempty"`          // Nearby Searchのみ
+	Types            []string       `json:"types"`
+	Rating           float64        `json:"rating,omitempty"`
+	UserRatingsTotal int            `json:"user_ratings_total,omitempty"`
+	Photos           []placesPhoto  `json:"photos,omitempty"`
+}
+
+// placesListResponse Text Search・Nearby Searchのレスポンス
+type placesListResponse struct {
+	Status        string         `json:"status"`
+	Results       []placesResult `json:"results"`
+	NextPageToken string         `json:"next_page_token,omitempty"`
+	ErrorMessage  string         `json:"error_message,omitempty"`
+}
+
+// nearbyQuery Nearby Searchの検索条件（next_page_tokenに埋め込む）
+type nearbyQuery struct {
+	Lat      float64   `json:"lat"`
+	Lng      float64   `json:"lng"`
+	Radius   float64   `json:"radius"`
+	Type     string    `json:"type,omitempty"`
+	Keyword  string    `json:"keyword,omitempty"`
+	Offset   int       `json:"offset"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// textSearch Text Search（場所名・別名の部分一致で検索し、完全一致を優先する）
+func (s *server) textSearch(w http.ResponseWriter, r *http.Request) {
+	if !hasPlacesKey(w, r) {
+		return
+	}
+	// APIサーバーは「<場所名> 福岡」の形式で検索する
+	query := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(r.URL.Query().Get("query")), "福岡"))
+	if query == "" {
+		writePlacesStatus(w, "INVALID_REQUEST", "query is required")
+		return
+	}
+
+	type match struct {
+		place *fixturePlace
+		exact bool
+	}
+	var matches []match
+	for i := range s.places {
+		p := &s.places[i]
+		if exact, ok := matchName(p, query); ok {
+			matches = append(matches, match{place: p, exact: exact})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].exact != matches[j].exact {
+			return matches[i].exact
+		}
+		return matches[i].place.UserRatingsTotal > matches[j].place.UserRatingsTotal
+	})
+
+	resp := placesListResponse{Status: "OK", Results: []placesResult{}}
+	for _, m := range matches {
+		result := toPlacesResult(m.place)
+		result.FormattedAddress = m.place.Address
+		resp.Results = append(resp.Results, result)
+	}
+	if len(resp.Results) == 0 {
+		resp.Status = "ZERO_RESULTS"
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// matchName 場所名・別名がqueryと一致するかを返す（exactは完全一致かどうか）
+func matchName(p *fixturePlace, query string) (exact bool, ok bool) {
+	q := strings.ToLower(query)
+	for _, name := range append([]string{p.Name}, p.Aliases...) {
+		n := strings.ToLower(name)
+		if n == q {
+			return true, true
+		}
+		if strings.Contains(n, q) || strings.Contains(q, n) {
+			ok = true
+		}
+	}
+	return false, ok
+}
+
+// nearbySearch Nearby Search（半径内でtype・keywordに一致する場所を評価と評価数の順に返す）
+func (s *server) nearbySearch(w http.ResponseWriter, r *http.Request) {
+	if !hasPlacesKey(w, r) {
+		return
+	}
+
+	params := r.URL.Query()
+	var q nearbyQuery
+	if token := params.Get("pagetoken"); token != "" {
+		var err error
+		if q, err = decodePageToken(token); err != nil || s.now().Before(q.IssuedAt.Add(s.tokenDelay)) {
+			// Googleと同様に、無効なトークンと有効になる前のトークンを区別しない
+			writePlacesStatus(w, "INVALID_REQUEST", "invalid or not yet valid pagetoken")
+			return
+		}
+	} else {
+		var err error
+		if q, err = parseNearbyQuery(params); err != nil {
+			writePlacesStatus(w, "INVALID_REQUEST", err.Error())
+			return
+		}
+	}
+
+	var matches []*fixturePlace
+	for i := range s.places {
+		p := &s.places[i]
+		if haversine(q.Lat, q.Lng, p.Lat, p.Lng) <= q.Radius && matchNearby(p, q) {
+			matches = append(matches, p)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return prominence(matches[i]) > prominence(matches[j])
+	})
+	if len(matches) > maxNearbyResults {
+		matches = matches[:maxNearbyResults]
+	}
+
+	resp := placesListResponse{Status: "OK", Results: []placesResult{}}
+	end := min(q.Offset+s.pageSize, len(matches))
+	for _, p := range matches[min(q.Offset, len(matches)):end] {
+		result := toPlacesResult(p)
+		result.Vicinity = p.Address
+		resp.Results = append(resp.Results, result)
+	}
+	if end < len(matches) {
+		next := q
+		next.Offset = end
+		next.IssuedAt = s.now()
+		resp.NextPageToken = encodePageToken(next)
+	}
+	if len(resp.Results) == 0 {
+		resp.Status = "ZERO_RESULTS"
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseNearbyQuery Nearby Searchのクエリパラメーターを検索条件に変換する
+func parseNearbyQuery(params url.Values) (nearbyQuery, error) {
+	get := params.Get
+
+	var q nearbyQuery
+	lat, lng, found := strings.Cut(get("location"), ",")
+	if !found {
+		return q, fmt.Errorf("location must be \"lat,lng\"")
+	}
+	var err error
+	if q.Lat, err = strconv.ParseFloat(strings.TrimSpace(lat), 64); err != nil {
+		return q, fmt.Errorf("invalid location: %s", get("location"))
+	}
+	if q.Lng, err = strconv.ParseFloat(strings.TrimSpace(lng), 64); err != nil {
+		return q, fmt.Errorf("invalid location: %s", get("location"))
+	}
+	if q.Radius, err = strconv.ParseFloat(get("radius"), 64); err != nil || q.Radius <= 0 || q.Radius > maxNearbyRadius {
+		return q, fmt.Errorf("radius must be between 1 and %d: %s", maxNearbyRadius, get("radius"))
+	}
+	q.Type = get("type")
+	q.Keyword = get("keyword")
+	return q, nil
+}
+
+// matchNearby 場所がtype・keywordの条件に一致するかを返す（指定されていない条件は一致とみなす）
+func matchNearby(p *fixturePlace, q nearbyQuery) bool {
+	if q.Type != "" && !contains(p.Types, q.Type) {
+		return false
+	}
+	if q.Keyword == "" {
+		return true
+	}
+	keyword := strings.ToLower(q.Keyword)
+	texts := append([]string{p.Name, p.Address}, p.Aliases...)
+	texts = append(texts, p.Types...)
+	for _, review := range p.Reviews {
+		texts = append(texts, review.Text)
+	}
+	for _, text := range texts {
+		if strings.Contains(strings.ToLower(text), keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// prominence 検索結果の並び順に使用する知名度（評価×評価数の対数）
+func prominence(p *fixturePlace) float64 {
+	return p.Rating * math.Log10(float64(p.UserRatingsTotal)+10)
+}
+
+// encodePageToken 検索条件をnext_page_tokenに変換する
+func encodePageToken(q nearbyQuery) string {
+	data, _ := json.Marshal(q)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageToken next_page_tokenを検索条件に変換する
+func decodePageToken(token string) (nearbyQuery, error) {
+	var q nearbyQuery
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return q, err
+	}
+	err = json.Unmarshal(data, &q)
+	return q, err
+}
+
+// placeDetailsResponse Place Detailsのレスポンス
+type placeDetailsResponse struct {
+	Status       string              `json:"status"`
+	Result       *placeDetailsResult `json:"result,omitempty"`
+	ErrorMessage string              `json:"error_message,omitempty"`
+}
+
+// placeDetailsResult Place Detailsの結果
+type placeDetailsResult struct {
+	placesResult
+	FormattedAddress string              `json:"formatted_address"`
+	Reviews          []placesReview      `json:"reviews,omitempty"`
+	OpeningHours     *placesOpeningHours `json:"opening_hours,omitempty"`
+}
+
+// placesReview Place Detailsのレビュー
+type placesReview struct {
+	AuthorName string `json:"author_name"`
+	Rating     int    `json:"rating"`
+	Text       string `json:"text"`
+}
+
+// placesOpeningHours Place Detailsの営業時間
+type placesOpeningHours struct {
+	Periods     []placesOpeningPeriod `json:"periods"`
+	WeekdayText []string              `json:"weekday_text"`
+}
+
+// placesOpeningPeriod Place Detailsの営業時間帯（24時間営業の場合はcloseを省略する）
+type placesOpeningPeriod struct {
+	Open  placesOpeningTime  `json:"open"`
+	Close *placesOpeningTime `json:"close,omitempty"`
+}
+
+// placesOpeningTime Place Detailsの営業時間の開始・終了（dayは0=日曜日、timeは"HHMM"形式）
+type placesOpeningTime struct {
+	Day  int    `json:"day"`
+	Time string `json:"time"`
+}
+
+// placeDetails Place Details（fieldsの指定にかかわらず全フィールドを返す）
+func (s *server) placeDetails(w http.ResponseWriter, r *http.Request) {
+	if !hasPlacesKey(w, r) {
+		return
+	}
+	placeID := r.URL.Query().Get("place_id")
+	if placeID == "" {
+		writePlacesStatus(w, "INVALID_REQUEST", "place_id is required")
+		return
+	}
+	p, ok := s.byID[placeID]
+	if !ok {
+		writePlacesStatus(w, "NOT_FOUND", "place_id was not found: "+placeID)
+		return
+	}
+
+	result := &placeDetailsResult{
+		placesResult:     toPlacesResult(p),
+		FormattedAddress: p.Address,
+		OpeningHours:     toOpeningHours(p.Hours),
+	}
+	for i, review := range p.Reviews {
+		result.Reviews = append(result.Reviews, placesReview{
+			AuthorName: fmt.Sprintf("レビュアー%d", i+1),
+			Rating:     review.Rating,
+			Text:       review.Text,
+		})
+	}
+	writeJSON(w, http.StatusOK, placeDetailsResponse{Status: "OK", Result: result})
+}
+
+// weekdayNames weekday_textの曜日名（Googleと同様に月曜日から並べる）
+var weekdayNames = []string{"月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日", "日曜日"}
+
+// toOpeningHours フィクスチャの営業時間をPlace Detailsの形式に変換する
+func toOpeningHours(h *fixtureHours) *placesOpeningHours {
+	if h == nil {
+		return nil
+	}
+	hours := &placesOpeningHours{Periods: []placesOpeningPeriod{}}
+	if h.AlwaysOpen {
+		// Googleは24時間営業を閉店時刻のない日曜日0時開始の営業時間帯で表す
+		hours.Periods = append(hours.Periods, placesOpeningPeriod{Open: placesOpeningTime{Day: 0, Time: "0000"}})
+		for _, name := range weekdayNames {
+			hours.WeekdayText = append(hours.WeekdayText, name+": 24 時間営業")
+		}
+		return hours
+	}
+
+	closeMinute, _ := parseHHMM(h.Close)
+	for day := 0; day < 7; day++ {
+		if containsInt(h.ClosedDays, day) {
+			continue
+		}
+		closeDay := (day + closeMinute/(24*60)) % 7
+		hours.Periods = append(hours.Periods, placesOpeningPeriod{
+			Open:  placesOpeningTime{Day: day, Time: h.Open},
+			Close: &placesOpeningTime{Day: closeDay, Time: formatHHMM(closeMinute % (24 * 60))},
+		})
+	}
+	for i, name := range weekdayNames {
+		day := (i + 1) % 7 // weekdayNamesは月曜日始まり
+		if containsInt(h.ClosedDays, day) {
+			hours.WeekdayText = append(hours.WeekdayText, name+": 定休日")
+			continue
+		}
+		hours.WeekdayText = append(hours.WeekdayText, fmt.Sprintf("%s: %s～%s", name, h.Open[:2]+":"+h.Open[2:], h.Close[:2]+":"+h.Close[2:]))
+	}
+	return hours
+}
+
+// placePhoto Place Photo（写真参照ごとに色の異なるPNG画像を生成して返す）
+func (s *server) placePhoto(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("key") == "" {
+		http.Error(w, "The provided API key is invalid.", http.StatusForbidden)
+		return
+	}
+	ref := r.URL.Query().Get("photoreference")
+	if _, ok := s.byPhoto[ref]; !ok {
+		http.Error(w, "photoreference was not found", http.StatusBadRequest)
+		return
+	}
+	width := 400
+	if v := r.URL.Query().Get("maxwidth"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1600 {
+			http.Error(w, "maxwidth must be between 1 and 1600", http.StatusBadRequest)
+			return
+		}
+		width = n
+	}
+	height := width * 3 / 4
+
+	// 写真参照から色を決め、上から下へ明るくなるグラデーションにする
+	h := fnv.New32a()
+	h.Write([]byte(ref))
+	sum := h.Sum32()
+	base := color.RGBA{R: uint8(sum), G: uint8(sum >> 8), B: uint8(sum >> 16), A: 255}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		t := float64(y) / float64(height)
+		c := color.RGBA{
+			R: uint8(float64(base.R) + (255-float64(base.R))*t*0.6),
+			G: uint8(float64(base.G) + (255-float64(base.G))*t*0.6),
+			B: uint8(float64(base.B) + (255-float64(base.B))*t*0.6),
+			A: 255,
+		}
+		for x := 0; x < width; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	png.Encode(w, img)
+}
+
+// toPlacesResult フィクスチャの場所をText Search・Nearby Searchの結果に変換する
+func toPlacesResult(p *fixturePlace) placesResult {
+	result := placesResult{
+		PlaceID:          p.PlaceID,
+		Name:             p.Name,
+		Types:            p.Types,
+		Rating:           p.Rating,
+		UserRatingsTotal: p.UserRatingsTotal,
+		Photos:           []placesPhoto{{PhotoReference: p.photoReference(), Width: 1600, Height: 1200}},
+	}
+	result.Geometry.Location.Lat = p.Lat
+	result.Geometry.Location.Lng = p.Lng
+	return result
+}
+
+// hasPlacesKey APIキーが指定されているかを確認し、指定されていない場合はREQUEST_DENIEDを返す
+func hasPlacesKey(w http.ResponseWriter, r *http.Request) bool {
+	if r.URL.Query().Get("key") == "" {
+		writePlacesStatus(w, "REQUEST_DENIED", "You must use an API key to authenticate each request to Google Maps Platform APIs.")
+		return false
+	}
+	return true
+}
+
+// writePlacesStatus Places APIのエラーレスポンスを返す（Places APIはエラーもHTTP 200で返す）
+func writePlacesStatus(w http.ResponseWriter, status, message string) {
+	writeJSON(w, http.StatusOK, placesListResponse{Status: status, Results: []placesResult{}, ErrorMessage: message})
+}
+
+// writeJSON JSONレスポンスを返す
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+// parseHHMM "HHMM"形式の時刻を0時からの分数に変換する（翌日を表す"2400"以上も受け付ける）
+func parseHHMM(hhmm string) (int, error) {
+	if len(hhmm) != 4 {
+		return 0, fmt.Errorf("invalid time (want \"HHMM\"): %q", hhmm)
+	}
+	hour, err1 := strconv.Atoi(hhmm[:2])
+	minute, err2 := strconv.Atoi(hhmm[2:])
+	if err1 != nil || err2 != nil || hour > 47 || minute > 59 {
+		return 0, fmt.Errorf("invalid time (want \"HHMM\"): %q", hhmm)
+	}
+	return hour*60 + minute, nil
+}
+
+// formatHHMM 0時からの分数を"HHMM"形式に変換する
+func formatHHMM(minutes int) string {
+	return fmt.Sprintf("%02d%02d", minutes/60, minutes%60)
+}
+
+// contains sにvが含まれるかを返す
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// containsInt sにvが含まれるかを返す
+func containsInt(s []int, v int) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/pkg/polyline"
+	"fukuoka-ai-api/usecase/solver"
+	"net/http"
+)
+
+// routesLatLng Routes APIのレスポンスの座標
+type routesLatLng struct {
+	LatLng struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	} `json:"latLng"`
+}
+
+// routesPolyline Routes APIのエンコード済みポリライン
+type routesPolyline struct {
+	EncodedPolyline string `json:"encodedPolyline"`
+}
+
+// routesLeg computeRoutesの区間
+type routesLeg struct {
+	DistanceMeters int            `json:"distanceMeters"`
+	Duration       string         `json:"duration"`
+	Polyline       routesPolyline `json:"polyline"`
+	StartLocation  routesLatLng   `json:"startLocation"`
+	EndLocation    routesLatLng   `json:"endLocation"`
+}
+
+// routesRoute computeRoutesのルート
+type routesRoute struct {
+	Legs                               []routesLeg    `json:"legs"`
+	DistanceMeters                     int            `json:"distanceMeters"`
+	Duration                           string         `json:"duration"`
+	Polyline                           routesPolyline `json:"polyline"`
+	OptimizedIntermediateWaypointIndex []int          `json:"optimizedIntermediateWaypointIndex,omitempty"`
+}
+
+// routeMatrixElement computeRouteMatrixの要素（Googleと同様に0のindexは省略する）
+type routeMatrixElement struct {
+	OriginIndex      int      `json:"originIndex,omitempty"`
+	DestinationIndex int      `json:"destinationIndex,omitempty"`
+	Status           struct{} `json:"status"`
+	DistanceMeters   int      `json:"distanceMeters"`
+	Duration         string   `json:"duration"`
+	Condition        string   `json:"condition"`
+}
+
+// computeRoutes computeRoutes（区間ごとの距離・所要時間は直線距離から見積もり、経由地順の最適化も行う）
+func (s *server) computeRoutes(w http.ResponseWriter, r *http.Request) {
+	if !allowRoutesRequest(w, r) {
+		return
+	}
+	var req service.RouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeRoutesError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid JSON payload: "+err.Error())
+		return
+	}
+	travelMode, ok := normalizeTravelMode(req.TravelMode)
+	if !ok {
+		writeRoutesError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid travelMode: "+req.TravelMode)
+		return
+	}
+	if len(req.Intermediates) > service.MaxIntermediates {
+		writeRoutesError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Number of intermediates must not exceed %d.", service.MaxIntermediates))
+		return
+	}
+	if travelMode == "TRANSIT" && len(req.Intermediates) > 0 {
+		writeRoutesError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Intermediate waypoints are not supported for TRANSIT.")
+		return
+	}
+
+	// [出発地点, 経由地点..., 目的地]
+	points := []models.Coordinate{{Lat: req.Origin.Location.LatLng.Latitude, Lng: req.Origin.Location.LatLng.Longitude}}
+	for _, waypoint := range req.Intermediates {
+		points = append(points, models.Coordinate{Lat: waypoint.Location.LatLng.Latitude, Lng: waypoint.Location.LatLng.Longitude})
+	}
+	points = append(points, models.Coordinate{Lat: req.Destination.Location.LatLng.Latitude, Lng: req.Destination.Location.LatLng.Longitude})
+
+	var route routesRoute
+	if req.OptimizeWaypointOrder && len(req.Intermediates) >= 2 {
+		order, err := optimizeOrder(points)
+		if err != nil {
+			writeRoutesError(w, http.StatusInternalServerError, "INTERNAL", err.Error())
+			return
+		}
+		route.OptimizedIntermediateWaypointIndex = order
+		ordered := []models.Coordinate{points[0]}
+		for _, i := range order {
+			ordered = append(ordered, points[i+1])
+		}
+		points = append(ordered, points[len(points)-1])
+	}
+
+	var totalSeconds int
+	for i := 0; i < len(points)-1; i++ {
+		from, to := points[i], points[i+1]
+		distance, seconds := travel(from.Lat, from.Lng, to.Lat, to.Lng, travelMode)
+		leg := routesLeg{
+			DistanceMeters: distance,
+			Duration:       fmt.Sprintf("%ds", seconds),
+			Polyline:       routesPolyline{EncodedPolyline: polyline.Encode([]models.Coordinate{from, to})},
+		}
+		leg.StartLocation.LatLng.Latitude, leg.StartLocation.LatLng.Longitude = from.Lat, from.Lng
+		leg.EndLocation.LatLng.Latitude, leg.EndLocation.LatLng.Longitude = to.Lat, to.Lng
+		route.Legs = append(route.Legs, leg)
+		route.DistanceMeters += distance
+		totalSeconds += seconds
+	}
+	route.Duration = fmt.Sprintf("%ds", totalSeconds)
+	route.Polyline = routesPolyline{EncodedPolyline: polyline.Encode(points)}
+
+	writeJSON(w, http.StatusOK, map[string][]routesRoute{"routes": {route}})
+}
+
+// optimizeOrder 出発地点・目的地を固定して経由地点の訪問順序を直線距離で最適化する
+// 戻り値は経由地点のインデックス（intermediates内の位置）を訪問順に並べたもの
+func optimizeOrder(points []models.Coordinate) ([]int, error) {
+	n := len(points)
+	cost := make([][]float64, n)
+	for i := range points {
+		cost[i] = make([]float64, n)
+		for j := range points {
+			if i != j {
+				cost[i][j] = haversine(points[i].Lat, points[i].Lng, points[j].Lat, points[j].Lng)
+			}
+		}
+	}
+	path, _, err := solver.SolvePath(cost, 0, n-1)
+	if err != nil {
+		return nil, err
+	}
+	order := make([]int, 0, n-2)
+	for _, i := range path[1 : len(path)-1] {
+		order = append(order, i-1)
+	}
+	return order, nil
+}
+
+// computeRouteMatrix computeRouteMatrix（全要素を直線距離から見積もる）
+func (s *server) computeRouteMatrix(w http.ResponseWriter, r *http.Request) {
+	if !allowRoutesRequest(w, r) {
+		return
+	}
+	var req service.RouteMatrixRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeRoutesError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid JSON payload: "+err.Error())
+		return
+	}
+	travelMode, ok := normalizeTravelMode(req.TravelMode)
+	if !ok {
+		writeRoutesError(w, http.StatusBadRequest, "INVALID_ARGUMENT", "Invalid travelMode: "+req.TravelMode)
+		return
+	}
+	limit := service.MaxRouteMatrixElements
+	if travelMode == "TRANSIT" {
+		limit = service.MaxTransitRouteMatrixElements
+	}
+	if len(req.Origins)*len(req.Destinations) > limit {
+		writeRoutesError(w, http.StatusBadRequest, "INVALID_ARGUMENT", fmt.Sprintf("Number of elements must not exceed %d.", limit))
+		return
+	}
+
+	elements := make([]routeMatrixElement, 0, len(req.Origins)*len(req.Destinations))
+	for i, origin := range req.Origins {
+		for j, destination := range req.Destinations {
+			from, to := origin.Waypoint.Location.LatLng, destination.Waypoint.Location.LatLng
+			distance, seconds := travel(from.Latitude, from.Longitude, to.Latitude, to.Longitude, travelMode)
+			elements = append(elements, routeMatrixElement{
+				OriginIndex:      i,
+				DestinationIndex: j,
+				DistanceMeters:   distance,
+				Duration:         fmt.Sprintf("%ds", seconds),
+				Condition:        "ROUTE_EXISTS",
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, elements)
+}
+
+// normalizeTravelMode 移動手段を検証する（空の場合はDRIVE）
+func normalizeTravelMode(travelMode string) (string, bool) {
+	if travelMode == "" || travelMode == "TRAVEL_MODE_UNSPECIFIED" {
+		return "DRIVE", true
+	}
+	_, ok := travelSpeeds[travelMode]
+	return travelMode, ok
+}
+
+// allowRoutesRequest POSTでAPIキーが指定されているかを確認し、そうでない場合はエラーを返す
+func allowRoutesRequest(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		writeRoutesError(w, http.StatusMethodNotAllowed, "INVALID_ARGUMENT", "Only POST is supported.")
+		return false
+	}
+	if r.URL.Query().Get("key") == "" && r.Header.Get("X-Goog-Api-Key") == "" {
+		writeRoutesError(w, http.StatusForbidden, "PERMISSION_DENIED", "The request is missing a valid API key.")
+		return false
+	}
+	return true
+}
+
+// writeRoutesError Routes APIのエラーレスポンスを返す
+func writeRoutesError(w http.ResponseWriter, statusCode int, status, message string) {
+	resp := map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    statusCode,
+			"message": message,
+			"status":  status,
+		},
+	}
+	writeJSON(w, statusCode, resp)
+}
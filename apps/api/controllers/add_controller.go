@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/usecase"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -8,15 +10,18 @@ import (
 
 // AddController 場所追加機能のコントローラー
 type AddController struct {
+	addUsecase usecase.IAddUsecase
 }
 
 // NewAddController 新しいAddControllerを作成
-func NewAddController() *AddController {
-	return &AddController{}
+func NewAddController(addUsecase usecase.IAddUsecase) *AddController {
+	return &AddController{
+		addUsecase: addUsecase,
+	}
 }
 
 // AddPlace リコメンド場所をリストに追加するエンドポイント
-// 注: APIドキュメントでは「入力なし、出力なし」となっているため、単純に200 OKを返す
+// ボディでtrip_idが指定された場合は旅程に保存し、省略された場合は従来どおり200 OKのみを返す
 func (c *AddController) AddPlace(ctx *gin.Context) {
 	placeID := ctx.Param("place_id")
 	if placeID == "" {
@@ -27,11 +32,39 @@ func (c *AddController) AddPlace(ctx *gin.Context) {
 		return
 	}
 
-	// ここでは単純に200 OKを返す
-	// 実際のリスト管理はクライアント側で行う想定
+	// ボディは省略可能
+	var req models.AddRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+				"code":    "INVALID_REQUEST",
+				"message": "リクエストの形式が不正です: " + err.Error(),
+			}})
+			return
+		}
+	}
+
+	req.UserID = ctx.GetHeader("X-User-Id")
+
+	if req.TripID == "" {
+		// 旅程IDがない場合、リスト管理はクライアント側で行う想定
+		ctx.JSON(http.StatusOK, gin.H{
+			"message":  "場所が追加されました",
+			"place_id": placeID,
+		})
+		return
+	}
+
+	tripPlace, err := c.addUsecase.AddPlace(ctx.Request.Context(), req.TripID, placeID, req.UserID)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, gin.H{
-		"message": "場所が追加されました",
-		"place_id": placeID,
+		"message":    "場所が追加されました",
+		"place_id":   placeID,
+		"trip_id":    req.TripID,
+		"trip_place": tripPlace,
 	})
 }
-
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"fukuoka-ai-api/infra/cache"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheController キャッシュ統計のコントローラー
+type CacheController struct {
+	caches []*cache.Cache
+}
+
+// NewCacheController 新しいCacheControllerを作成
+func NewCacheController(caches ...*cache.Cache) *CacheController {
+	return &CacheController{
+		caches: caches,
+	}
+}
+
+// GetStats キャッシュごとのヒット/ミス統計を返すエンドポイント
+func (c *CacheController) GetStats(ctx *gin.Context) {
+	stats := make([]cache.Stats, 0, len(c.caches))
+	for _, cc := range c.caches {
+		stats = append(stats, cc.Stats())
+	}
+	ctx.JSON(http.StatusOK, gin.H{"caches": stats})
+}
@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deadline エンドポイント全体の処理時間の上限を設定するミドルウェア
+// 設定した期限はリクエストのcontextを通じて外部APIの呼び出しまで伝わり、クライアントの切断時と同様に処理を打ち切る
+func Deadline(timeout time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), timeout)
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
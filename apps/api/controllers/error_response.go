@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fukuoka-ai-api/infra/repository"
+	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/infra/usage"
+	"fukuoka-ai-api/pkg/apperror"
+	"fukuoka-ai-api/pkg/logging"
+	"fukuoka-ai-api/usecase"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest クライアントが応答を待たずに切断した場合のステータスコード（nginxの慣例）
+const statusClientClosedRequest = 499
+
+// partialResultHeader 一部の処理に失敗した結果を返す場合に、失敗のエラーコードをカンマ区切りで設定するヘッダー
+const partialResultHeader = "X-Partial-Result"
+
+// errorKinds エラーの種類ごとのHTTPステータスと既定のエラーコード
+// apperror.ErrorにCodeが設定されている場合はそちらを優先する
+var errorKinds = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{apperror.ErrInvalidInput, http.StatusBadRequest, "INVALID_REQUEST"},
+	{apperror.ErrPlaceNotFound, http.StatusBadRequest, "PLACE_NOT_FOUND"},
+	{apperror.ErrUpstreamQuota, http.StatusServiceUnavailable, "UPSTREAM_QUOTA_EXCEEDED"},
+	{apperror.ErrConfiguration, http.StatusInternalServerError, "CONFIGURATION_ERROR"},
+	{apperror.ErrUpstream, http.StatusInternalServerError, "UPSTREAM_ERROR"},
+	{apperror.ErrPartialResult, http.StatusInternalServerError, "PARTIAL_RESULT"},
+}
+
+// respondResult ユースケースの結果をレスポンスに変換する
+// 一部の処理の失敗（apperror.ErrPartialResult）だけの場合は結果を200で返し、失敗のエラーコードをX-Partial-Resultヘッダーに設定する
+func respondResult[T any](ctx *gin.Context, response *T, err error) {
+	if err != nil && !(response != nil && apperror.IsPartial(err)) {
+		respondError(ctx, err)
+		return
+	}
+	if err != nil {
+		ctx.Header(partialResultHeader, strings.Join(apperror.PartialCodes(err), ","))
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// respondError エラーの種類からHTTPステータスとエラーコードを決めてレスポンスを返す
+func respondError(ctx *gin.Context, err error) {
+	message := err.Error()
+	var statusCode int
+	var errorCode string
+	switch {
+	case errors.Is(err, context.Canceled) && ctx.Request.Context().Err() != nil:
+		// クライアントは既に切断しているため、ステータスのみ記録する
+		ctx.AbortWithStatus(statusClientClosedRequest)
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		statusCode, errorCode = http.StatusGatewayTimeout, "TIMEOUT"
+		message = "処理が制限時間内に完了しませんでした: " + message
+	case errors.Is(err, service.ErrCircuitOpen):
+		statusCode, errorCode = http.StatusServiceUnavailable, "UPSTREAM_UNAVAILABLE"
+		message = "外部APIの障害が続いているため、しばらくしてから再度お試しください: " + message
+	case errors.Is(err, usage.ErrBudgetExceeded):
+		statusCode, errorCode = http.StatusServiceUnavailable, "BUDGET_EXCEEDED"
+		message = "外部APIの利用回数が予算の上限に近いため、キャッシュにない情報は取得できません: " + message
+	case errors.Is(err, repository.ErrNotFound):
+		statusCode, errorCode = http.StatusNotFound, "TRIP_NOT_FOUND"
+	case errors.Is(err, usecase.ErrForbidden):
+		statusCode, errorCode = http.StatusForbidden, "FORBIDDEN"
+	default:
+		statusCode, errorCode = http.StatusInternalServerError, "INTERNAL_ERROR"
+		for _, k := range errorKinds {
+			if errors.Is(err, k.kind) {
+				statusCode, errorCode = k.status, k.code
+				break
+			}
+		}
+		if code := apperror.Code(err); code != "" {
+			errorCode = code
+		}
+	}
+	if statusCode >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx.Request.Context(), "request failed", "code", errorCode, "error", err)
+	}
+	writeError(ctx, statusCode, errorCode, message, apperror.Details(err))
+}
+
+// writeError エラーレスポンスを返す（detailsは空の場合は省略する）
+// 外部APIのエラーメッセージを含む場合があるため、メッセージと詳細情報からAPIキーを伏せる
+func writeError(ctx *gin.Context, statusCode int, errorCode string, message string, details string) {
+	body := gin.H{
+		"code":    errorCode,
+		"message": logging.Redact(message),
+	}
+	if details != "" {
+		body["details"] = logging.Redact(details)
+	}
+	ctx.JSON(statusCode, gin.H{"error": body})
+}
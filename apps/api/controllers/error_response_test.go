@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fukuoka-ai-api/infra/repository"
+	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/infra/usage"
+	"fukuoka-ai-api/pkg/apperror"
+	"fukuoka-ai-api/usecase"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errorBody エラーレスポンスのボディ
+type errorBody struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Details string `json:"details"`
+	} `json:"error"`
+}
+
+// newTestContext レスポンスを記録するgin.Contextを作成
+func newTestContext(reqCtx context.Context) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	return ctx, w
+}
+
+func TestRespondError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name        string
+		reqCtx      context.Context
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantDetails string
+	}{
+		{"旅程が見つからない", nil, fmt.Errorf("旅程の取得に失敗しました: %w", repository.ErrNotFound), http.StatusNotFound, "TRIP_NOT_FOUND", ""},
+		{"旅程の所有者ではない", nil, fmt.Errorf("共有の作成に失敗しました: %w", usecase.ErrForbidden), http.StatusForbidden, "FORBIDDEN", ""},
+		{"リクエストが不正", nil, apperror.New(apperror.ErrInvalidInput, "開始時刻の形式が不正です").WithDetails("start_time"), http.StatusBadRequest, "INVALID_REQUEST", "start_time"},
+		{"リクエストが不正（エラーコードあり）", nil, fmt.Errorf("検証に失敗しました: %w", apperror.New(apperror.ErrInvalidInput, "日付が不正です").WithCode("INVALID_DATE")), http.StatusBadRequest, "INVALID_DATE", ""},
+		{"場所が見つからない", nil, apperror.New(apperror.ErrPlaceNotFound, "場所が見つかりません"), http.StatusBadRequest, "PLACE_NOT_FOUND", ""},
+		{"一部の処理の失敗", nil, apperror.Partial("OPENING_HOURS_UNAVAILABLE", errors.New("details failed"), "営業時間を取得できませんでした"), http.StatusInternalServerError, "OPENING_HOURS_UNAVAILABLE", ""},
+		{"外部APIの失敗", nil, fmt.Errorf("ルート計算に失敗しました: %w", apperror.Wrap(apperror.ErrUpstream, errors.New("HTTP 500"), "Routes APIの呼び出しに失敗しました").WithDetails("status=500")), http.StatusInternalServerError, "UPSTREAM_ERROR", "status=500"},
+		{"外部APIの利用上限", nil, apperror.New(apperror.ErrUpstreamQuota, "OVER_QUERY_LIMIT"), http.StatusServiceUnavailable, "UPSTREAM_QUOTA_EXCEEDED", ""},
+		{"設定の不正", nil, apperror.New(apperror.ErrConfiguration, "APIキーが設定されていません"), http.StatusInternalServerError, "CONFIGURATION_ERROR", ""},
+		{"サーキットブレーカーがopen", nil, fmt.Errorf("places: %w", service.ErrCircuitOpen), http.StatusServiceUnavailable, "UPSTREAM_UNAVAILABLE", ""},
+		{"利用回数の予算超過", nil, fmt.Errorf("places: %w", usage.ErrBudgetExceeded), http.StatusServiceUnavailable, "BUDGET_EXCEEDED", ""},
+		{"期限切れ", nil, fmt.Errorf("検索に失敗しました: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "TIMEOUT", ""},
+		{"クライアントの切断ではないキャンセル", nil, context.Canceled, http.StatusInternalServerError, "INTERNAL_ERROR", ""},
+		{"種類のないエラー", nil, errors.New("unexpected"), http.StatusInternalServerError, "INTERNAL_ERROR", ""},
+		{"クライアントが切断した", canceled, fmt.Errorf("検索に失敗しました: %w", context.Canceled), statusClientClosedRequest, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqCtx := tt.reqCtx
+			if reqCtx == nil {
+				reqCtx = context.Background()
+			}
+			ctx, w := newTestContext(reqCtx)
+			respondError(ctx, tt.err)
+
+			if ctx.Writer.Status() != tt.wantStatus {
+				t.Errorf("status = %d, want %d", ctx.Writer.Status(), tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				if w.Body.Len() != 0 {
+					t.Errorf("body = %s, want empty", w.Body.String())
+				}
+				return
+			}
+			var body errorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to parse body %s: %v", w.Body.String(), err)
+			}
+			if body.Error.Code != tt.wantCode || body.Error.Details != tt.wantDetails {
+				t.Errorf("code, details = %s, %q, want %s, %q", body.Error.Code, body.Error.Details, tt.wantCode, tt.wantDetails)
+			}
+			if !strings.Contains(body.Error.Message, tt.err.Error()) {
+				t.Errorf("message = %q, want to contain %q", body.Error.Message, tt.err.Error())
+			}
+		})
+	}
+
+	// メッセージと詳細情報のAPIキーは伏せる
+	ctx, w := newTestContext(context.Background())
+	respondError(ctx, apperror.Wrap(apperror.ErrUpstream, errors.New(`Get "https://maps.googleapis.com/maps/api/place/details/json?key=secret-key-123": EOF`), "詳細の取得に失敗しました").
+		WithDetails("url=/json?key=secret-key-123"))
+	if strings.Contains(w.Body.String(), "secret-key-123") {
+		t.Errorf("body contains the API key: %s", w.Body.String())
+	}
+}
+
+func TestRespondResult(t *testing.T) {
+	type result struct {
+		TripID string `json:"trip_id"`
+	}
+	response := &result{TripID: "trip-1"}
+	hoursUnavailable := apperror.Partial("OPENING_HOURS_UNAVAILABLE", errors.New("details failed"), "営業時間を取得できませんでした")
+	detourFallback := apperror.Partial("DETOUR_MATRIX_FALLBACK", errors.New("matrix failed"), "寄り道の距離を直線距離で見積もりました")
+
+	tests := []struct {
+		name        string
+		response    *result
+		err         error
+		wantStatus  int
+		wantPartial string
+		wantCode    string
+	}{
+		{"成功", response, nil, http.StatusOK, "", ""},
+		{"一部の処理の失敗は結果を200で返す", response, hoursUnavailable, http.StatusOK, "OPENING_HOURS_UNAVAILABLE", ""},
+		{"複数の一部の処理の失敗はコードを重複を除いて並べる", response, errors.Join(hoursUnavailable, detourFallback, hoursUnavailable), http.StatusOK, "OPENING_HOURS_UNAVAILABLE,DETOUR_MATRIX_FALLBACK", ""},
+		{"結果がない一部の処理の失敗はエラー", nil, hoursUnavailable, http.StatusInternalServerError, "", "OPENING_HOURS_UNAVAILABLE"},
+		{"一部の処理の失敗ではないエラーは結果があってもエラー", response, apperror.Wrap(apperror.ErrUpstream, errors.New("HTTP 500"), "Routes APIの呼び出しに失敗しました"), http.StatusInternalServerError, "", "UPSTREAM_ERROR"},
+		{"旅程が見つからない", nil, repository.ErrNotFound, http.StatusNotFound, "", "TRIP_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, w := newTestContext(context.Background())
+			respondResult(ctx, tt.response, tt.err)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get(partialResultHeader); got != tt.wantPartial {
+				t.Errorf("%s = %q, want %q", partialResultHeader, got, tt.wantPartial)
+			}
+			if tt.wantCode == "" {
+				var got result
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got != *tt.response {
+					t.Errorf("body = %s, want %+v", w.Body.String(), *tt.response)
+				}
+				return
+			}
+			var body errorBody
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to parse body %s: %v", w.Body.String(), err)
+			}
+			if body.Error.Code != tt.wantCode {
+				t.Errorf("code = %s, want %s", body.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -41,12 +41,9 @@ func (c *GeocodingController) GetPlaceID(ctx *gin.Context) {
 	}
 
 	// ジオコーディングサービスを呼び出し
-	lat, lng, placeID, err := c.geocodingService.GetCoordinates(req.PlaceName)
+	lat, lng, placeID, err := c.geocodingService.GetCoordinates(ctx.Request.Context(), req.PlaceName)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{
-			"code":    "GEOCODING_ERROR",
-			"message": "場所の座標取得に失敗しました: " + err.Error(),
-		}})
+		respondError(ctx, err)
 		return
 	}
 
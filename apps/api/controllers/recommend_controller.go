@@ -4,7 +4,6 @@ import (
 	"fukuoka-ai-api/models"
 	"fukuoka-ai-api/usecase"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -49,32 +48,10 @@ func (c *RecommendController) Recommend(ctx *gin.Context) {
 		return
 	}
 
-	// ユースケースを呼び出し
-	response, err := c.recommendUsecase.Recommend(&req)
-	if err != nil {
-		statusCode := http.StatusInternalServerError
-		errorCode := "INTERNAL_ERROR"
-		message := err.Error()
-
-		// エラーメッセージからエラーコードを判定
-		errMsg := err.Error()
-		if strings.Contains(errMsg, "座標取得") {
-			statusCode = http.StatusBadRequest
-			errorCode = "GEOCODING_ERROR"
-		} else if strings.Contains(errMsg, "Google Places API") {
-			errorCode = "PLACES_API_ERROR"
-		} else if strings.Contains(errMsg, "APIキー") || strings.Contains(errMsg, "API_KEY") {
-			statusCode = http.StatusInternalServerError
-			errorCode = "CONFIGURATION_ERROR"
-		}
+	req.UserID = ctx.GetHeader("X-User-Id")
 
-		ctx.JSON(statusCode, gin.H{"error": gin.H{
-			"code":    errorCode,
-			"message": message,
-		}})
-		return
-	}
-
-	ctx.JSON(http.StatusOK, response)
+	// ユースケースを呼び出し
+	response, err := c.recommendUsecase.Recommend(ctx.Request.Context(), &req)
+	respondResult(ctx, response, err)
 }
 
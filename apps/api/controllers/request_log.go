@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fukuoka-ai-api/pkg/logging"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDHeader リクエストIDを受け取り・返すヘッダー
+const requestIDHeader = "X-Request-Id"
+
+// requestIDPattern クライアントから受け取るリクエストIDとして受け付ける形式（ログを汚さないよう英数字と一部の記号のみ）
+var requestIDPattern = regexp.MustCompile(`^[0-9A-Za-z._-]{1,64}$`)
+
+// RequestLogger リクエストIDをcontextに設定し、リクエストの完了時にアクセスログを出力するミドルウェア
+// X-Request-Idヘッダーで指定されたIDを引き継ぎ、指定がない場合は新しく発行する（レスポンスのX-Request-Idヘッダーで返す）
+// リクエストIDはcontextを通じて外部APIの呼び出しまでのすべてのログに付与される
+func RequestLogger() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(requestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		ctx.Request = ctx.Request.WithContext(logging.WithRequestID(ctx.Request.Context(), id))
+		ctx.Header(requestIDHeader, id)
+
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		slog.LogAttrs(ctx.Request.Context(), level, "request",
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.String("route", ctx.FullPath()),
+			slog.Int("status", status),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.String("client_ip", ctx.ClientIP()),
+		)
+	}
+}
+
+// newRequestID ランダムな16バイトの16進数のリクエストIDを発行する
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
@@ -4,7 +4,6 @@ import (
 	"fukuoka-ai-api/models"
 	"fukuoka-ai-api/usecase"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -41,31 +40,10 @@ func (c *ResultController) Result(ctx *gin.Context) {
 		return
 	}
 
-	// ユースケースを呼び出し
-	response, err := c.resultUsecase.ComputeOptimizedRoute(&req)
-	if err != nil {
-		statusCode := http.StatusInternalServerError
-		errorCode := "INTERNAL_ERROR"
-		message := err.Error()
-
-		// エラーメッセージからエラーコードを判定
-		if strings.Contains(message, "場所リストが空") {
-			statusCode = http.StatusBadRequest
-			errorCode = "INVALID_REQUEST"
-		} else if strings.Contains(message, "有効な場所") {
-			statusCode = http.StatusBadRequest
-			errorCode = "INVALID_REQUEST"
-		} else if strings.Contains(message, "ルート計算") || strings.Contains(message, "Routes API") {
-			errorCode = "ROUTES_API_ERROR"
-		}
+	req.UserID = ctx.GetHeader("X-User-Id")
 
-		ctx.JSON(statusCode, gin.H{"error": gin.H{
-			"code":    errorCode,
-			"message": message,
-		}})
-		return
-	}
-
-	ctx.JSON(http.StatusOK, response)
+	// ユースケースを呼び出し
+	response, err := c.resultUsecase.ComputeOptimizedRoute(ctx.Request.Context(), &req)
+	respondResult(ctx, response, err)
 }
 
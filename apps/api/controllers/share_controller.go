@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"fukuoka-ai-api/infra/repository"
+	"fukuoka-ai-api/usecase"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShareController 旅程共有機能のコントローラー
+type ShareController struct {
+	shareUsecase usecase.IShareUsecase
+}
+
+// NewShareController 新しいShareControllerを作成
+func NewShareController(shareUsecase usecase.IShareUsecase) *ShareController {
+	return &ShareController{
+		shareUsecase: shareUsecase,
+	}
+}
+
+// CreateShare 旅程の共有IDを発行するエンドポイント
+func (c *ShareController) CreateShare(ctx *gin.Context) {
+	tripID := ctx.Param("trip_id")
+	share, err := c.shareUsecase.CreateShare(tripID, ctx.GetHeader("X-User-Id"))
+	if err != nil {
+		respondShareError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, share)
+}
+
+// RevokeShare 旅程の共有を停止するエンドポイント
+func (c *ShareController) RevokeShare(ctx *gin.Context) {
+	tripID := ctx.Param("trip_id")
+	if err := c.shareUsecase.RevokeShare(tripID, ctx.GetHeader("X-User-Id")); err != nil {
+		respondShareError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetShare 共有された旅程を取得するエンドポイント（ログイン不要）
+func (c *ShareController) GetShare(ctx *gin.Context) {
+	shareID := ctx.Param("share_id")
+	response, err := c.shareUsecase.GetShare(shareID)
+	if err != nil {
+		respondShareError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// respondShareError 共有機能のエラーをHTTPステータスに変換して返す
+// 共有IDが見つからない場合もあるため、旅程に限らないNOT_FOUNDを返す
+func respondShareError(ctx *gin.Context, err error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		writeError(ctx, http.StatusNotFound, "NOT_FOUND", err.Error(), "")
+		return
+	}
+	respondError(ctx, err)
+}
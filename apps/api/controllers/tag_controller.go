@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"fukuoka-ai-api/infra/tags"
+	"fukuoka-ai-api/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TagController 興味タグのコントローラー
+type TagController struct {
+	tagRegistry *tags.Registry
+}
+
+// NewTagController 新しいTagControllerを作成
+func NewTagController(tagRegistry *tags.Registry) *TagController {
+	return &TagController{
+		tagRegistry: tagRegistry,
+	}
+}
+
+// ListTags 興味タグの一覧を返すエンドポイント（フロントエンドのタグの選択肢に使用）
+func (c *TagController) ListTags(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, models.TagsResponse{Tags: c.tagRegistry.Tags()})
+}
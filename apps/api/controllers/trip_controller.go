@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/usecase"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TripController 保存済み旅程のコントローラー
+type TripController struct {
+	tripUsecase usecase.ITripUsecase
+}
+
+// NewTripController 新しいTripControllerを作成
+func NewTripController(tripUsecase usecase.ITripUsecase) *TripController {
+	return &TripController{
+		tripUsecase: tripUsecase,
+	}
+}
+
+// CreateTrip リコメンドとルート計算をまとめて行い、旅程を生成するエンドポイント
+func (c *TripController) CreateTrip(ctx *gin.Context) {
+	var req models.CreateTripRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    "INVALID_REQUEST",
+			"message": "リクエストの形式が不正です: " + err.Error(),
+		}})
+		return
+	}
+
+	// バリデーション
+	if len(req.MustPlaces) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    "INVALID_REQUEST",
+			"message": "寄りたい場所が指定されていません",
+		}})
+		return
+	}
+
+	if len(req.InterestTags) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    "INVALID_REQUEST",
+			"message": "興味タグが指定されていません",
+		}})
+		return
+	}
+
+	req.UserID = ctx.GetHeader("X-User-Id")
+
+	// ユースケースを呼び出し
+	response, err := c.tripUsecase.CreateTrip(ctx.Request.Context(), &req)
+	respondResult(ctx, response, err)
+}
+
+// RecomputeTrip ユーザーが指定した順序・滞在時間で旅程を再計算するエンドポイント
+func (c *TripController) RecomputeTrip(ctx *gin.Context) {
+	tripID := ctx.Param("trip_id")
+
+	var req models.RecomputeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    "INVALID_REQUEST",
+			"message": "リクエストの形式が不正です: " + err.Error(),
+		}})
+		return
+	}
+
+	req.UserID = ctx.GetHeader("X-User-Id")
+
+	response, err := c.tripUsecase.RecomputeTrip(ctx.Request.Context(), tripID, &req)
+	respondResult(ctx, response, err)
+}
+
+// GetTrip 保存済みの旅程を取得するエンドポイント
+func (c *TripController) GetTrip(ctx *gin.Context) {
+	tripID := ctx.Param("trip_id")
+	if tripID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": gin.H{
+			"code":    "INVALID_REQUEST",
+			"message": "trip_idが指定されていません",
+		}})
+		return
+	}
+
+	response, err := c.tripUsecase.GetTrip(tripID, ctx.GetHeader("X-User-Id"))
+	respondResult(ctx, response, err)
+}
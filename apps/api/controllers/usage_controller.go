@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"fukuoka-ai-api/infra/usage"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usageHeader リクエストごとの外部APIの呼び出し回数を返すレスポンスヘッダー
+const usageHeader = "X-Upstream-Usage"
+
+// UsageController 外部APIの呼び出し回数のコントローラー
+type UsageController struct {
+	meter *usage.Meter
+}
+
+// NewUsageController 新しいUsageControllerを作成
+func NewUsageController(meter *usage.Meter) *UsageController {
+	return &UsageController{
+		meter: meter,
+	}
+}
+
+// GetStats SKUごとの呼び出し回数と予算を返すエンドポイント
+func (c *UsageController) GetStats(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.meter.Stats())
+}
+
+// UsageHeader リクエストの処理中に行った外部APIの呼び出し回数をX-Upstream-Usageヘッダーで返すミドルウェア
+// ヘッダーはレスポンスの書き込み直前に設定するため、ハンドラー内のすべての呼び出しが含まれる
+func UsageHeader() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		reqCtx, recorder := usage.WithRecorder(ctx.Request.Context())
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Writer = &usageHeaderWriter{ResponseWriter: ctx.Writer, recorder: recorder}
+		ctx.Next()
+	}
+}
+
+// usageHeaderWriter レスポンスの書き込み直前にX-Upstream-Usageヘッダーを設定するResponseWriter
+type usageHeaderWriter struct {
+	gin.ResponseWriter
+	recorder *usage.Recorder
+}
+
+// setHeader まだヘッダーを送信していない場合にX-Upstream-Usageヘッダーを設定する
+func (w *usageHeaderWriter) setHeader() {
+	if !w.Written() {
+		w.Header().Set(usageHeader, w.recorder.String())
+	}
+}
+
+func (w *usageHeaderWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *usageHeaderWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *usageHeaderWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,118 @@
+// Package cache 外部APIのレスポンスをキャッシュする
+//
+// メモリ上のLRUと、再起動後も保持される任意の永続ストアの2層で構成する。
+// 値はJSONエンコードして保持するため、取得した値を呼び出し側で変更しても
+// キャッシュ内の値には影響しない。
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Cache 名前空間ごとのキャッシュ
+type Cache struct {
+	name  string
+	ttl   time.Duration
+	lru   *LRU
+	store IStore // nilの場合はメモリのみ
+
+	hits      atomic.Uint64
+	storeHits atomic.Uint64
+	misses    atomic.Uint64
+}
+
+// Stats キャッシュのヒット/ミス統計
+type Stats struct {
+	Name      string  `json:"name"`
+	Hits      uint64  `json:"hits"`       // ヒット数（永続ストアからのヒットを含む）
+	StoreHits uint64  `json:"store_hits"` // 永続ストアからのヒット数
+	Misses    uint64  `json:"misses"`
+	HitRate   float64 `json:"hit_rate"`  // ヒット率（0〜1）
+	Size      int     `json:"size"`      // メモリ上のエントリ数
+	Evictions uint64  `json:"evictions"` // 容量超過で削除したエントリ数
+	TTL       string  `json:"ttl"`
+	Persisted bool    `json:"persisted"` // 永続ストアを使用しているか
+}
+
+// New 新しいCacheを作成
+// storeがnilの場合はメモリ上のLRUのみを使用する
+func New(name string, ttl time.Duration, capacity int, store IStore) *Cache {
+	return &Cache{
+		name:  name,
+		ttl:   ttl,
+		lru:   NewLRU(capacity),
+		store: store,
+	}
+}
+
+// Get キーに対応する値をdestにデコードする（見つからない場合はfalse）
+// メモリにない場合は永続ストアを参照し、見つかった値はメモリにも保持する
+func (c *Cache) Get(ctx context.Context, key string, dest interface{}) bool {
+	if data, ok := c.lru.Get(key); ok && json.Unmarshal(data, dest) == nil {
+		c.hits.Add(1)
+		return true
+	}
+
+	if c.store != nil {
+		data, expiresAt, found, err := c.store.Get(c.storeKey(key))
+		if err != nil {
+			slog.WarnContext(ctx, "キャッシュの読み込みに失敗しました", "cache", c.name, "error", err)
+		} else if found && time.Now().Before(expiresAt) && json.Unmarshal(data, dest) == nil {
+			c.lru.Set(key, data, expiresAt)
+			c.hits.Add(1)
+			c.storeHits.Add(1)
+			return true
+		}
+	}
+
+	c.misses.Add(1)
+	return false
+}
+
+// Set キーに値を保存する（有効期限は作成時に指定したTTL）
+// 永続ストアへの書き込みに失敗した場合も、メモリには保持する
+func (c *Cache) Set(ctx context.Context, key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		slog.WarnContext(ctx, "キャッシュの値をエンコードできませんでした", "cache", c.name, "error", err)
+		return
+	}
+
+	expiresAt := time.Now().Add(c.ttl)
+	c.lru.Set(key, data, expiresAt)
+	if c.store != nil {
+		if err := c.store.Set(c.storeKey(key), data, expiresAt); err != nil {
+			slog.WarnContext(ctx, "キャッシュの書き込みに失敗しました", "cache", c.name, "error", err)
+		}
+	}
+}
+
+// Stats ヒット/ミス統計を返す
+func (c *Cache) Stats() Stats {
+	hits := c.hits.Load()
+	misses := c.misses.Load()
+	var hitRate float64
+	if hits+misses > 0 {
+		hitRate = float64(hits) / float64(hits+misses)
+	}
+	return Stats{
+		Name:      c.name,
+		Hits:      hits,
+		StoreHits: c.storeHits.Load(),
+		Misses:    misses,
+		HitRate:   hitRate,
+		Size:      c.lru.Len(),
+		Evictions: c.lru.Evictions(),
+		TTL:       c.ttl.String(),
+		Persisted: c.store != nil,
+	}
+}
+
+// storeKey 永続ストアのキー（キャッシュ間で衝突しないよう名前空間を付ける）
+func (c *Cache) storeKey(key string) string {
+	return c.name + ":" + key
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// memoryStore テスト用のメモリ上の永続ストア
+type memoryStore struct {
+	values    map[string][]byte
+	expiresAt map[string]time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: make(map[string][]byte), expiresAt: make(map[string]time.Time)}
+}
+
+func (s *memoryStore) Get(key string) ([]byte, time.Time, bool, error) {
+	value, ok := s.values[key]
+	return value, s.expiresAt[key], ok, nil
+}
+
+func (s *memoryStore) Set(key string, value []byte, expiresAt time.Time) error {
+	s.values[key] = value
+	s.expiresAt[key] = expiresAt
+	return nil
+}
+
+func (s *memoryStore) DeleteExpired() error {
+	return nil
+}
+
+func TestCacheGet(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name          string
+		setup         func(c *Cache, store *memoryStore)
+		wantOK        bool
+		wantValue     string
+		wantStoreHits uint64
+	}{
+		{
+			name:      "メモリにある",
+			setup:     func(c *Cache, store *memoryStore) { c.Set(ctx, "key", "value") },
+			wantOK:    true,
+			wantValue: "value",
+		},
+		{
+			name:   "見つからない",
+			setup:  func(c *Cache, store *memoryStore) {},
+			wantOK: false,
+		},
+		{
+			name: "永続ストアにある",
+			setup: func(c *Cache, store *memoryStore) {
+				store.Set(c.storeKey("key"), []byte(`"stored"`), time.Now().Add(time.Hour))
+			},
+			wantOK:        true,
+			wantValue:     "stored",
+			wantStoreHits: 1,
+		},
+		{
+			name: "永続ストアの値が期限切れ",
+			setup: func(c *Cache, store *memoryStore) {
+				store.Set(c.storeKey("key"), []byte(`"stored"`), time.Now().Add(-time.Second))
+			},
+			wantOK: false,
+		},
+		{
+			name: "メモリの値が期限切れの場合は永続ストアも参照する",
+			setup: func(c *Cache, store *memoryStore) {
+				c.lru.Set("key", []byte(`"old"`), time.Now().Add(-time.Second))
+				store.Set(c.storeKey("key"), []byte(`"stored"`), time.Now().Add(time.Hour))
+			},
+			wantOK:        true,
+			wantValue:     "stored",
+			wantStoreHits: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			c := New("test", time.Hour, 10, store)
+			tt.setup(c, store)
+
+			var got string
+			if ok := c.Get(ctx, "key", &got); ok != tt.wantOK {
+				t.Fatalf("Get ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got != tt.wantValue {
+				t.Errorf("Get = %q, want %q", got, tt.wantValue)
+			}
+
+			stats := c.Stats()
+			wantHits, wantMisses := uint64(0), uint64(1)
+			if tt.wantOK {
+				wantHits, wantMisses = 1, 0
+			}
+			if stats.Hits != wantHits || stats.Misses != wantMisses || stats.StoreHits != tt.wantStoreHits {
+				t.Errorf("Stats = hits %d, misses %d, store hits %d, want %d, %d, %d",
+					stats.Hits, stats.Misses, stats.StoreHits, wantHits, wantMisses, tt.wantStoreHits)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config キャッシュの設定
+type Config struct {
+	Enabled      bool          // キャッシュを使用するか（CACHE_ENABLED、デフォルト: true）
+	Capacity     int           // キャッシュごとのメモリ上の最大エントリ数（CACHE_CAPACITY、デフォルト: 1000）
+	Persist      bool          // 永続ストアを使用するか（CACHE_PERSIST、デフォルト: false）
+	GeocodingTTL time.Duration // Text Searchの結果の有効期間（CACHE_TTL_GEOCODING、デフォルト: 24h）
+	NearbyTTL    time.Duration // Nearby Searchの結果の有効期間（CACHE_TTL_NEARBY、デフォルト: 6h）
+	DetailsTTL   time.Duration // Place Detailsの結果の有効期間（CACHE_TTL_DETAILS、デフォルト: 24h）
+}
+
+// LoadConfig 環境変数からキャッシュの設定を読み込む（未設定の項目は既定値）
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		Enabled:      true,
+		Capacity:     1000,
+		Persist:      false,
+		GeocodingTTL: 24 * time.Hour,
+		NearbyTTL:    6 * time.Hour,
+		DetailsTTL:   24 * time.Hour,
+	}
+
+	var err error
+	if cfg.Enabled, err = envBool("CACHE_ENABLED", cfg.Enabled); err != nil {
+		return cfg, err
+	}
+	if cfg.Persist, err = envBool("CACHE_PERSIST", cfg.Persist); err != nil {
+		return cfg, err
+	}
+	if v := os.Getenv("CACHE_CAPACITY"); v != "" {
+		capacity, err := strconv.Atoi(v)
+		if err != nil || capacity <= 0 {
+			return cfg, fmt.Errorf("CACHE_CAPACITY must be a positive integer: %s", v)
+		}
+		cfg.Capacity = capacity
+	}
+	if cfg.GeocodingTTL, err = envDuration("CACHE_TTL_GEOCODING", cfg.GeocodingTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.NearbyTTL, err = envDuration("CACHE_TTL_NEARBY", cfg.NearbyTTL); err != nil {
+		return cfg, err
+	}
+	if cfg.DetailsTTL, err = envDuration("CACHE_TTL_DETAILS", cfg.DetailsTTL); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// envBool 環境変数を真偽値として読み込む（"1"/"true"など、未設定の場合は既定値）
+func envBool(name string, def bool) (bool, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def, fmt.Errorf("%s must be a boolean: %s", name, v)
+	}
+	return b, nil
+}
+
+// envDuration 環境変数を期間として読み込む（"30m"/"24h"など、未設定の場合は既定値）
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return def, fmt.Errorf("%s must be a positive duration (e.g. \"24h\"): %s", name, v)
+	}
+	return d, nil
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU 有効期限付きのLRUキャッシュ（複数のgoroutineから安全に利用できる）
+// 容量を超えた場合は最も長く参照されていないエントリを削除する
+type LRU struct {
+	mu        sync.Mutex
+	capacity  int
+	ll        *list.List
+	items     map[string]*list.Element
+	evictions uint64
+	now       func() time.Time
+}
+
+// lruEntry LRUキャッシュの1エントリ
+type lruEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewLRU 新しいLRUを作成（capacityが0以下の場合は1とする）
+func NewLRU(capacity int) *LRU {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &LRU{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// Get キーに対応する値を取得する（期限切れの場合は削除してfalseを返す）
+func (c *LRU) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set キーに値を保存する（既に存在する場合は値と有効期限を更新する）
+func (c *LRU) Set(key string, value []byte, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+		c.evictions++
+	}
+}
+
+// Len 保持しているエントリ数（期限切れで未削除のものを含む）
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// Evictions 容量超過で削除したエントリ数
+func (c *LRU) Evictions() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
+}
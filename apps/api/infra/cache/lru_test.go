@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// lruKeys 最近参照された順のキー
+func lruKeys(c *LRU) []string {
+	var keys []string
+	for elem := c.ll.Front(); elem != nil; elem = elem.Next() {
+		keys = append(keys, elem.Value.(*lruEntry).key)
+	}
+	return keys
+}
+
+func TestLRUEviction(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	tests := []struct {
+		name          string
+		capacity      int
+		ops           []string // "set:キー" または "get:キー"
+		wantKeys      []string
+		wantEvictions uint64
+	}{
+		{
+			name:     "容量以内",
+			capacity: 3,
+			ops:      []string{"set:a", "set:b", "set:c"},
+			wantKeys: []string{"c", "b", "a"},
+		},
+		{
+			name:          "最も古いエントリを削除する",
+			capacity:      2,
+			ops:           []string{"set:a", "set:b", "set:c"},
+			wantKeys:      []string{"c", "b"},
+			wantEvictions: 1,
+		},
+		{
+			name:          "参照したエントリは残す",
+			capacity:      2,
+			ops:           []string{"set:a", "set:b", "get:a", "set:c"},
+			wantKeys:      []string{"c", "a"},
+			wantEvictions: 1,
+		},
+		{
+			name:          "更新したエントリは残す",
+			capacity:      2,
+			ops:           []string{"set:a", "set:b", "set:a", "set:c"},
+			wantKeys:      []string{"c", "a"},
+			wantEvictions: 1,
+		},
+		{
+			name:          "容量が0以下の場合は1件だけ保持する",
+			capacity:      0,
+			ops:           []string{"set:a", "set:b"},
+			wantKeys:      []string{"b"},
+			wantEvictions: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLRU(tt.capacity)
+			for _, op := range tt.ops {
+				key := op[4:]
+				switch op[:4] {
+				case "set:":
+					c.Set(key, []byte(key), expiresAt)
+				case "get:":
+					c.Get(key)
+				}
+			}
+			if got := lruKeys(c); !reflect.DeepEqual(got, tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", got, tt.wantKeys)
+			}
+			if got := c.Len(); got != len(tt.wantKeys) {
+				t.Errorf("Len = %d, want %d", got, len(tt.wantKeys))
+			}
+			if got := c.Evictions(); got != tt.wantEvictions {
+				t.Errorf("Evictions = %d, want %d", got, tt.wantEvictions)
+			}
+		})
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	base := time.Date(2025, 4, 5, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		wantOK  bool
+	}{
+		{"有効期限前", 59 * time.Minute, true},
+		{"有効期限ちょうど", time.Hour, false},
+		{"有効期限後", 2 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLRU(2)
+			now := base
+			c.now = func() time.Time { return now }
+			c.Set("a", []byte("value"), base.Add(time.Hour))
+
+			now = base.Add(tt.elapsed)
+			value, ok := c.Get("a")
+			if ok != tt.wantOK {
+				t.Fatalf("Get ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && string(value) != "value" {
+				t.Errorf("Get = %q, want %q", value, "value")
+			}
+			// 期限切れのエントリは取得時に削除する（容量超過による削除には数えない）
+			wantLen := 1
+			if !tt.wantOK {
+				wantLen = 0
+			}
+			if got := c.Len(); got != wantLen {
+				t.Errorf("Len = %d, want %d", got, wantLen)
+			}
+			if got := c.Evictions(); got != 0 {
+				t.Errorf("Evictions = %d, want 0", got)
+			}
+		})
+	}
+}
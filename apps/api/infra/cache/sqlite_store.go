@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IStore 再起動後も保持される永続キャッシュストアのインターフェース
+type IStore interface {
+	Get(key string) (value []byte, expiresAt time.Time, found bool, err error)
+	Set(key string, value []byte, expiresAt time.Time) error
+	DeleteExpired() error
+}
+
+// SQLiteStore SQLiteのcache_entriesテーブルを使用した永続キャッシュストア
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore 新しいSQLiteStoreを作成
+func NewSQLiteStore(db *sql.DB) IStore {
+	return &SQLiteStore{
+		db: db,
+	}
+}
+
+// Get キーに対応する値と有効期限を取得する
+func (s *SQLiteStore) Get(key string) ([]byte, time.Time, bool, error) {
+	var value []byte
+	var expiresAt int64
+	err := s.db.QueryRow(`SELECT value, expires_at FROM cache_entries WHERE cache_key = ?`, key).Scan(&value, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("failed to get cache entry: %w", err)
+	}
+	return value, time.UnixMilli(expiresAt), true, nil
+}
+
+// Set キーに値を保存する（既に存在する場合は上書きする）
+func (s *SQLiteStore) Set(key string, value []byte, expiresAt time.Time) error {
+	_, err := s.db.Exec(
+		`INSERT INTO cache_entries (cache_key, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(cache_key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at`,
+		key, value, expiresAt.UnixMilli(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set cache entry: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired 期限切れのエントリを削除する
+func (s *SQLiteStore) DeleteExpired() error {
+	if _, err := s.db.Exec(`DELETE FROM cache_entries WHERE expires_at <= ?`, time.Now().UnixMilli()); err != nil {
+		return fmt.Errorf("failed to delete expired cache entries: %w", err)
+	}
+	return nil
+}
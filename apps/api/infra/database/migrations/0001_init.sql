@@ -0,0 +1,38 @@
+-- docs/database.md のスキーマ定義
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS trips (
+    id TEXT PRIMARY KEY,
+    user_id TEXT REFERENCES users(id),
+    title TEXT NOT NULL DEFAULT '',
+    start_time TEXT NOT NULL DEFAULT '10:00',
+    created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_trips_user_id ON trips(user_id);
+
+CREATE TABLE IF NOT EXISTS trip_places (
+    id TEXT PRIMARY KEY,
+    trip_id TEXT NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    place_id TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    lat REAL NOT NULL DEFAULT 0,
+    lng REAL NOT NULL DEFAULT 0,
+    kind TEXT NOT NULL,
+    stay_minutes INTEGER NOT NULL DEFAULT 60,
+    order_index INTEGER NOT NULL DEFAULT 0,
+    reason TEXT NOT NULL DEFAULT '',
+    review_summary TEXT NOT NULL DEFAULT '',
+    photo_url TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_trip_places_trip_id ON trip_places(trip_id);
+
+CREATE TABLE IF NOT EXISTS shares (
+    share_id TEXT PRIMARY KEY,
+    trip_id TEXT NOT NULL UNIQUE REFERENCES trips(id) ON DELETE CASCADE,
+    created_at TEXT NOT NULL
+);
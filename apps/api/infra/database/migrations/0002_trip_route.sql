@@ -0,0 +1,2 @@
+-- 共有ページで再計算せずにルートを返せるよう、最後に計算したルートをJSONで保存する
+ALTER TABLE trips ADD COLUMN route_json TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+-- 外部APIのレスポンスキャッシュ（再起動後も保持する永続キャッシュ）
+CREATE TABLE IF NOT EXISTS cache_entries (
+    cache_key TEXT PRIMARY KEY,       -- 名前空間:キー
+    value BLOB NOT NULL,              -- JSONエンコードした値
+    expires_at INTEGER NOT NULL       -- 有効期限（UNIXミリ秒）
+);
+
+CREATE INDEX IF NOT EXISTS idx_cache_entries_expires_at ON cache_entries(expires_at);
@@ -0,0 +1,7 @@
+-- 外部APIの呼び出し回数（SKUごと・日/月ごとの集計、予算の判定に使用する）
+CREATE TABLE IF NOT EXISTS api_usage (
+    sku TEXT NOT NULL,                -- 課金単位（text_search, nearby_search など）
+    period TEXT NOT NULL,             -- 集計期間（日: 2006-01-02、月: 2006-01、日本時間）
+    count INTEGER NOT NULL DEFAULT 0, -- 呼び出し回数
+    PRIMARY KEY (sku, period)
+);
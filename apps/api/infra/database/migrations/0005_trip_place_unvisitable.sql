@@ -0,0 +1,2 @@
+-- 営業時間内に訪問できずルートから除外したスポットも旅程に残すため、除外した理由を保存する（訪問するスポットは空）
+ALTER TABLE trip_places ADD COLUMN unvisitable_reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- 再計算でも同じ旅行日の営業時間・交通状況を使えるよう、旅行日を保存する（未指定の場合は空）
+ALTER TABLE trips ADD COLUMN date TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNewSQLiteDBMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "test.db")
+
+	// 同じデータベースを2回開いても、適用済みのマイグレーションは再実行しない
+	for i := 0; i < 2; i++ {
+		db, err := NewSQLiteDB(path)
+		if err != nil {
+			t.Fatalf("NewSQLiteDB (%d回目): %v", i+1, err)
+		}
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count); err != nil {
+			t.Fatalf("count schema_migrations: %v", err)
+		}
+		entries, err := migrationFiles.ReadDir("migrations")
+		if err != nil {
+			t.Fatalf("ReadDir: %v", err)
+		}
+		if count != len(entries) {
+			t.Errorf("schema_migrations = %d, want %d", count, len(entries))
+		}
+		db.Close()
+	}
+
+	db, err := NewSQLiteDB(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteDB: %v", err)
+	}
+	defer db.Close()
+
+	// 後から追加したマイグレーションの列が存在する
+	tests := []struct {
+		name   string
+		table  string
+		column string
+	}{
+		{"ルート", "trips", "route_json"},
+		{"旅行日", "trips", "date"},
+		{"訪問できない理由", "trip_places", "unvisitable_reason"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var count int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, tt.table, tt.column).Scan(&count); err != nil {
+				t.Fatalf("table_info: %v", err)
+			}
+			if count != 1 {
+				t.Errorf("%s.%s does not exist", tt.table, tt.column)
+			}
+		})
+	}
+}
+
+func TestNewSQLiteDBEmptyPath(t *testing.T) {
+	if _, err := NewSQLiteDB(""); err == nil {
+		t.Error("NewSQLiteDB(\"\") error = nil, want error")
+	}
+}
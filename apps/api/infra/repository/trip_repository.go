@@ -0,0 +1,356 @@
+package repository
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fukuoka-ai-api/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound 指定されたレコードが存在しない
+var ErrNotFound = errors.New("record not found")
+
+// ITripRepository 旅程の永続化リポジトリのインターフェース
+type ITripRepository interface {
+	EnsureUser(userID string) error
+	CreateTrip(trip *models.Trip) error
+	GetTrip(tripID string) (*models.Trip, error)
+	UpdateTripStartTime(tripID string, startTime string) error
+	UpdateTripDate(tripID string, date string) error
+	SaveTripRoute(tripID string, route *models.Route) error
+	GetTripRoute(tripID string) (*models.Route, error)
+	ListTripPlaces(tripID string) ([]models.TripPlace, error)
+	AddTripPlace(place *models.TripPlace) error
+	ReplaceTripPlaces(tripID string, places []models.TripPlace) error
+	CreateShare(share *models.Share) error
+	GetShare(shareID string) (*models.Share, error)
+	GetShareByTripID(tripID string) (*models.Share, error)
+	DeleteShare(shareID string) error
+}
+
+// TripRepository SQLiteを使用した旅程リポジトリ
+type TripRepository struct {
+	db *sql.DB
+}
+
+// NewTripRepository 新しいTripRepositoryを作成
+func NewTripRepository(db *sql.DB) ITripRepository {
+	return &TripRepository{
+		db: db,
+	}
+}
+
+// now 現在時刻をISO8601形式で返す
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// EnsureUser ユーザーが存在しない場合に作成する
+func (r *TripRepository) EnsureUser(userID string) error {
+	if userID == "" {
+		return nil
+	}
+	_, err := r.db.Exec(`INSERT OR IGNORE INTO users (id, created_at) VALUES (?, ?)`, userID, now())
+	if err != nil {
+		return fmt.Errorf("failed to ensure user: %w", err)
+	}
+	return nil
+}
+
+// CreateTrip 旅程を作成する（ID・作成日時が空の場合は採番する）
+func (r *TripRepository) CreateTrip(trip *models.Trip) error {
+	if trip.ID == "" {
+		trip.ID = uuid.NewString()
+	}
+	if trip.CreatedAt == "" {
+		trip.CreatedAt = now()
+	}
+	if trip.StartTime == "" {
+		trip.StartTime = models.DefaultTripStartTime
+	}
+
+	// user_idは匿名利用の場合NULLにする
+	var userID sql.NullString
+	if trip.UserID != "" {
+		if err := r.EnsureUser(trip.UserID); err != nil {
+			return err
+		}
+		userID = sql.NullString{String: trip.UserID, Valid: true}
+	}
+
+	_, err := r.db.Exec(
+		`INSERT INTO trips (id, user_id, title, start_time, date, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		trip.ID, userID, trip.Title, trip.StartTime, trip.Date, trip.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create trip: %w", err)
+	}
+	return nil
+}
+
+// GetTrip 旅程を取得する
+func (r *TripRepository) GetTrip(tripID string) (*models.Trip, error) {
+	var trip models.Trip
+	var userID sql.NullString
+	err := r.db.QueryRow(
+		`SELECT id, user_id, title, start_time, date, created_at FROM trips WHERE id = ?`, tripID,
+	).Scan(&trip.ID, &userID, &trip.Title, &trip.StartTime, &trip.Date, &trip.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip: %w", err)
+	}
+	trip.UserID = userID.String
+	return &trip, nil
+}
+
+// UpdateTripStartTime 旅程の開始時刻を更新する
+func (r *TripRepository) UpdateTripStartTime(tripID string, startTime string) error {
+	res, err := r.db.Exec(`UPDATE trips SET start_time = ? WHERE id = ?`, startTime, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to update trip start time: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateTripDate 旅程の旅行日を更新する
+func (r *TripRepository) UpdateTripDate(tripID string, date string) error {
+	res, err := r.db.Exec(`UPDATE trips SET date = ? WHERE id = ?`, date, tripID)
+	if err != nil {
+		return fmt.Errorf("failed to update trip date: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SaveTripRoute 旅程の最新のルートを保存する
+// 座標列（path）はポリラインから復元できるため保存しない
+func (r *TripRepository) SaveTripRoute(tripID string, route *models.Route) error {
+	stored := *route
+	stored.Path = nil
+	stored.Legs = make([]models.RouteLeg, len(route.Legs))
+	for i, leg := range route.Legs {
+		leg.Path = nil
+		stored.Legs[i] = leg
+	}
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal route: %w", err)
+	}
+	res, err := r.db.Exec(`UPDATE trips SET route_json = ? WHERE id = ?`, string(data), tripID)
+	if err != nil {
+		return fmt.Errorf("failed to save trip route: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetTripRoute 旅程に保存されたルートを取得する（未計算の場合はnil）
+// 座標列（path）は含まないため、必要な場合は呼び出し元でポリラインから復元する
+func (r *TripRepository) GetTripRoute(tripID string) (*models.Route, error) {
+	var data string
+	err := r.db.QueryRow(`SELECT route_json FROM trips WHERE id = ?`, tripID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip route: %w", err)
+	}
+	if data == "" {
+		return nil, nil
+	}
+
+	var route models.Route
+	if err := json.Unmarshal([]byte(data), &route); err != nil {
+		return nil, fmt.Errorf("failed to parse trip route: %w", err)
+	}
+	return &route, nil
+}
+
+// ListTripPlaces 旅程に含まれるスポットをorder_index順に取得する
+func (r *TripRepository) ListTripPlaces(tripID string) ([]models.TripPlace, error) {
+	rows, err := r.db.Query(
+		`SELECT id, trip_id, place_id, name, lat, lng, kind, stay_minutes, order_index, reason, review_summary, photo_url, unvisitable_reason
+		FROM trip_places WHERE trip_id = ? ORDER BY order_index, rowid`, tripID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trip places: %w", err)
+	}
+	defer rows.Close()
+
+	places := []models.TripPlace{}
+	for rows.Next() {
+		var p models.TripPlace
+		if err := rows.Scan(&p.ID, &p.TripID, &p.PlaceID, &p.Name, &p.Lat, &p.Lng, &p.Kind,
+			&p.StayMinutes, &p.OrderIndex, &p.Reason, &p.ReviewSummary, &p.PhotoURL, &p.UnvisitableReason); err != nil {
+			return nil, fmt.Errorf("failed to scan trip place: %w", err)
+		}
+		places = append(places, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list trip places: %w", err)
+	}
+	return places, nil
+}
+
+// AddTripPlace 旅程の末尾にスポットを追加する
+// 同じplace_idが既に登録されている場合は既存のレコードを返す
+func (r *TripRepository) AddTripPlace(place *models.TripPlace) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var existingID string
+	var existingOrder int
+	err = tx.QueryRow(
+		`SELECT id, order_index FROM trip_places WHERE trip_id = ? AND place_id = ?`,
+		place.TripID, place.PlaceID,
+	).Scan(&existingID, &existingOrder)
+	if err == nil {
+		place.ID = existingID
+		place.OrderIndex = existingOrder
+		return tx.Commit()
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to check trip place: %w", err)
+	}
+
+	var nextOrder int
+	if err := tx.QueryRow(
+		`SELECT COALESCE(MAX(order_index) + 1, 0) FROM trip_places WHERE trip_id = ?`, place.TripID,
+	).Scan(&nextOrder); err != nil {
+		return fmt.Errorf("failed to get next order index: %w", err)
+	}
+
+	if place.ID == "" {
+		place.ID = uuid.NewString()
+	}
+	place.OrderIndex = nextOrder
+
+	if err := insertTripPlace(tx, place); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceTripPlaces 旅程のスポットを与えられた順序で置き換える
+func (r *TripRepository) ReplaceTripPlaces(tripID string, places []models.TripPlace) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM trip_places WHERE trip_id = ?`, tripID); err != nil {
+		return fmt.Errorf("failed to delete trip places: %w", err)
+	}
+
+	for i := range places {
+		p := &places[i]
+		p.TripID = tripID
+		p.OrderIndex = i
+		if p.ID == "" {
+			p.ID = uuid.NewString()
+		}
+		if err := insertTripPlace(tx, p); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// insertTripPlace トランザクション内でスポットを1件挿入する
+func insertTripPlace(tx *sql.Tx, p *models.TripPlace) error {
+	_, err := tx.Exec(
+		`INSERT INTO trip_places (id, trip_id, place_id, name, lat, lng, kind, stay_minutes, order_index, reason, review_summary, photo_url, unvisitable_reason)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ID, p.TripID, p.PlaceID, p.Name, p.Lat, p.Lng, p.Kind,
+		p.StayMinutes, p.OrderIndex, p.Reason, p.ReviewSummary, p.PhotoURL, p.UnvisitableReason,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert trip place: %w", err)
+	}
+	return nil
+}
+
+// newShareID 推測不可能な共有IDを生成する（256bitの乱数をURLセーフなBase64で表現）
+func newShareID() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate share id: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CreateShare 共有情報を作成する（share_idが空の場合は採番する）
+func (r *TripRepository) CreateShare(share *models.Share) error {
+	if share.ShareID == "" {
+		shareID, err := newShareID()
+		if err != nil {
+			return err
+		}
+		share.ShareID = shareID
+	}
+	if share.CreatedAt == "" {
+		share.CreatedAt = now()
+	}
+	_, err := r.db.Exec(
+		`INSERT INTO shares (share_id, trip_id, created_at) VALUES (?, ?, ?)`,
+		share.ShareID, share.TripID, share.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create share: %w", err)
+	}
+	return nil
+}
+
+// GetShare share_idから共有情報を取得する
+func (r *TripRepository) GetShare(shareID string) (*models.Share, error) {
+	return r.getShare(`SELECT share_id, trip_id, created_at FROM shares WHERE share_id = ?`, shareID)
+}
+
+// GetShareByTripID 旅程IDから共有情報を取得する
+func (r *TripRepository) GetShareByTripID(tripID string) (*models.Share, error) {
+	return r.getShare(`SELECT share_id, trip_id, created_at FROM shares WHERE trip_id = ?`, tripID)
+}
+
+func (r *TripRepository) getShare(query string, arg string) (*models.Share, error) {
+	var share models.Share
+	err := r.db.QueryRow(query, arg).Scan(&share.ShareID, &share.TripID, &share.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share: %w", err)
+	}
+	return &share, nil
+}
+
+// DeleteShare 共有情報を削除する
+func (r *TripRepository) DeleteShare(shareID string) error {
+	res, err := r.db.Exec(`DELETE FROM shares WHERE share_id = ?`, shareID)
+	if err != nil {
+		return fmt.Errorf("failed to delete share: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"errors"
+	"fukuoka-ai-api/infra/database"
+	"fukuoka-ai-api/models"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTestRepository 一時ファイルのSQLiteデータベースを使うリポジトリと、作成済みの旅程IDを返す
+func newTestRepository(t *testing.T) (ITripRepository, string) {
+	t.Helper()
+	db, err := database.NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repo := NewTripRepository(db)
+	trip := &models.Trip{UserID: "user-1", Title: "福岡"}
+	if err := repo.CreateTrip(trip); err != nil {
+		t.Fatalf("CreateTrip: %v", err)
+	}
+	return repo, trip.ID
+}
+
+// placeIDs スポットのplace_idとorder_indexを並べる
+func placeIDs(places []models.TripPlace) [][2]interface{} {
+	var ids [][2]interface{}
+	for _, p := range places {
+		ids = append(ids, [2]interface{}{p.PlaceID, p.OrderIndex})
+	}
+	return ids
+}
+
+func TestCreateTrip(t *testing.T) {
+	repo, tripID := newTestRepository(t)
+
+	trip, err := repo.GetTrip(tripID)
+	if err != nil {
+		t.Fatalf("GetTrip: %v", err)
+	}
+	if trip.UserID != "user-1" || trip.StartTime != models.DefaultTripStartTime || trip.Date != "" {
+		t.Errorf("GetTrip = %+v", trip)
+	}
+
+	if err := repo.UpdateTripDate(tripID, "2025-04-06"); err != nil {
+		t.Fatalf("UpdateTripDate: %v", err)
+	}
+	if trip, err := repo.GetTrip(tripID); err != nil || trip.Date != "2025-04-06" {
+		t.Errorf("GetTrip = %+v, %v, want date 2025-04-06", trip, err)
+	}
+
+	if _, err := repo.GetTrip("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTrip(missing) error = %v, want %v", err, ErrNotFound)
+	}
+	if err := repo.UpdateTripDate("missing", "2025-04-06"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateTripDate(missing) error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestAddTripPlace(t *testing.T) {
+	tests := []struct {
+		name string
+		add  []string
+		want [][2]interface{}
+	}{
+		{"末尾に追加する", []string{"a", "b", "c"}, [][2]interface{}{{"a", 0}, {"b", 1}, {"c", 2}}},
+		{"同じplace_idは既存のレコードを返す", []string{"a", "b", "a", "b"}, [][2]interface{}{{"a", 0}, {"b", 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, tripID := newTestRepository(t)
+			added := make([]models.TripPlace, len(tt.add))
+			for i, placeID := range tt.add {
+				added[i] = models.TripPlace{TripID: tripID, PlaceID: placeID, Kind: "must", StayMinutes: 60}
+				if err := repo.AddTripPlace(&added[i]); err != nil {
+					t.Fatalf("AddTripPlace(%s): %v", placeID, err)
+				}
+			}
+			first := map[string]models.TripPlace{}
+			for _, p := range added {
+				if f, ok := first[p.PlaceID]; ok && (p.ID != f.ID || p.OrderIndex != f.OrderIndex) {
+					t.Errorf("AddTripPlace(%s) = %+v, want %+v", p.PlaceID, p, f)
+				} else if !ok {
+					first[p.PlaceID] = p
+				}
+			}
+
+			places, err := repo.ListTripPlaces(tripID)
+			if err != nil {
+				t.Fatalf("ListTripPlaces: %v", err)
+			}
+			if got := placeIDs(places); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListTripPlaces = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceTripPlaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		replace []models.TripPlace
+		want    [][2]interface{}
+	}{
+		{
+			"与えられた順序で置き換える",
+			[]models.TripPlace{{PlaceID: "c", OrderIndex: 5}, {PlaceID: "a", OrderIndex: 0}, {PlaceID: "d"}},
+			[][2]interface{}{{"c", 0}, {"a", 1}, {"d", 2}},
+		},
+		{
+			"訪問できないスポットも順序どおりに保存する",
+			[]models.TripPlace{{PlaceID: "b"}, {PlaceID: "a", UnvisitableReason: "closed"}},
+			[][2]interface{}{{"b", 0}, {"a", 1}},
+		},
+		{"空にする", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, tripID := newTestRepository(t)
+			for _, placeID := range []string{"a", "b"} {
+				if err := repo.AddTripPlace(&models.TripPlace{TripID: tripID, PlaceID: placeID, Kind: "must"}); err != nil {
+					t.Fatalf("AddTripPlace(%s): %v", placeID, err)
+				}
+			}
+
+			for i := range tt.replace {
+				tt.replace[i].Kind = "must"
+			}
+			if err := repo.ReplaceTripPlaces(tripID, tt.replace); err != nil {
+				t.Fatalf("ReplaceTripPlaces: %v", err)
+			}
+			places, err := repo.ListTripPlaces(tripID)
+			if err != nil {
+				t.Fatalf("ListTripPlaces: %v", err)
+			}
+			if got := placeIDs(places); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListTripPlaces = %v, want %v", got, tt.want)
+			}
+			for i, p := range places {
+				if p.ID != tt.replace[i].ID || p.UnvisitableReason != tt.replace[i].UnvisitableReason {
+					t.Errorf("places[%d] = %+v, want %+v", i, p, tt.replace[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSaveTripRoute(t *testing.T) {
+	repo, tripID := newTestRepository(t)
+
+	if route, err := repo.GetTripRoute(tripID); err != nil || route != nil {
+		t.Errorf("GetTripRoute = %v, %v, want nil", route, err)
+	}
+
+	path := []models.Coordinate{{Lat: 33.59, Lng: 130.40}, {Lat: 33.60, Lng: 130.41}}
+	route := &models.Route{
+		Legs:     []models.RouteLeg{{DistanceMeters: 100, Duration: "60s", Polyline: "abc", Path: path}},
+		Duration: "60s",
+		Polyline: "abc",
+		Path:     path,
+	}
+	if err := repo.SaveTripRoute(tripID, route); err != nil {
+		t.Fatalf("SaveTripRoute: %v", err)
+	}
+	if route.Path == nil || route.Legs[0].Path == nil {
+		t.Error("SaveTripRoute modified the given route")
+	}
+
+	// 座標列は保存しない
+	got, err := repo.GetTripRoute(tripID)
+	if err != nil {
+		t.Fatalf("GetTripRoute: %v", err)
+	}
+	want := &models.Route{
+		Legs:     []models.RouteLeg{{DistanceMeters: 100, Duration: "60s", Polyline: "abc"}},
+		Duration: "60s",
+		Polyline: "abc",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetTripRoute = %+v, want %+v", got, want)
+	}
+
+	if err := repo.SaveTripRoute("missing", route); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SaveTripRoute(missing) error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestShare(t *testing.T) {
+	repo, tripID := newTestRepository(t)
+
+	share := &models.Share{TripID: tripID}
+	if err := repo.CreateShare(share); err != nil {
+		t.Fatalf("CreateShare: %v", err)
+	}
+	if len(share.ShareID) != 43 {
+		t.Errorf("ShareID = %q, want 43 characters", share.ShareID)
+	}
+	if got, err := repo.GetShare(share.ShareID); err != nil || !reflect.DeepEqual(got, share) {
+		t.Errorf("GetShare = %+v, %v, want %+v", got, err, share)
+	}
+	if got, err := repo.GetShareByTripID(tripID); err != nil || !reflect.DeepEqual(got, share) {
+		t.Errorf("GetShareByTripID = %+v, %v, want %+v", got, err, share)
+	}
+
+	if err := repo.DeleteShare(share.ShareID); err != nil {
+		t.Fatalf("DeleteShare: %v", err)
+	}
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"削除した共有IDで取得する", func() error { _, err := repo.GetShare(share.ShareID); return err }},
+		{"削除した共有の旅程IDで取得する", func() error { _, err := repo.GetShareByTripID(tripID); return err }},
+		{"削除した共有を再度削除する", func() error { return repo.DeleteShare(share.ShareID) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrNotFound) {
+				t.Errorf("error = %v, want %v", err, ErrNotFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"fukuoka-ai-api/infra/cache"
+	"strings"
+)
+
+// 各サービスのキャッシュは成功した結果のみを保持する（エラーはキャッシュしない）
+
+// CachedGeocodingService IGeocodingServiceの結果をキャッシュするデコレーター
+type CachedGeocodingService struct {
+	inner IGeocodingService
+	cache *cache.Cache
+}
+
+// cachedCoordinates ジオコーディング結果のキャッシュ値
+type cachedCoordinates struct {
+	Lat     float64 `json:"lat"`
+	Lng     float64 `json:"lng"`
+	PlaceID string  `json:"place_id"`
+}
+
+// NewCachedGeocodingService 新しいCachedGeocodingServiceを作成
+func NewCachedGeocodingService(inner IGeocodingService, c *cache.Cache) IGeocodingService {
+	return &CachedGeocodingService{
+		inner: inner,
+		cache: c,
+	}
+}
+
+// GetCoordinates 場所名から座標を取得（キャッシュにない場合のみAPIを呼び出す）
+func (s *CachedGeocodingService) GetCoordinates(ctx context.Context, placeName string) (lat, lng float64, placeID string, err error) {
+	key := strings.TrimSpace(placeName)
+
+	var cached cachedCoordinates
+	if s.cache.Get(ctx, key, &cached) {
+		return cached.Lat, cached.Lng, cached.PlaceID, nil
+	}
+
+	lat, lng, placeID, err = s.inner.GetCoordinates(ctx, placeName)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	s.cache.Set(ctx, key, cachedCoordinates{Lat: lat, Lng: lng, PlaceID: placeID})
+	return lat, lng, placeID, nil
+}
+
+// CachedNearbySearchService INearbySearchServiceの結果をキャッシュするデコレーター
+type CachedNearbySearchService struct {
+	inner INearbySearchService
+	cache *cache.Cache
+}
+
+// NewCachedNearbySearchService 新しいCachedNearbySearchServiceを作成
+func NewCachedNearbySearchService(inner INearbySearchService, c *cache.Cache) INearbySearchService {
+	return &CachedNearbySearchService{
+		inner: inner,
+		cache: c,
+	}
+}
+
+// SearchNearby 周辺の場所を検索（キャッシュにない場合のみAPIを呼び出す）
+// キーは座標（小数点以下5桁、約1m）・半径・興味タグ（指定順）・最大ページ数から作成する
+func (s *CachedNearbySearchService) SearchNearby(ctx context.Context, lat, lng, radius float64, interestTags []string, maxPages int) ([]PlaceResult, error) {
+	key := fmt.Sprintf("%.5f,%.5f,%.0f,%s,%d", lat, lng, radius, strings.Join(interestTags, "\x1f"), maxPages)
+
+	var cached []PlaceResult
+	if s.cache.Get(ctx, key, &cached) {
+		return cached, nil
+	}
+
+	results, err := s.inner.SearchNearby(ctx, lat, lng, radius, interestTags, maxPages)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Set(ctx, key, results)
+	return results, nil
+}
+
+// CachedPlaceDetailsService IPlaceDetailsServiceの結果をキャッシュするデコレーター
+type CachedPlaceDetailsService struct {
+	inner IPlaceDetailsService
+	cache *cache.Cache
+}
+
+// NewCachedPlaceDetailsService 新しいCachedPlaceDetailsServiceを作成
+func NewCachedPlaceDetailsService(inner IPlaceDetailsService, c *cache.Cache) IPlaceDetailsService {
+	return &CachedPlaceDetailsService{
+		inner: inner,
+		cache: c,
+	}
+}
+
+// GetPlaceDetails 場所の詳細情報を取得（キャッシュにない場合のみAPIを呼び出す）
+// 写真URLはphotoReference、営業時間の有無はfieldsによって変わるため、キーに含める
+func (s *CachedPlaceDetailsService) GetPlaceDetails(ctx context.Context, placeID string, photoReference string, fields string) (*PlaceDetails, error) {
+	key := placeID + "|" + photoReference + "|" + fields
+
+	var cached PlaceDetails
+	if s.cache.Get(ctx, key, &cached) {
+		return &cached, nil
+	}
+
+	details, err := s.inner.GetPlaceDetails(ctx, placeID, photoReference, fields)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Set(ctx, key, details)
+	return details, nil
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 外部APIの障害が続いているため、呼び出さずに失敗させた
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// circuitState サーキットブレーカーの状態
+type circuitState int
+
+const (
+	circuitClosed   circuitState = iota // 通常どおり呼び出す
+	circuitOpen                         // 呼び出さずに失敗させる
+	circuitHalfOpen                     // 回復を確認するため1回だけ呼び出す
+)
+
+// String ログ出力用の状態名
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker 外部APIごとのサーキットブレーカー
+// 一時的な失敗（再試行の対象になる失敗）がfailureThreshold回続くとopenになり、openDurationの間は呼び出さずに失敗させる
+// openDuration経過後は1回だけ試し、成功すればclosedに戻り、失敗すれば再びopenになる
+type CircuitBreaker struct {
+	name             string
+	failureThreshold int
+	openDuration     time.Duration
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int       // 連続した一時的な失敗の回数
+	openedAt time.Time // openになった時刻
+	probing  bool      // half-openで試している呼び出しがあるか
+	now      func() time.Time
+}
+
+// NewCircuitBreaker 新しいCircuitBreakerを作成
+func NewCircuitBreaker(name string, failureThreshold int, openDuration time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		name:             name,
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		now:              time.Now,
+	}
+}
+
+// Allow 外部APIを呼び出してよいかを返す
+// trueを返した場合、呼び出し側は結果をSuccess・Failure・Releaseのいずれかで必ず報告する
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.openDuration {
+			return false
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+		return true
+	case circuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// Success 呼び出しが成功した（外部APIが応答した）ことを記録する
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	if b.state != circuitClosed {
+		b.setState(circuitClosed)
+	}
+}
+
+// Failure 一時的な失敗を記録する
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == circuitHalfOpen || b.failures >= b.failureThreshold {
+		b.openedAt = b.now()
+		if b.state != circuitOpen {
+			b.setState(circuitOpen)
+		}
+	}
+}
+
+// Release 成否を判定できなかった呼び出し（呼び出し元のキャンセルなど）を記録せずに終える
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// setState 状態を変更してログに出力する（b.muを保持した状態で呼び出す）
+func (b *CircuitBreaker) setState(state circuitState) {
+	level := slog.LevelInfo
+	if state == circuitOpen {
+		level = slog.LevelWarn
+	}
+	slog.Log(context.Background(), level, "circuit breaker state changed", "upstream", b.name, "from", b.state.String(), "to", state.String())
+	b.state = state
+}
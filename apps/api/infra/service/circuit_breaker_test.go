@@ -0,0 +1,103 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// breakerOp サーキットブレーカーへの操作
+// "allow"はAllowの結果がallowと一致することを確認し、"wait"は時刻をwaitだけ進める
+type breakerOp struct {
+	op    string
+	allow bool
+	wait  time.Duration
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	const openDuration = 10 * time.Second
+	allow := breakerOp{op: "allow", allow: true}
+	deny := breakerOp{op: "allow", allow: false}
+	success := breakerOp{op: "success"}
+	failure := breakerOp{op: "failure"}
+	release := breakerOp{op: "release"}
+	wait := func(d time.Duration) breakerOp { return breakerOp{op: "wait", wait: d} }
+
+	tests := []struct {
+		name      string
+		ops       []breakerOp
+		wantState circuitState
+	}{
+		{
+			name:      "失敗が閾値未満ならclosedのまま",
+			ops:       []breakerOp{allow, failure, allow, failure, allow},
+			wantState: circuitClosed,
+		},
+		{
+			name:      "成功で連続失敗の回数をリセットする",
+			ops:       []breakerOp{allow, failure, allow, failure, allow, success, allow, failure, allow, failure, allow},
+			wantState: circuitClosed,
+		},
+		{
+			name:      "連続失敗が閾値に達するとopen",
+			ops:       []breakerOp{allow, failure, allow, failure, allow, failure, deny},
+			wantState: circuitOpen,
+		},
+		{
+			name:      "openDurationの間は呼び出さない",
+			ops:       []breakerOp{failure, failure, failure, wait(openDuration - time.Second), deny},
+			wantState: circuitOpen,
+		},
+		{
+			name:      "openDuration経過後はhalf-openで1回だけ試す",
+			ops:       []breakerOp{failure, failure, failure, wait(openDuration), allow, deny},
+			wantState: circuitHalfOpen,
+		},
+		{
+			name:      "half-openで成功するとclosed",
+			ops:       []breakerOp{failure, failure, failure, wait(openDuration), allow, success, allow, allow},
+			wantState: circuitClosed,
+		},
+		{
+			name:      "half-openで失敗すると再びopen",
+			ops:       []breakerOp{failure, failure, failure, wait(openDuration), allow, failure, deny, wait(openDuration - time.Second), deny},
+			wantState: circuitOpen,
+		},
+		{
+			name:      "再びopenになった後もopenDuration経過後に試す",
+			ops:       []breakerOp{failure, failure, failure, wait(openDuration), allow, failure, wait(openDuration), allow},
+			wantState: circuitHalfOpen,
+		},
+		{
+			name:      "half-openで成否を判定できなかった場合は次の呼び出しで試す",
+			ops:       []breakerOp{failure, failure, failure, wait(openDuration), allow, release, allow, deny},
+			wantState: circuitHalfOpen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker("test", 3, openDuration)
+			now := time.Date(2025, 4, 5, 10, 0, 0, 0, time.UTC)
+			b.now = func() time.Time { return now }
+
+			for i, op := range tt.ops {
+				switch op.op {
+				case "allow":
+					if got := b.Allow(); got != op.allow {
+						t.Fatalf("ops[%d]: Allow = %v, want %v (state %s)", i, got, op.allow, b.state)
+					}
+				case "success":
+					b.Success()
+				case "failure":
+					b.Failure()
+				case "release":
+					b.Release()
+				case "wait":
+					now = now.Add(op.wait)
+				}
+			}
+			if b.state != tt.wantState {
+				t.Errorf("state = %s, want %s", b.state, tt.wantState)
+			}
+		})
+	}
+}
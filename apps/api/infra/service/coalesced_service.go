@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"fukuoka-ai-api/infra/usage"
+	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/pkg/logging"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 同じキーの呼び出しが同時に行われた場合、1回のHTTPリクエストの結果を共有するデコレーター
+// キャッシュとは独立して動作し、キャッシュの内側（実際のサービス側）に置くことでキャッシュミスの同時発生もまとめられる
+// 共有した結果は呼び出し側で変更されても影響しないよう、呼び出し元ごとに複製して返す
+
+// coalesceLocationPrecision 周辺検索の座標をまとめる精度（小数点以下の桁数、4桁で約11m）
+const coalesceLocationPrecision = 4
+
+// coalescedCall 実行中の共有された呼び出し
+type coalescedCall struct {
+	done      chan struct{}
+	val       interface{}
+	err       error
+	usage     *usage.Recorder // 共有された呼び出しで行った外部APIの呼び出し回数
+	requestID string          // 共有された呼び出しを始めたリクエストのID
+	waiters   int             // 結果を待っている呼び出し元の数
+	ctx       *sharedContext  // 共有された呼び出しのcontext
+}
+
+// callGroup 同じキーの呼び出しをまとめる
+// 共有された呼び出しは最初の呼び出し元のcontextの値を引き継ぐが、キャンセルは引き継がない
+// 期限は待っている呼び出し元の中で最も遅い期限とし、呼び出し元はそれぞれ自分のcontextがキャンセルされた時点で待つのをやめる
+// 待っている呼び出し元がいなくなった時点で共有された呼び出しもキャンセルする
+// 外部APIの呼び出し回数は最初の呼び出し元に計上し、結果を共有した呼び出し元には共有した回数として記録する
+type callGroup struct {
+	mu    sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+// Do keyの呼び出しが実行中であればその結果を待ち、なければfnを実行する
+func (g *callGroup) Do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*coalescedCall)
+	}
+	c, shared := g.calls[key]
+	if shared {
+		c.ctx.extend(ctx)
+	} else {
+		callCtx := newSharedContext(ctx)
+		recordCtx, recorder := usage.WithRecorder(callCtx)
+		c = &coalescedCall{done: make(chan struct{}), usage: recorder, requestID: logging.RequestID(ctx), ctx: callCtx}
+		g.calls[key] = c
+		go func() {
+			defer callCtx.cancel(context.Canceled)
+			c.val, c.err = fn(recordCtx)
+			g.mu.Lock()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+			close(c.done)
+		}()
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		if shared {
+			usage.RecordCoalesced(ctx, c.usage.Counts())
+			slog.DebugContext(ctx, "upstream call coalesced", "shared_with", c.requestID, "usage", c.usage.String())
+		} else {
+			usage.Record(ctx, c.usage.Counts())
+		}
+		return c.val, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// 後から来た呼び出し元がキャンセル済みの呼び出しを待たないよう、先に取り除く
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			c.ctx.cancel(context.Canceled)
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// sharedContext 共有された呼び出しのcontext
+// 最初の呼び出し元のcontextの値を引き継ぎ、期限は待っている呼び出し元の中で最も遅い期限に延ばす
+// 期限のない呼び出し元が加わった場合は期限をなくす（待っている呼び出し元がいなくなった時点でのキャンセルは残る）
+type sharedContext struct {
+	context.Context // 最初の呼び出し元のcontext（キャンセルは引き継がない）
+
+	mu       sync.Mutex
+	done     chan struct{}
+	err      error
+	deadline time.Time // ゼロ値の場合は期限なし
+	timer    *time.Timer
+}
+
+// newSharedContext ctxの値と期限を引き継ぐsharedContextを作成
+func newSharedContext(ctx context.Context) *sharedContext {
+	s := &sharedContext{Context: context.WithoutCancel(ctx), done: make(chan struct{})}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.deadline = deadline
+		s.timer = time.AfterFunc(time.Until(deadline), s.expire)
+	}
+	return s
+}
+
+// extend 呼び出し元ctxの期限がより遅い場合は期限を延ばす
+func (s *sharedContext) extend(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.deadline.IsZero() || s.err != nil {
+		return
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		s.deadline = time.Time{}
+		s.timer.Stop()
+		return
+	}
+	if deadline.After(s.deadline) {
+		s.deadline = deadline
+		s.timer.Reset(time.Until(deadline))
+	}
+}
+
+// expire 期限を過ぎていれば共有された呼び出しをキャンセルする（期限が延びた後に発火した場合は何もしない）
+func (s *sharedContext) expire() {
+	s.mu.Lock()
+	expired := !s.deadline.IsZero() && !time.Now().Before(s.deadline)
+	s.mu.Unlock()
+	if expired {
+		s.cancel(context.DeadlineExceeded)
+	}
+}
+
+// cancel 共有された呼び出しをerrでキャンセルする（2回目以降は何もしない）
+func (s *sharedContext) cancel(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	s.err = err
+	close(s.done)
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+// Deadline 現在の期限を返す
+func (s *sharedContext) Deadline() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deadline, !s.deadline.IsZero()
+}
+
+// Done キャンセルされた時点で閉じるチャネルを返す
+func (s *sharedContext) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err キャンセルの理由（期限切れの場合はcontext.DeadlineExceeded）を返す
+func (s *sharedContext) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// CoalescedGeocodingService 同時に行われた同じ場所名のジオコーディングをまとめるデコレーター
+type CoalescedGeocodingService struct {
+	inner IGeocodingService
+	group callGroup
+}
+
+// NewCoalescedGeocodingService 新しいCoalescedGeocodingServiceを作成
+func NewCoalescedGeocodingService(inner IGeocodingService) IGeocodingService {
+	return &CoalescedGeocodingService{
+		inner: inner,
+	}
+}
+
+// GetCoordinates 場所名から座標を取得（同じ場所名の呼び出しが実行中の場合はその結果を待つ）
+func (s *CoalescedGeocodingService) GetCoordinates(ctx context.Context, placeName string) (lat, lng float64, placeID string, err error) {
+	key := strings.TrimSpace(placeName)
+	v, err := s.group.Do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		lat, lng, placeID, err := s.inner.GetCoordinates(ctx, placeName)
+		if err != nil {
+			return nil, err
+		}
+		return cachedCoordinates{Lat: lat, Lng: lng, PlaceID: placeID}, nil
+	})
+	if err != nil {
+		return 0, 0, "", err
+	}
+	coords := v.(cachedCoordinates)
+	return coords.Lat, coords.Lng, coords.PlaceID, nil
+}
+
+// CoalescedNearbySearchService 同時に行われた同じ条件の周辺検索をまとめるデコレーター
+type CoalescedNearbySearchService struct {
+	inner INearbySearchService
+	group callGroup
+}
+
+// NewCoalescedNearbySearchService 新しいCoalescedNearbySearchServiceを作成
+func NewCoalescedNearbySearchService(inner INearbySearchService) INearbySearchService {
+	return &CoalescedNearbySearchService{
+		inner: inner,
+	}
+}
+
+// SearchNearby 周辺の場所を検索（同じ条件の呼び出しが実行中の場合はその結果を待つ）
+// 座標を丸め、興味タグを並べ替えた条件で検索するため、どの呼び出しが先に実行されても結果は同じになる
+func (s *CoalescedNearbySearchService) SearchNearby(ctx context.Context, lat, lng, radius float64, interestTags []string, maxPages int) ([]PlaceResult, error) {
+	lat = roundTo(lat, coalesceLocationPrecision)
+	lng = roundTo(lng, coalesceLocationPrecision)
+	tags := append([]string(nil), interestTags...)
+	sort.Strings(tags)
+
+	key := fmt.Sprintf("%.*f,%.*f,%.0f,%s,%d",
+		coalesceLocationPrecision, lat, coalesceLocationPrecision, lng, radius, strings.Join(tags, "\x1f"), maxPages)
+	v, err := s.group.Do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return s.inner.SearchNearby(ctx, lat, lng, radius, tags, maxPages)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clonePlaceResults(v.([]PlaceResult)), nil
+}
+
+// CoalescedPlaceDetailsService 同時に行われた同じ場所の詳細取得をまとめるデコレーター
+type CoalescedPlaceDetailsService struct {
+	inner IPlaceDetailsService
+	group callGroup
+}
+
+// NewCoalescedPlaceDetailsService 新しいCoalescedPlaceDetailsServiceを作成
+func NewCoalescedPlaceDetailsService(inner IPlaceDetailsService) IPlaceDetailsService {
+	return &CoalescedPlaceDetailsService{
+		inner: inner,
+	}
+}
+
+// GetPlaceDetails 場所の詳細情報を取得（同じplace_id・取得フィールドの呼び出しが実行中の場合はその結果を待つ）
+func (s *CoalescedPlaceDetailsService) GetPlaceDetails(ctx context.Context, placeID string, photoReference string, fields string) (*PlaceDetails, error) {
+	key := placeID + "|" + fields + "|" + photoReference
+	v, err := s.group.Do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		return s.inner.GetPlaceDetails(ctx, placeID, photoReference, fields)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clonePlaceDetails(v.(*PlaceDetails)), nil
+}
+
+// roundTo 小数点以下digits桁に四捨五入
+func roundTo(v float64, digits int) float64 {
+	p := math.Pow10(digits)
+	return math.Round(v*p) / p
+}
+
+// clonePlaceResults 周辺検索の結果を複製（スライスのフィールドも複製する）
+func clonePlaceResults(results []PlaceResult) []PlaceResult {
+	if results == nil {
+		return nil
+	}
+	cloned := make([]PlaceResult, len(results))
+	for i, r := range results {
+		cloned[i] = r
+		cloned[i].Types = append([]string(nil), r.Types...)
+		cloned[i].MatchedTags = append([]string(nil), r.MatchedTags...)
+	}
+	return cloned
+}
+
+// clonePlaceDetails 場所の詳細情報を複製（営業時間も複製する）
+func clonePlaceDetails(details *PlaceDetails) *PlaceDetails {
+	if details == nil {
+		return nil
+	}
+	cloned := *details
+	if details.OpeningHours != nil {
+		hours := *details.OpeningHours
+		hours.Periods = append([]models.OpeningPeriod(nil), details.OpeningHours.Periods...)
+		hours.WeekdayText = append([]string(nil), details.OpeningHours.WeekdayText...)
+		cloned.OpeningHours = &hours
+	}
+	return &cloned
+}
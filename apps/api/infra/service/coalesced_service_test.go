@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fukuoka-ai-api/infra/usage"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// waitWaiters keyの呼び出しを待っている呼び出し元がn人になるまで待つ
+func waitWaiters(g *callGroup, key string, n int) {
+	for {
+		g.mu.Lock()
+		c := g.calls[key]
+		ready := c != nil && c.waiters == n
+		g.mu.Unlock()
+		if ready {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCallGroupDeadline(t *testing.T) {
+	t.Run("最初の呼び出し元の期限を引き継ぐ", func(t *testing.T) {
+		var g callGroup
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		want, _ := ctx.Deadline()
+
+		_, err := g.Do(ctx, "key", func(ctx context.Context) (interface{}, error) {
+			if got, ok := ctx.Deadline(); !ok || !got.Equal(want) {
+				t.Errorf("Deadline = %v, %v, want %v", got, ok, want)
+			}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("err = %v, want DeadlineExceeded", err)
+		}
+	})
+
+	t.Run("後から来た呼び出し元の期限まで延ばす", func(t *testing.T) {
+		var g callGroup
+		started := make(chan struct{})
+		release := make(chan struct{})
+		fn := func(ctx context.Context) (interface{}, error) {
+			close(started)
+			select {
+			case <-release:
+				return "ok", nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		leaderCtx, cancelLeader := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancelLeader()
+		leaderErr := make(chan error, 1)
+		go func() {
+			_, err := g.Do(leaderCtx, "key", fn)
+			leaderErr <- err
+		}()
+		<-started
+
+		followerCtx, cancelFollower := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelFollower()
+		followerVal := make(chan interface{}, 1)
+		go func() {
+			v, _ := g.Do(followerCtx, "key", fn)
+			followerVal <- v
+		}()
+		waitWaiters(&g, "key", 2)
+		g.mu.Lock()
+		shared := g.calls["key"].ctx
+		g.mu.Unlock()
+		want, _ := followerCtx.Deadline()
+		if got, ok := shared.Deadline(); !ok || !got.Equal(want) {
+			t.Errorf("shared Deadline = %v, %v, want %v", got, ok, want)
+		}
+
+		if err := <-leaderErr; !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("leader err = %v, want DeadlineExceeded", err)
+		}
+		// 最初の呼び出し元の期限を過ぎても、共有された呼び出しは後から来た呼び出し元のために続く
+		close(release)
+		if v := <-followerVal; v != "ok" {
+			t.Errorf("follower val = %v, want ok", v)
+		}
+	})
+
+	t.Run("期限のない呼び出し元は期限を持たない", func(t *testing.T) {
+		var g callGroup
+		_, err := g.Do(context.Background(), "key", func(ctx context.Context) (interface{}, error) {
+			if _, ok := ctx.Deadline(); ok {
+				t.Error("shared context must not have a deadline")
+			}
+			return nil, nil
+		})
+		if err != nil {
+			t.Errorf("err = %v", err)
+		}
+	})
+}
+
+func TestCallGroupUsage(t *testing.T) {
+	var g callGroup
+	meter, err := usage.NewMeter(usage.Config{}, nil)
+	if err != nil {
+		t.Fatalf("NewMeter: %v", err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		if err := meter.Use(ctx, usage.SKUNearbySearch, 2); err != nil {
+			return nil, err
+		}
+		return "ok", nil
+	}
+
+	leaderCtx, leader := usage.WithRecorder(context.Background())
+	followerCtx, follower := usage.WithRecorder(context.Background())
+	done := make(chan struct{}, 2)
+	go func() {
+		g.Do(leaderCtx, "key", fn)
+		done <- struct{}{}
+	}()
+	<-started
+	go func() {
+		g.Do(followerCtx, "key", fn)
+		done <- struct{}{}
+	}()
+	// 後から来た呼び出し元が待ち始めてから共有された呼び出しを終える
+	waitWaiters(&g, "key", 2)
+	close(release)
+	<-done
+	<-done
+
+	tests := []struct {
+		name          string
+		recorder      *usage.Recorder
+		wantCounts    map[usage.SKU]int64
+		wantCoalesced map[usage.SKU]int64
+		wantString    string
+	}{
+		{"最初の呼び出し元に計上する", leader, map[usage.SKU]int64{usage.SKUNearbySearch: 2}, map[usage.SKU]int64{}, "nearby_search=2"},
+		{"結果を共有した呼び出し元には共有した回数を記録する", follower, map[usage.SKU]int64{}, map[usage.SKU]int64{usage.SKUNearbySearch: 2}, "none; coalesced: nearby_search=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.recorder.Counts(); !reflect.DeepEqual(got, tt.wantCounts) {
+				t.Errorf("Counts = %v, want %v", got, tt.wantCounts)
+			}
+			if got := tt.recorder.Coalesced(); !reflect.DeepEqual(got, tt.wantCoalesced) {
+				t.Errorf("Coalesced = %v, want %v", got, tt.wantCoalesced)
+			}
+			if got := tt.recorder.String(); got != tt.wantString {
+				t.Errorf("String = %q, want %q", got, tt.wantString)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Endpoints 外部APIのベースURL
+// ローカルの偽サーバー（cmd/fakegmaps）などに向けることで、APIキーやネットワークなしで実際の呼び出し経路を動かせる
+type Endpoints struct {
+	PlacesBaseURL string // Places API（GOOGLE_PLACES_BASE_URL、デフォルト: https://maps.googleapis.com/maps/api/place）
+	RoutesBaseURL string // Routes API（GOOGLE_ROUTES_BASE_URL、デフォルト: https://routes.googleapis.com）
+	MLBaseURL     string // MLサービス（ML_SERVICE_URL、デフォルト: なし。未設定の場合はfree_textを辞書のみで解釈する）
+}
+
+// LoadEndpoints 環境変数から外部APIのベースURLを読み込む（未設定の項目は既定値）
+func LoadEndpoints() (Endpoints, error) {
+	e := Endpoints{
+		PlacesBaseURL: "https://maps.googleapis.com/maps/api/place",
+		RoutesBaseURL: "https://routes.googleapis.com",
+	}
+
+	for _, item := range []struct {
+		name string
+		dest *string
+	}{
+		{"GOOGLE_PLACES_BASE_URL", &e.PlacesBaseURL},
+		{"GOOGLE_ROUTES_BASE_URL", &e.RoutesBaseURL},
+		{"ML_SERVICE_URL", &e.MLBaseURL},
+	} {
+		v := os.Getenv(item.name)
+		if v == "" {
+			continue
+		}
+		u, err := url.Parse(v)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return e, fmt.Errorf("%s must be an absolute http(s) URL (e.g. \"http://localhost:8090\"): %s", item.name, v)
+		}
+		*item.dest = strings.TrimRight(v, "/")
+	}
+	return e, nil
+}
@@ -1,9 +1,10 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"fukuoka-ai-api/pkg/apperror"
 	"net/http"
 	"net/url"
 	"os"
@@ -11,17 +12,19 @@ import (
 
 // IGeocodingService ジオコーディングサービスのインターフェース
 type IGeocodingService interface {
-	GetCoordinates(placeName string) (lat, lng float64, placeID string, err error)
+	GetCoordinates(ctx context.Context, placeName string) (lat, lng float64, placeID string, err error)
 }
 
 // GeocodingService Google Places Text Search APIを使用したジオコーディングサービス
 type GeocodingService struct {
-	apiKey string
-	client *http.Client
+	apiKey   string
+	baseURL  string
+	upstream *Upstream
 }
 
 // NewGeocodingService 新しいGeocodingServiceを作成
-func NewGeocodingService() IGeocodingService {
+// baseURLはPlaces APIのベースURL（Endpoints.PlacesBaseURL）
+func NewGeocodingService(upstream *Upstream, baseURL string) IGeocodingService {
 	apiKey := os.Getenv("GOOGLE_MAPS_API_KEY")
 	if apiKey == "" {
 		// エラーを返すか、デフォルト値を設定するかは要件による
@@ -29,8 +32,9 @@ func NewGeocodingService() IGeocodingService {
 	}
 
 	return &GeocodingService{
-		apiKey: apiKey,
-		client: &http.Client{},
+		apiKey:   apiKey,
+		baseURL:  baseURL,
+		upstream: upstream,
 	}
 }
 
@@ -51,9 +55,9 @@ type TextSearchResponse struct {
 }
 
 // GetCoordinates 場所名から座標を取得
-func (s *GeocodingService) GetCoordinates(placeName string) (lat, lng float64, placeID string, err error) {
+func (s *GeocodingService) GetCoordinates(ctx context.Context, placeName string) (lat, lng float64, placeID string, err error) {
 	if s.apiKey == "" {
-		return 0, 0, "", fmt.Errorf("GOOGLE_MAPS_API_KEY is not set")
+		return 0, 0, "", errMissingAPIKey()
 	}
 
 	// 博多駅のデフォルト値（place_idも取得するため、通常の検索を実行）
@@ -62,36 +66,34 @@ func (s *GeocodingService) GetCoordinates(placeName string) (lat, lng float64, p
 		return 33.5904, 130.4208, "", nil
 	}
 
-	baseURL := "https://maps.googleapis.com/maps/api/place/textsearch/json"
 	params := url.Values{}
 	params.Add("query", fmt.Sprintf("%s 福岡", placeName))
 	params.Add("key", s.apiKey)
 	params.Add("language", "ja")
 
-	reqURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
-
-	resp, err := s.client.Get(reqURL)
-	if err != nil {
-		return 0, 0, "", fmt.Errorf("failed to call Google Places API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return 0, 0, "", fmt.Errorf("failed to read response: %w", err)
-	}
+	reqURL := fmt.Sprintf("%s/textsearch/json?%s", s.baseURL, params.Encode())
 
 	var result TextSearchResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return 0, 0, "", fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if result.Status != "OK" {
-		return 0, 0, "", fmt.Errorf("Google Places API error: %s - %s", result.Status, result.ErrorMessage)
+	err = s.upstream.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	}, func(statusCode int, body []byte) error {
+		var resp TextSearchResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+		// 該当する場所がない場合は空の結果として扱う
+		if resp.Status != "OK" && resp.Status != "ZERO_RESULTS" {
+			return placesStatusError(resp.Status, resp.ErrorMessage)
+		}
+		result = resp
+		return nil
+	})
+	if err != nil {
+		return 0, 0, "", err
 	}
 
 	if len(result.Results) == 0 {
-		return 0, 0, "", fmt.Errorf("place not found: %s", placeName)
+		return 0, 0, "", apperror.New(apperror.ErrPlaceNotFound, "place not found: %s", placeName).WithCode("GEOCODING_ERROR").WithDetails("%s", placeName)
 	}
 
 	firstResult := result.Results[0]
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"fukuoka-ai-api/infra/tags"
+	"fukuoka-ai-api/models"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// IInterestExtractor 自由記述（free_text）から興味タグと追加の検索キーワードを推定するインターフェース
+type IInterestExtractor interface {
+	Extract(ctx context.Context, text string) (*models.ExtractedInterests, error)
+}
+
+const (
+	// MaxExtractedTags 自由記述から推定する興味タグの上限（タグごとに周辺検索が増えるため）
+	MaxExtractedTags = 3
+	// MaxExtractedKeywords 自由記述から推定する追加の検索キーワードの上限
+	MaxExtractedKeywords = 2
+	// maxKeywordLength 追加の検索キーワードの最大文字数（これより長いものは使わない）
+	maxKeywordLength = 30
+	// dictionaryTagWeight 辞書で推定した興味タグの重み
+	dictionaryTagWeight = 0.7
+)
+
+// DictionaryInterestExtractor タグ定義の表示名・別名・キーワードとの文字列の一致で推定する
+// ネットワークを使わないため、MLサービスが使えない場合の代替として使う
+type DictionaryInterestExtractor struct {
+	tagRegistry *tags.Registry
+}
+
+// NewDictionaryInterestExtractor 新しいDictionaryInterestExtractorを作成
+func NewDictionaryInterestExtractor(tagRegistry *tags.Registry) IInterestExtractor {
+	return &DictionaryInterestExtractor{
+		tagRegistry: tagRegistry,
+	}
+}
+
+// Extract textに含まれるタグの名前・追加の検索キーワードを、textに現れる順に上限まで返す
+func (e *DictionaryInterestExtractor) Extract(ctx context.Context, text string) (*models.ExtractedInterests, error) {
+	normalized := strings.ToLower(text)
+
+	type match struct {
+		value string
+		pos   int
+	}
+	var tagMatches []match
+	for _, tag := range e.tagRegistry.Tags() {
+		names := append([]string{tag.ID, tag.Labels.Ja, tag.Labels.En}, tag.Synonyms...)
+		names = append(names, tag.Keywords...)
+		if pos := firstIndex(normalized, names); pos >= 0 {
+			tagMatches = append(tagMatches, match{tag.ID, pos})
+		}
+	}
+	var keywordMatches []match
+	for _, keyword := range e.tagRegistry.FreeTextKeywords() {
+		if pos := firstIndex(normalized, []string{keyword}); pos >= 0 {
+			keywordMatches = append(keywordMatches, match{keyword, pos})
+		}
+	}
+	sort.SliceStable(tagMatches, func(i, j int) bool { return tagMatches[i].pos < tagMatches[j].pos })
+	sort.SliceStable(keywordMatches, func(i, j int) bool { return keywordMatches[i].pos < keywordMatches[j].pos })
+
+	result := &models.ExtractedInterests{Tags: []models.WeightedTag{}, Keywords: []string{}}
+	for _, m := range tagMatches[:min(len(tagMatches), MaxExtractedTags)] {
+		result.Tags = append(result.Tags, models.WeightedTag{ID: m.value, Weight: dictionaryTagWeight})
+	}
+	for _, m := range keywordMatches[:min(len(keywordMatches), MaxExtractedKeywords)] {
+		result.Keywords = append(result.Keywords, m.value)
+	}
+	return result, nil
+}
+
+// firstIndex namesのいずれかがtext（小文字にしたもの）に最初に現れる位置を返す（現れない場合は-1）
+func firstIndex(text string, names []string) int {
+	pos := -1
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if i := strings.Index(text, name); i >= 0 && (pos < 0 || i < pos) {
+			pos = i
+		}
+	}
+	return pos
+}
+
+// MLInterestExtractor MLサービス（apps/ml）のPOST /extract-interestsで推定する
+type MLInterestExtractor struct {
+	baseURL     string
+	upstream    *Upstream
+	tagRegistry *tags.Registry
+}
+
+// NewMLInterestExtractor 新しいMLInterestExtractorを作成
+// baseURLはMLサービスのベースURL（Endpoints.MLBaseURL）
+// tagRegistryの興味タグを候補としてMLサービスに渡し、結果のタグはタグ定義のIDに揃える
+func NewMLInterestExtractor(upstream *Upstream, baseURL string, tagRegistry *tags.Registry) IInterestExtractor {
+	return &MLInterestExtractor{
+		baseURL:     baseURL,
+		upstream:    upstream,
+		tagRegistry: tagRegistry,
+	}
+}
+
+// mlExtractRequest MLサービスのPOST /extract-interestsのリクエスト
+type mlExtractRequest struct {
+	Text        string          `json:"text"`
+	Tags        []mlInterestTag `json:"tags"` // 推定する興味タグの候補
+	MaxTags     int             `json:"max_tags"`
+	MaxKeywords int             `json:"max_keywords"`
+}
+
+// mlInterestTag MLサービスに渡す興味タグの候補
+type mlInterestTag struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// mlExtractResponse MLサービスのPOST /extract-interestsのレスポンス
+type mlExtractResponse struct {
+	Tags     []models.WeightedTag `json:"tags"`
+	Keywords []string             `json:"keywords"`
+}
+
+// Extract MLサービスでtextから興味を推定する
+// タグ定義にないタグは追加の検索キーワードとして扱い、重みは0〜1に収める
+func (e *MLInterestExtractor) Extract(ctx context.Context, text string) (*models.ExtractedInterests, error) {
+	reqBody := mlExtractRequest{
+		Text:        text,
+		MaxTags:     MaxExtractedTags,
+		MaxKeywords: MaxExtractedKeywords,
+	}
+	for _, tag := range e.tagRegistry.Tags() {
+		reqBody.Tags = append(reqBody.Tags, mlInterestTag{ID: tag.ID, Label: tag.Labels.Ja})
+	}
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var resp mlExtractResponse
+	err = e.upstream.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/extract-interests", bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	}, func(statusCode int, body []byte) error {
+		if statusCode != http.StatusOK {
+			return fmt.Errorf("ML service error: HTTP %d", statusCode)
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ExtractedInterests{Tags: []models.WeightedTag{}, Keywords: []string{}}
+	seen := make(map[string]bool)
+	for _, t := range resp.Tags {
+		tag, ok := e.tagRegistry.Lookup(t.ID)
+		if !ok {
+			resp.Keywords = append(resp.Keywords, t.ID)
+			continue
+		}
+		if seen[tag.ID] || t.Weight <= 0 || len(result.Tags) >= MaxExtractedTags {
+			continue
+		}
+		seen[tag.ID] = true
+		result.Tags = append(result.Tags, models.WeightedTag{ID: tag.ID, Weight: min(t.Weight, 1)})
+	}
+	for _, keyword := range resp.Keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" || utf8.RuneCountInString(keyword) > maxKeywordLength || seen[keyword] || len(result.Keywords) >= MaxExtractedKeywords {
+			continue
+		}
+		seen[keyword] = true
+		result.Keywords = append(result.Keywords, keyword)
+	}
+	return result, nil
+}
+
+// FallbackInterestExtractor primaryで推定できない場合にfallbackで推定する
+type FallbackInterestExtractor struct {
+	primary  IInterestExtractor
+	fallback IInterestExtractor
+}
+
+// NewFallbackInterestExtractor 新しいFallbackInterestExtractorを作成
+func NewFallbackInterestExtractor(primary, fallback IInterestExtractor) IInterestExtractor {
+	return &FallbackInterestExtractor{
+		primary:  primary,
+		fallback: fallback,
+	}
+}
+
+// Extract primaryで推定し、失敗した場合はログを出力してfallbackで推定する
+// 呼び出し元のキャンセル・期限切れの場合はfallbackを使わずにエラーを返す
+func (e *FallbackInterestExtractor) Extract(ctx context.Context, text string) (*models.ExtractedInterests, error) {
+	result, err := e.primary.Extract(ctx, text)
+	if err == nil {
+		return result, nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	slog.WarnContext(ctx, "interest extraction failed, falling back to dictionary", slog.Any("error", err))
+	return e.fallback.Extract(ctx, text)
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fukuoka-ai-api/infra/tags"
+	"fukuoka-ai-api/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestTagRegistry 推定のテストに使う小さなタグ定義
+func newTestTagRegistry(t *testing.T) *tags.Registry {
+	t.Helper()
+	registry, err := tags.Parse([]byte(`{
+		"free_text_keywords": ["レトロ", "古民家", "絶景"],
+		"tags": [
+			{"id": "cafe", "labels": {"ja": "カフェ", "en": "Cafe"}, "synonyms": ["喫茶店", "coffee"]},
+			{"id": "ramen", "labels": {"ja": "ラーメン", "en": "Ramen"}, "keywords": ["とんこつ"]},
+			{"id": "shrine", "labels": {"ja": "神社", "en": "Shrine"}},
+			{"id": "night_view", "labels": {"ja": "夜景", "en": "Night view"}, "synonyms": ["夜の景色"]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return registry
+}
+
+// weighted 重みが同じ興味タグを並べる
+func weighted(weight float64, ids ...string) []models.WeightedTag {
+	result := []models.WeightedTag{}
+	for _, id := range ids {
+		result = append(result, models.WeightedTag{ID: id, Weight: weight})
+	}
+	return result
+}
+
+func TestDictionaryInterestExtractor(t *testing.T) {
+	extractor := NewDictionaryInterestExtractor(newTestTagRegistry(t))
+
+	tests := []struct {
+		name         string
+		text         string
+		wantTags     []string
+		wantKeywords []string
+	}{
+		{"日本語の表示名", "博多でラーメンを食べてから神社に行きたい", []string{"ramen", "shrine"}, []string{}},
+		{"日本語の別名・キーワード", "喫茶店でひと休みしてから、とんこつの店へ", []string{"cafe", "ramen"}, []string{}},
+		{"英語の表示名は大文字・小文字を区別しない", "I'd like to visit a SHRINE and see the night view", []string{"shrine", "night_view"}, []string{}},
+		{"英語の別名", "Good coffee near the station", []string{"cafe"}, []string{}},
+		{"日本語と英語が混ざった文", "coffeeのあとに夜景", []string{"cafe", "night_view"}, []string{}},
+		{"同じタグの複数の名前は1つにまとめる", "カフェ巡り。喫茶店もcoffeeスタンドもCafeも好き", []string{"cafe"}, []string{}},
+		{"文中に現れる順に上限まで", "夜景、神社、ラーメン、カフェ", []string{"night_view", "shrine", "ramen"}, []string{}},
+		{"追加の検索キーワードは文中に現れる順に上限まで", "絶景の見える古民家のレトロなカフェ", []string{"cafe"}, []string{"絶景", "古民家"}},
+		{"同じキーワードの繰り返しは1つにまとめる", "レトロ、レトロ、とにかくレトロ", []string{}, []string{"レトロ"}},
+		{"一致しない", "のんびりしたい", []string{}, []string{}},
+		{"空文字列", "", []string{}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractor.Extract(context.Background(), tt.text)
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			want := &models.ExtractedInterests{Tags: weighted(dictionaryTagWeight, tt.wantTags...), Keywords: tt.wantKeywords}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Extract(%q) = %+v, want %+v", tt.text, got, want)
+			}
+		})
+	}
+}
+
+func TestMLInterestExtractor(t *testing.T) {
+	registry := newTestTagRegistry(t)
+
+	tests := []struct {
+		name     string
+		response string
+		want     *models.ExtractedInterests
+		wantErr  bool
+	}{
+		{
+			name:     "タグはタグ定義のIDに揃える",
+			response: `{"tags": [{"id": "喫茶店", "weight": 0.9}, {"id": "Night view", "weight": 0.6}], "keywords": ["レトロ"]}`,
+			want:     &models.ExtractedInterests{Tags: []models.WeightedTag{{ID: "cafe", Weight: 0.9}, {ID: "night_view", Weight: 0.6}}, Keywords: []string{"レトロ"}},
+		},
+		{
+			name:     "同じタグは最初のものだけを使う",
+			response: `{"tags": [{"id": "cafe", "weight": 0.9}, {"id": "coffee", "weight": 0.5}, {"id": "カフェ", "weight": 1}]}`,
+			want:     &models.ExtractedInterests{Tags: []models.WeightedTag{{ID: "cafe", Weight: 0.9}}, Keywords: []string{}},
+		},
+		{
+			name:     "重みは1までに収め、0以下のタグは使わない",
+			response: `{"tags": [{"id": "ramen", "weight": 1.5}, {"id": "shrine", "weight": 0}, {"id": "cafe", "weight": -0.2}]}`,
+			want:     &models.ExtractedInterests{Tags: []models.WeightedTag{{ID: "ramen", Weight: 1}}, Keywords: []string{}},
+		},
+		{
+			name:     "タグ定義にないタグは追加の検索キーワードにする",
+			response: `{"tags": [{"id": "温泉", "weight": 0.8}, {"id": "shrine", "weight": 0.7}], "keywords": ["レトロ"]}`,
+			want:     &models.ExtractedInterests{Tags: []models.WeightedTag{{ID: "shrine", Weight: 0.7}}, Keywords: []string{"レトロ", "温泉"}},
+		},
+		{
+			name:     "空・長すぎる・重複するキーワードとタグと同じキーワードは使わない",
+			response: fmt.Sprintf(`{"tags": [{"id": "cafe", "weight": 0.9}], "keywords": [" ", %q, "cafe", " 古民家 ", "古民家", "絶景"]}`, strings.Repeat("長", maxKeywordLength+1)),
+			want:     &models.ExtractedInterests{Tags: []models.WeightedTag{{ID: "cafe", Weight: 0.9}}, Keywords: []string{"古民家", "絶景"}},
+		},
+		{
+			name:     "タグ・キーワードは上限まで",
+			response: `{"tags": [{"id": "cafe", "weight": 0.9}, {"id": "ramen", "weight": 0.8}, {"id": "shrine", "weight": 0.7}, {"id": "night_view", "weight": 0.6}], "keywords": ["レトロ", "古民家", "絶景"]}`,
+			want:     &models.ExtractedInterests{Tags: []models.WeightedTag{{ID: "cafe", Weight: 0.9}, {ID: "ramen", Weight: 0.8}, {ID: "shrine", Weight: 0.7}}, Keywords: []string{"レトロ", "古民家"}},
+		},
+		{
+			name:     "推定なし",
+			response: `{"tags": [], "keywords": []}`,
+			want:     &models.ExtractedInterests{Tags: []models.WeightedTag{}, Keywords: []string{}},
+		},
+		{
+			name:     "不正なレスポンス",
+			response: `tags`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req mlExtractRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/extract-interests" {
+					http.NotFound(w, r)
+					return
+				}
+				json.NewDecoder(r.Body).Decode(&req)
+				fmt.Fprint(w, tt.response)
+			}))
+			defer server.Close()
+			upstream := NewUpstream("ml", "", time.Second, ResilienceConfig{MaxAttempts: 1, FailureThreshold: 5, OpenDuration: time.Second}, nil)
+
+			got, err := NewMLInterestExtractor(upstream, server.URL, registry).Extract(context.Background(), "古民家カフェ")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Extract = %+v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract = %+v, want %+v", got, tt.want)
+			}
+
+			// タグ定義の全てのタグを候補として渡す
+			wantReq := mlExtractRequest{
+				Text: "古民家カフェ",
+				Tags: []mlInterestTag{
+					{ID: "cafe", Label: "カフェ"}, {ID: "ramen", Label: "ラーメン"}, {ID: "shrine", Label: "神社"}, {ID: "night_view", Label: "夜景"},
+				},
+				MaxTags:     MaxExtractedTags,
+				MaxKeywords: MaxExtractedKeywords,
+			}
+			if !reflect.DeepEqual(req, wantReq) {
+				t.Errorf("request = %+v, want %+v", req, wantReq)
+			}
+		})
+	}
+
+	// MLサービスがエラーを返した場合はエラー
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	upstream := NewUpstream("ml", "", time.Second, ResilienceConfig{MaxAttempts: 1, FailureThreshold: 5, OpenDuration: time.Second}, nil)
+	if got, err := NewMLInterestExtractor(upstream, server.URL, registry).Extract(context.Background(), "カフェ"); err == nil {
+		t.Errorf("Extract = %+v, want error", got)
+	}
+}
+
+// stubInterestExtractor 決まった結果・エラーを返す推定
+type stubInterestExtractor struct {
+	result *models.ExtractedInterests
+	err    error
+	calls  int
+}
+
+func (s *stubInterestExtractor) Extract(ctx context.Context, text string) (*models.ExtractedInterests, error) {
+	s.calls++
+	return s.result, s.err
+}
+
+func TestFallbackInterestExtractor(t *testing.T) {
+	primaryResult := &models.ExtractedInterests{Tags: weighted(0.9, "cafe"), Keywords: []string{}}
+	fallbackResult := &models.ExtractedInterests{Tags: weighted(dictionaryTagWeight, "ramen"), Keywords: []string{}}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name              string
+		ctx               context.Context
+		primaryErr        error
+		want              *models.ExtractedInterests
+		wantErr           error
+		wantFallbackCalls int
+	}{
+		{"primaryで推定できた場合はfallbackを使わない", context.Background(), nil, primaryResult, nil, 0},
+		{"primaryが失敗した場合はfallbackで推定する", context.Background(), errors.New("ml unavailable"), fallbackResult, nil, 1},
+		{"キャンセルされた場合はfallbackを使わない", canceled, context.Canceled, nil, context.Canceled, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &stubInterestExtractor{err: tt.primaryErr}
+			if tt.primaryErr == nil {
+				primary.result = primaryResult
+			}
+			fallback := &stubInterestExtractor{result: fallbackResult}
+
+			got, err := NewFallbackInterestExtractor(primary, fallback).Extract(tt.ctx, "カフェ")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Extract error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract = %+v, want %+v", got, tt.want)
+			}
+			if fallback.calls != tt.wantFallbackCalls {
+				t.Errorf("fallback calls = %d, want %d", fallback.calls, tt.wantFallbackCalls)
+			}
+		})
+	}
+}
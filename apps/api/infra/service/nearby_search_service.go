@@ -1,181 +1,194 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"fukuoka-ai-api/infra/tags"
+	"fukuoka-ai-api/pkg/parallel"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"time"
 )
 
 // INearbySearchService 周辺検索サービスのインターフェース
 type INearbySearchService interface {
-	SearchNearby(lat, lng, radius float64, interestTags []string) ([]PlaceResult, error)
+	SearchNearby(ctx context.Context, lat, lng, radius float64, interestTags []string, maxPages int) ([]PlaceResult, error)
+}
+
+// MaxNearbySearchPages Nearby Search APIで1回の検索につき取得できるページ数の上限（1ページ最大20件）
+const MaxNearbySearchPages = 3
+
+// pageTokenAttempts next_page_tokenが有効になっていない（INVALID_REQUEST）場合に待ち直す回数の上限
+const pageTokenAttempts = 3
+
+// NearbyPaginationConfig Nearby Searchのページングの設定
+type NearbyPaginationConfig struct {
+	DefaultPages int           // 検索ごとに取得するページ数の既定値（NEARBY_DEFAULT_PAGES、デフォルト: 1）
+	MaxPages     int           // リクエストで指定できるページ数の上限（NEARBY_MAX_PAGES、デフォルト: 3）
+	TokenDelay   time.Duration // next_page_tokenが有効になるまで待つ時間（NEARBY_PAGE_TOKEN_DELAY、デフォルト: 2s）
+}
+
+// LoadNearbyPaginationConfig 環境変数からNearby Searchのページングの設定を読み込む（未設定の項目は既定値）
+func LoadNearbyPaginationConfig() (NearbyPaginationConfig, error) {
+	cfg := NearbyPaginationConfig{
+		DefaultPages: 1,
+		MaxPages:     MaxNearbySearchPages,
+		TokenDelay:   2 * time.Second,
+	}
+
+	for _, item := range []struct {
+		name string
+		dest *int
+	}{
+		{"NEARBY_DEFAULT_PAGES", &cfg.DefaultPages},
+		{"NEARBY_MAX_PAGES", &cfg.MaxPages},
+	} {
+		v := os.Getenv(item.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxNearbySearchPages {
+			return cfg, fmt.Errorf("%s must be an integer between 1 and %d: %s", item.name, MaxNearbySearchPages, v)
+		}
+		*item.dest = n
+	}
+	if cfg.DefaultPages > cfg.MaxPages {
+		return cfg, fmt.Errorf("NEARBY_DEFAULT_PAGES (%d) must not exceed NEARBY_MAX_PAGES (%d)", cfg.DefaultPages, cfg.MaxPages)
+	}
+
+	if v := os.Getenv("NEARBY_PAGE_TOKEN_DELAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("NEARBY_PAGE_TOKEN_DELAY must be a non-negative duration (e.g. \"2s\"): %s", v)
+		}
+		cfg.TokenDelay = d
+	}
+	return cfg, nil
 }
 
 // NearbySearchService Google Places Nearby Search APIを使用した周辺検索サービス
 type NearbySearchService struct {
-	apiKey string
-	client *http.Client
+	apiKey      string
+	baseURL     string
+	upstream    *Upstream
+	concurrency int // 興味タグごとの検索の同時実行数
+	pagination  NearbyPaginationConfig
+	tagRegistry *tags.Registry // 興味タグから検索するタイプ・キーワードを引く
 }
 
 // PlaceResult 周辺検索の結果
 type PlaceResult struct {
-	PlaceID       string   `json:"place_id"`
-	Name          string   `json:"name"`
-	Lat           float64  `json:"lat"`
-	Lng           float64  `json:"lng"`
-	Rating        float64  `json:"rating"`
-	PhotoReference string  `json:"photo_reference,omitempty"`
-	Types         []string `json:"types,omitempty"`
-	MatchedTags   []string `json:"matched_tags,omitempty"` // この結果が見つかった検索タグ
+	PlaceID          string   `json:"place_id"`
+	Name             string   `json:"name"`
+	Lat              float64  `json:"lat"`
+	Lng              float64  `json:"lng"`
+	Rating           float64  `json:"rating"`
+	UserRatingsTotal int      `json:"user_ratings_total,omitempty"` // 評価の件数
+	PhotoReference   string   `json:"photo_reference,omitempty"`
+	Types            []string `json:"types,omitempty"`
+	MatchedTags      []string `json:"matched_tags,omitempty"` // この結果が見つかった検索タグ
 }
 
 // NewNearbySearchService 新しいNearbySearchServiceを作成
-func NewNearbySearchService() INearbySearchService {
+// baseURLはPlaces APIのベースURL（Endpoints.PlacesBaseURL）
+// concurrencyは興味タグごとの検索の同時実行数（1以下の場合は順番に実行）
+// tagRegistryに定義されていない興味タグは、タグ自体をキーワードとして検索する
+func NewNearbySearchService(upstream *Upstream, baseURL string, concurrency int, pagination NearbyPaginationConfig, tagRegistry *tags.Registry) INearbySearchService {
 	apiKey := os.Getenv("GOOGLE_MAPS_API_KEY")
 	return &NearbySearchService{
-		apiKey: apiKey,
-		client: &http.Client{},
+		apiKey:      apiKey,
+		baseURL:     baseURL,
+		upstream:    upstream,
+		concurrency: concurrency,
+		pagination:  pagination,
+		tagRegistry: tagRegistry,
 	}
 }
 
 // NearbySearchResponse Google Places Nearby Search APIのレスポンス
 type NearbySearchResponse struct {
-	Status  string `json:"status"`
-	Results []struct {
-		PlaceID       string   `json:"place_id"`
-		Name          string   `json:"name"`
-		Rating        float64  `json:"rating,omitempty"`
-		UserRatingsTotal int   `json:"user_ratings_total,omitempty"`
-		Geometry      struct {
-			Location struct {
-				Lat float64 `json:"lat"`
-				Lng float64 `json:"lng"`
-			} `json:"location"`
-		} `json:"geometry"`
-		Photos []struct {
-			PhotoReference string `json:"photo_reference"`
-		} `json:"photos,omitempty"`
-		Types []string `json:"types,omitempty"`
-	} `json:"results"`
-	ErrorMessage string `json:"error_message,omitempty"`
+	Status        string               `json:"status"`
+	Results       []nearbySearchResult `json:"results"`
+	NextPageToken string               `json:"next_page_token,omitempty"` // 次のページがある場合のみ
+	ErrorMessage  string               `json:"error_message,omitempty"`
 }
 
-// tagToKeyword 興味タグをGoogle Places APIのキーワード/タイプにマッピング
-func tagToKeyword(tag string) (keyword string, placeType string) {
-	keywordMap := map[string]string{
-		"カフェ":     "cafe",
-		"レストラン":  "restaurant",
-		"神社":      "shrine",
-		"寺":       "temple",
-		"公園":      "park",
-		"自然":      "park",
-		"観光":      "tourist_attraction",
-		"ショッピング": "shopping_mall",
-		"博物館":     "museum",
-		"美術館":     "art_gallery",
-	}
-
-	// タイプとして使用できるもの
-	typeMap := map[string]string{
-		"カフェ":     "cafe",
-		"レストラン":  "restaurant",
-		"神社":      "shrine",
-		"寺":       "temple",
-		"公園":      "park",
-		"自然":      "park",
-		"観光":      "tourist_attraction",
-		"ショッピング": "shopping_mall",
-		"博物館":     "museum",
-		"美術館":     "art_gallery",
-	}
-
-	if k, ok := keywordMap[tag]; ok {
-		if t, ok := typeMap[tag]; ok {
-			return "", t // typeとして使用
-		}
-		return k, ""
-	}
-
-	// デフォルトはキーワードとして使用
-	return tag, ""
+// nearbySearchResult Nearby Search APIのレスポンスの1件
+type nearbySearchResult struct {
+	PlaceID          string  `json:"place_id"`
+	Name             string  `json:"name"`
+	Rating           float64 `json:"rating,omitempty"`
+	UserRatingsTotal int     `json:"user_ratings_total,omitempty"`
+	Geometry         struct {
+		Location struct {
+			Lat float64 `json:"lat"`
+			Lng float64 `json:"lng"`
+		} `json:"location"`
+	} `json:"geometry"`
+	Photos []struct {
+		PhotoReference string `json:"photo_reference"`
+	} `json:"photos,omitempty"`
+	Types []string `json:"types,omitempty"`
 }
 
 // SearchNearby 指定された座標の周辺を検索
-func (s *NearbySearchService) SearchNearby(lat, lng, radius float64, interestTags []string) ([]PlaceResult, error) {
+// 興味タグごとの検索は最大concurrency件まで並列に実行し、結果はタグの指定順にマージする
+// maxPagesはタグごとに取得する最大ページ数（0以下の場合は既定値、設定の上限を超える場合は上限まで）
+// いずれかのタグの検索が（再試行しても）失敗した場合は、一部のタグの結果だけを返さずにエラーを返す
+func (s *NearbySearchService) SearchNearby(ctx context.Context, lat, lng, radius float64, interestTags []string, maxPages int) ([]PlaceResult, error) {
 	if s.apiKey == "" {
-		return nil, fmt.Errorf("GOOGLE_MAPS_API_KEY is not set")
+		return nil, errMissingAPIKey()
 	}
-
-	var allResults []PlaceResult
-	seenPlaceIDs := make(map[string]*PlaceResult) // PlaceResultへのポインタを保持
+	if maxPages <= 0 {
+		maxPages = s.pagination.DefaultPages
+	}
+	maxPages = min(maxPages, s.pagination.MaxPages)
 
 	// 各興味タグで検索
-	for _, tag := range interestTags {
-		keyword, placeType := tagToKeyword(tag)
-
-		baseURL := "https://maps.googleapis.com/maps/api/place/nearbysearch/json"
-		params := url.Values{}
-		params.Add("location", fmt.Sprintf("%.6f,%.6f", lat, lng))
-		params.Add("radius", fmt.Sprintf("%.0f", radius))
-		params.Add("key", s.apiKey)
-		params.Add("language", "ja")
-
-		if placeType != "" {
-			params.Add("type", placeType)
-		} else if keyword != "" {
-			params.Add("keyword", keyword)
-		} else {
-			params.Add("keyword", tag)
-		}
-
-		reqURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
-
-		resp, err := s.client.Get(reqURL)
-		if err != nil {
-			continue // エラーが発生しても次のタグで続行
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			continue
-		}
-
-		var result NearbySearchResponse
-		if err := json.Unmarshal(body, &result); err != nil {
-			continue
+	resultsByTag, errs := parallel.Map(ctx, s.concurrency, interestTags, func(_ int, tag string) ([]nearbySearchResult, error) {
+		return s.searchByTag(ctx, lat, lng, radius, tag, maxPages)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for i, tag := range interestTags {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("興味タグ「%s」の検索に失敗しました: %w", tag, errs[i])
 		}
+	}
 
-		if result.Status != "OK" {
-			continue
-		}
+	var allResults []PlaceResult
+	seenPlaceIDs := make(map[string]int) // place_id → allResultsのインデックス
 
+	for i, tag := range interestTags {
 		// 結果を追加（重複排除、タグ情報を記録）
-		for _, r := range result.Results {
-			if existing, exists := seenPlaceIDs[r.PlaceID]; exists {
+		for _, r := range resultsByTag[i] {
+			if idx, exists := seenPlaceIDs[r.PlaceID]; exists {
 				// 既存の結果にタグを追加
-				existing.MatchedTags = append(existing.MatchedTags, tag)
+				allResults[idx].MatchedTags = append(allResults[idx].MatchedTags, tag)
 			} else {
 				// 新しい結果を作成
 				photoRef := ""
 				if len(r.Photos) > 0 {
 					photoRef = r.Photos[0].PhotoReference
 				}
-				newResult := PlaceResult{
-					PlaceID:        r.PlaceID,
-					Name:           r.Name,
-					Lat:            r.Geometry.Location.Lat,
-					Lng:            r.Geometry.Location.Lng,
-					Rating:         r.Rating,
-					PhotoReference: photoRef,
-					Types:          r.Types,
-					MatchedTags:    []string{tag},
-				}
-				allResults = append(allResults, newResult)
-				seenPlaceIDs[r.PlaceID] = &allResults[len(allResults)-1]
+				allResults = append(allResults, PlaceResult{
+					PlaceID:          r.PlaceID,
+					Name:             r.Name,
+					Lat:              r.Geometry.Location.Lat,
+					Lng:              r.Geometry.Location.Lng,
+					Rating:           r.Rating,
+					UserRatingsTotal: r.UserRatingsTotal,
+					PhotoReference:   photoRef,
+					Types:            r.Types,
+					MatchedTags:      []string{tag},
+				})
+				seenPlaceIDs[r.PlaceID] = len(allResults) - 1
 			}
 		}
 	}
@@ -183,3 +196,107 @@ func (s *NearbySearchService) SearchNearby(lat, lng, radius float64, interestTag
 	return allResults, nil
 }
 
+// searchByTag 1つの興味タグでNearby Search APIを呼び出し、next_page_tokenをたどって最大maxPagesページ分の結果を返す
+// 結果はページ順に並び、同じ場所が複数のページに含まれる場合は最初のものだけを残す
+func (s *NearbySearchService) searchByTag(ctx context.Context, lat, lng, radius float64, tag string, maxPages int) ([]nearbySearchResult, error) {
+	keyword, placeType := s.tagRegistry.SearchQuery(tag)
+
+	params := url.Values{}
+	params.Add("location", fmt.Sprintf("%.6f,%.6f", lat, lng))
+	params.Add("radius", fmt.Sprintf("%.0f", radius))
+	params.Add("key", s.apiKey)
+	params.Add("language", "ja")
+
+	if placeType != "" {
+		params.Add("type", placeType)
+	} else {
+		params.Add("keyword", keyword)
+	}
+
+	page, err := s.fetchPage(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	results := page.Results
+	seen := make(map[string]bool, len(results))
+	for _, r := range results {
+		seen[r.PlaceID] = true
+	}
+
+	for pageNum := 2; pageNum <= maxPages && page.NextPageToken != ""; pageNum++ {
+		page, err = s.fetchNextPage(ctx, page.NextPageToken)
+		if err != nil {
+			return nil, fmt.Errorf("%dページ目の取得に失敗しました: %w", pageNum, err)
+		}
+		for _, r := range page.Results {
+			if !seen[r.PlaceID] {
+				seen[r.PlaceID] = true
+				results = append(results, r)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// fetchNextPage next_page_tokenで次のページを取得する
+// トークンは発行から有効になるまで時間がかかるため、待ってから取得し、まだ有効でない（INVALID_REQUEST）場合は待ち直す
+func (s *NearbySearchService) fetchNextPage(ctx context.Context, token string) (*NearbySearchResponse, error) {
+	params := url.Values{}
+	params.Add("pagetoken", token)
+	params.Add("key", s.apiKey)
+
+	var lastErr error
+	for attempt := 0; attempt < pageTokenAttempts; attempt++ {
+		timer := time.NewTimer(s.pagination.TokenDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		page, err := s.fetchPage(ctx, params)
+		if err == nil {
+			return page, nil
+		}
+		if !errors.Is(err, errPageTokenNotReady) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// errPageTokenNotReady next_page_tokenがまだ有効になっていない
+var errPageTokenNotReady = errors.New("next_page_token is not ready yet")
+
+// fetchPage Nearby Search APIを1回呼び出す
+func (s *NearbySearchService) fetchPage(ctx context.Context, params url.Values) (*NearbySearchResponse, error) {
+	reqURL := fmt.Sprintf("%s/nearbysearch/json?%s", s.baseURL, params.Encode())
+	isTokenRequest := params.Has("pagetoken")
+
+	var result NearbySearchResponse
+	err := s.upstream.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	}, func(statusCode int, body []byte) error {
+		var resp NearbySearchResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+		if isTokenRequest && resp.Status == "INVALID_REQUEST" {
+			return errPageTokenNotReady
+		}
+		// 該当する場所がない場合は空の結果として扱う
+		if resp.Status != "OK" && resp.Status != "ZERO_RESULTS" {
+			return placesStatusError(resp.Status, resp.ErrorMessage)
+		}
+		result = resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
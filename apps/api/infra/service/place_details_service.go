@@ -1,103 +1,130 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"fukuoka-ai-api/models"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 )
 
 // IPlaceDetailsService 場所詳細サービスのインターフェース
 type IPlaceDetailsService interface {
-	GetPlaceDetails(placeID string, photoReference string) (*PlaceDetails, error)
+	// GetPlaceDetails fieldsはPlaceDetailsFieldsまたはPlaceDetailsFieldsWithOpeningHours
+	GetPlaceDetails(ctx context.Context, placeID string, photoReference string, fields string) (*PlaceDetails, error)
 }
 
 // PlaceDetails 場所の詳細情報
 type PlaceDetails struct {
-	PlaceID       string  `json:"place_id"`
-	Name          string  `json:"name"`
-	Lat           float64 `json:"lat"`
-	Lng           float64 `json:"lng"`
-	PhotoURL      string  `json:"photo_url"`
-	Rating        float64 `json:"rating"`
-	ReviewSummary string  `json:"review_summary"`
-	Address       string  `json:"address"`
-	Category      string  `json:"category"`
+	PlaceID       string               `json:"place_id"`
+	Name          string               `json:"name"`
+	Lat           float64              `json:"lat"`
+	Lng           float64              `json:"lng"`
+	PhotoURL      string               `json:"photo_url"`
+	Rating        float64              `json:"rating"`
+	ReviewSummary string               `json:"review_summary"`
+	Address       string               `json:"address"`
+	Category      string               `json:"category"`
+	OpeningHours  *models.OpeningHours `json:"opening_hours,omitempty"` // 営業時間（情報がない場合はnil）
 }
 
+// Place Details APIで取得するフィールド
+// opening_hoursはContactデータの料金が適用されるため、営業時間を使う場合のみ取得する
+const (
+	PlaceDetailsFields                 = "place_id,name,rating,formatted_address,geometry,types,reviews"
+	PlaceDetailsFieldsWithOpeningHours = PlaceDetailsFields + ",opening_hours"
+)
+
 // PlaceDetailsService Google Places Place Details APIを使用した詳細取得サービス
 type PlaceDetailsService struct {
-	apiKey string
-	client *http.Client
+	apiKey   string
+	baseURL  string
+	upstream *Upstream
 }
 
 // NewPlaceDetailsService 新しいPlaceDetailsServiceを作成
-func NewPlaceDetailsService() IPlaceDetailsService {
+// baseURLはPlaces APIのベースURL（Endpoints.PlacesBaseURL、写真URLにも使用する）
+func NewPlaceDetailsService(upstream *Upstream, baseURL string) IPlaceDetailsService {
 	apiKey := os.Getenv("GOOGLE_MAPS_API_KEY")
 	return &PlaceDetailsService{
-		apiKey: apiKey,
-		client: &http.Client{},
+		apiKey:   apiKey,
+		baseURL:  baseURL,
+		upstream: upstream,
 	}
 }
 
 // PlaceDetailsResponse Google Places Place Details APIのレスポンス
 type PlaceDetailsResponse struct {
-	Status  string `json:"status"`
-	Result  struct {
-		PlaceID       string   `json:"place_id"`
-		Name          string   `json:"name"`
-		Rating        float64  `json:"rating,omitempty"`
-		FormattedAddress string `json:"formatted_address,omitempty"`
-		Geometry      struct {
+	Status string `json:"status"`
+	Result struct {
+		PlaceID          string  `json:"place_id"`
+		Name             string  `json:"name"`
+		Rating           float64 `json:"rating,omitempty"`
+		FormattedAddress string  `json:"formatted_address,omitempty"`
+		Geometry         struct {
 			Location struct {
 				Lat float64 `json:"lat"`
 				Lng float64 `json:"lng"`
 			} `json:"location"`
 		} `json:"geometry"`
-		Types []string `json:"types,omitempty"`
+		Types   []string `json:"types,omitempty"`
 		Reviews []struct {
-			Text string `json:"text"`
-			Rating int `json:"rating"`
+			Text   string `json:"text"`
+			Rating int    `json:"rating"`
 		} `json:"reviews,omitempty"`
+		OpeningHours *struct {
+			Periods     []OpeningPeriodResponse `json:"periods"`
+			WeekdayText []string                `json:"weekday_text,omitempty"`
+		} `json:"opening_hours,omitempty"`
 	} `json:"result"`
 	ErrorMessage string `json:"error_message,omitempty"`
 }
 
-// GetPlaceDetails 場所の詳細情報を取得
-func (s *PlaceDetailsService) GetPlaceDetails(placeID string, photoReference string) (*PlaceDetails, error) {
+// OpeningPeriodResponse Place Details APIの営業時間帯
+type OpeningPeriodResponse struct {
+	Open  OpeningTime  `json:"open"`
+	Close *OpeningTime `json:"close,omitempty"` // 24時間営業の場合は省略される
+}
+
+// OpeningTime Place Details APIの営業時間の開始・終了（dayは0=日曜日、timeは"HHMM"形式）
+type OpeningTime struct {
+	Day  int    `json:"day"`
+	Time string `json:"time"`
+}
+
+// GetPlaceDetails 場所の詳細情報をfieldsのフィールドで取得
+func (s *PlaceDetailsService) GetPlaceDetails(ctx context.Context, placeID string, photoReference string, fields string) (*PlaceDetails, error) {
 	if s.apiKey == "" {
-		return nil, fmt.Errorf("GOOGLE_MAPS_API_KEY is not set")
+		return nil, errMissingAPIKey()
 	}
 
-	baseURL := "https://maps.googleapis.com/maps/api/place/details/json"
 	params := url.Values{}
 	params.Add("place_id", placeID)
 	params.Add("key", s.apiKey)
 	params.Add("language", "ja")
-	params.Add("fields", "place_id,name,rating,formatted_address,geometry,types,reviews")
-
-	reqURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
+	params.Add("fields", fields)
 
-	resp, err := s.client.Get(reqURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call Google Places API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
+	reqURL := fmt.Sprintf("%s/details/json?%s", s.baseURL, params.Encode())
 
 	var result PlaceDetailsResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	if result.Status != "OK" {
-		return nil, fmt.Errorf("Google Places API error: %s - %s", result.Status, result.ErrorMessage)
+	err := s.upstream.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	}, func(statusCode int, body []byte) error {
+		var resp PlaceDetailsResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+		if resp.Status != "OK" {
+			return placesStatusError(resp.Status, resp.ErrorMessage)
+		}
+		result = resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	details := &PlaceDetails{
@@ -123,13 +150,68 @@ func (s *PlaceDetailsService) GetPlaceDetails(placeID string, photoReference str
 		details.ReviewSummary = reviewText
 	}
 
+	// 営業時間を週単位のスケジュールに変換
+	if result.Result.OpeningHours != nil {
+		details.OpeningHours = parseOpeningHours(result.Result.OpeningHours.Periods, result.Result.OpeningHours.WeekdayText)
+	}
+
 	// 写真URLを生成
 	if photoReference != "" {
-		photoURL := fmt.Sprintf("https://maps.googleapis.com/maps/api/place/photo?maxwidth=400&photoreference=%s&key=%s",
-			photoReference, s.apiKey)
+		photoURL := fmt.Sprintf("%s/photo?maxwidth=400&photoreference=%s&key=%s",
+			s.baseURL, photoReference, s.apiKey)
 		details.PhotoURL = photoURL
 	}
 
 	return details, nil
 }
 
+// parseOpeningHours Place Details APIの営業時間を週単位のスケジュールに変換
+// 閉店時刻のない営業時間帯が含まれる場合は24時間営業として扱う
+// 時刻の形式が不正な営業時間帯は無視し、有効な営業時間帯が1つもない場合はnil（営業時間の情報なし）を返す
+func parseOpeningHours(periods []OpeningPeriodResponse, weekdayText []string) *models.OpeningHours {
+	hours := &models.OpeningHours{
+		Periods:     []models.OpeningPeriod{},
+		WeekdayText: weekdayText,
+	}
+	for _, period := range periods {
+		if period.Close == nil {
+			hours.AlwaysOpen = true
+			hours.Periods = []models.OpeningPeriod{}
+			return hours
+		}
+		openMinute, err := parseOpeningTime(period.Open.Time)
+		if err != nil {
+			continue
+		}
+		closeMinute, err := parseOpeningTime(period.Close.Time)
+		if err != nil {
+			continue
+		}
+		hours.Periods = append(hours.Periods, models.OpeningPeriod{
+			OpenDay:     period.Open.Day,
+			OpenMinute:  openMinute,
+			CloseDay:    period.Close.Day,
+			CloseMinute: closeMinute,
+		})
+	}
+	if len(hours.Periods) == 0 {
+		return nil
+	}
+	return hours
+}
+
+// parseOpeningTime "HHMM"形式の時刻を0時からの分数に変換
+func parseOpeningTime(hhmm string) (int, error) {
+	if len(hhmm) != 4 {
+		return 0, fmt.Errorf("invalid opening time: %s", hhmm)
+	}
+	hour, err := strconv.Atoi(hhmm[:2])
+	if err != nil {
+		return 0, fmt.Errorf("invalid opening time: %s", hhmm)
+	}
+	minute, err := strconv.Atoi(hhmm[2:])
+	if err != nil {
+		return 0, fmt.Errorf("invalid opening time: %s", hhmm)
+	}
+	return hour*60 + minute, nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"fukuoka-ai-api/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseOpeningHours(t *testing.T) {
+	weekdayText := []string{"月曜日: 10時00分～17時00分"}
+	tests := []struct {
+		name    string
+		periods []OpeningPeriodResponse
+		want    *models.OpeningHours
+	}{
+		{
+			name: "営業時間帯",
+			periods: []OpeningPeriodResponse{
+				{Open: OpeningTime{Day: 1, Time: "1000"}, Close: &OpeningTime{Day: 1, Time: "1700"}},
+				{Open: OpeningTime{Day: 5, Time: "1800"}, Close: &OpeningTime{Day: 6, Time: "0200"}},
+			},
+			want: &models.OpeningHours{
+				Periods: []models.OpeningPeriod{
+					{OpenDay: 1, OpenMinute: 600, CloseDay: 1, CloseMinute: 1020},
+					{OpenDay: 5, OpenMinute: 1080, CloseDay: 6, CloseMinute: 120},
+				},
+				WeekdayText: weekdayText,
+			},
+		},
+		{
+			name:    "閉店時刻なしは24時間営業",
+			periods: []OpeningPeriodResponse{{Open: OpeningTime{Day: 0, Time: "0000"}}},
+			want:    &models.OpeningHours{AlwaysOpen: true, Periods: []models.OpeningPeriod{}, WeekdayText: weekdayText},
+		},
+		{
+			name: "不正な営業時間帯は無視する",
+			periods: []OpeningPeriodResponse{
+				{Open: OpeningTime{Day: 1, Time: "10:00"}, Close: &OpeningTime{Day: 1, Time: "1700"}},
+				{Open: OpeningTime{Day: 2, Time: "1000"}, Close: &OpeningTime{Day: 2, Time: "1700"}},
+			},
+			want: &models.OpeningHours{
+				Periods:     []models.OpeningPeriod{{OpenDay: 2, OpenMinute: 600, CloseDay: 2, CloseMinute: 1020}},
+				WeekdayText: weekdayText,
+			},
+		},
+		{
+			name:    "営業時間帯なしは営業時間の情報なし",
+			periods: []OpeningPeriodResponse{},
+			want:    nil,
+		},
+		{
+			name: "全ての営業時間帯が不正な場合は営業時間の情報なし",
+			periods: []OpeningPeriodResponse{
+				{Open: OpeningTime{Day: 1, Time: "10"}, Close: &OpeningTime{Day: 1, Time: "1700"}},
+				{Open: OpeningTime{Day: 2, Time: "1000"}, Close: &OpeningTime{Day: 2, Time: "5pm"}},
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseOpeningHours(tt.periods, weekdayText); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOpeningHours = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPlaceDetailsFields(t *testing.T) {
+	t.Setenv("GOOGLE_MAPS_API_KEY", "testkey")
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Query().Get("fields"))
+		fmt.Fprint(w, `{"status": "OK", "result": {"place_id": "p1", "name": "カフェ"}}`)
+	}))
+	defer server.Close()
+
+	upstream := NewUpstream("test", "", time.Second, ResilienceConfig{MaxAttempts: 1, FailureThreshold: 5, OpenDuration: time.Second}, nil)
+	svc := NewPlaceDetailsService(upstream, server.URL)
+	for _, fields := range []string{PlaceDetailsFields, PlaceDetailsFieldsWithOpeningHours} {
+		if _, err := svc.GetPlaceDetails(context.Background(), "p1", "", fields); err != nil {
+			t.Fatalf("GetPlaceDetails: %v", err)
+		}
+	}
+	want := []string{
+		"place_id,name,rating,formatted_address,geometry,types,reviews",
+		"place_id,name,rating,formatted_address,geometry,types,reviews,opening_hours",
+	}
+	if !reflect.DeepEqual(requested, want) {
+		t.Errorf("fields = %v, want %v", requested, want)
+	}
+}
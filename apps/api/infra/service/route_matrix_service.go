@@ -0,0 +1,321 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Routes APIのcomputeRouteMatrixで1リクエストに含められる要素数（出発地点数×目的地数）の上限
+const (
+	MaxRouteMatrixElements        = 625 // 通常
+	MaxTransitRouteMatrixElements = 100 // TRANSITの場合
+)
+
+// IRouteMatrixService ルート行列サービスのインターフェース
+type IRouteMatrixService interface {
+	ComputeRouteMatrix(ctx context.Context, waypoints []Waypoint, travelMode string, departureTime *time.Time) (*RouteMatrix, error)
+	ComputeRouteMatrixBlocks(ctx context.Context, waypoints []Waypoint, blocks []RouteMatrixBlock, travelMode string, departureTime *time.Time) (*RouteMatrix, error)
+}
+
+// RouteMatrixBlock ルート行列のうち計算する部分（Origins×Destinationsの要素）
+// OriginsとDestinationsはwaypointsのインデックス
+type RouteMatrixBlock struct {
+	Origins      []int
+	Destinations []int
+}
+
+// RouteMatrixService Google Maps Routes API（computeRouteMatrix）を使用したルート行列サービス
+type RouteMatrixService struct {
+	apiKey   string
+	baseURL  string
+	upstream *Upstream
+}
+
+// RouteMatrix 地点間の移動距離・所要時間の行列
+// Elements[i][j]は地点iから地点jへの移動を表す
+type RouteMatrix struct {
+	Elements [][]RouteMatrixElement
+}
+
+// RouteMatrixElement ルート行列の1要素
+type RouteMatrixElement struct {
+	DistanceMeters int
+	Duration       time.Duration
+	Found          bool // ルートが見つかったか
+}
+
+// DurationCosts 所要時間（秒）のコスト行列を返す（ルートが見つからない区間はmath.Inf(1)）
+func (m *RouteMatrix) DurationCosts() [][]float64 {
+	costs := make([][]float64, len(m.Elements))
+	for i, row := range m.Elements {
+		costs[i] = make([]float64, len(row))
+		for j, element := range row {
+			if i == j {
+				continue
+			}
+			if !element.Found {
+				costs[i][j] = math.Inf(1)
+				continue
+			}
+			costs[i][j] = element.Duration.Seconds()
+		}
+	}
+	return costs
+}
+
+// RouteMatrixWaypoint computeRouteMatrixの出発地点・目的地
+type RouteMatrixWaypoint struct {
+	Waypoint struct {
+		Location struct {
+			LatLng struct {
+				Latitude  float64 `json:"latitude"`
+				Longitude float64 `json:"longitude"`
+			} `json:"latLng"`
+		} `json:"location"`
+	} `json:"waypoint"`
+}
+
+// RouteMatrixRequest computeRouteMatrixのリクエスト
+type RouteMatrixRequest struct {
+	Origins           []RouteMatrixWaypoint `json:"origins"`
+	Destinations      []RouteMatrixWaypoint `json:"destinations"`
+	TravelMode        string                `json:"travelMode"`
+	RoutingPreference string                `json:"routingPreference,omitempty"`
+	DepartureTime     string                `json:"departureTime,omitempty"` // RFC3339形式
+}
+
+// RouteMatrixResponseElement computeRouteMatrixのレスポンスの1要素
+// indexはリクエストのorigins・destinations内の位置（0の場合は省略される）
+type RouteMatrixResponseElement struct {
+	OriginIndex      int    `json:"originIndex"`
+	DestinationIndex int    `json:"destinationIndex"`
+	DistanceMeters   int    `json:"distanceMeters"`
+	Duration         string `json:"duration"`  // "3600s"形式
+	Condition        string `json:"condition"` // ROUTE_EXISTS / ROUTE_NOT_FOUND
+	Status           struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+// routeMatrixErrorResponse computeRouteMatrixのエラーレスポンス
+type routeMatrixErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	} `json:"error"`
+}
+
+// NewRouteMatrixService 新しいRouteMatrixServiceを作成
+// baseURLはRoutes APIのベースURL（Endpoints.RoutesBaseURL）
+func NewRouteMatrixService(upstream *Upstream, baseURL string) IRouteMatrixService {
+	apiKey := os.Getenv("GOOGLE_MAPS_API_KEY")
+	return &RouteMatrixService{
+		apiKey:   apiKey,
+		baseURL:  baseURL,
+		upstream: upstream,
+	}
+}
+
+// ComputeRouteMatrix 全地点間（N×N）の移動距離・所要時間を計算
+// 要素数の上限を超える場合は、出発地点を複数のタイルに分割してリクエストする
+// travelModeはDRIVE/BICYCLE/WALK/TWO_WHEELER/TRANSIT（空の場合はDRIVE）
+func (s *RouteMatrixService) ComputeRouteMatrix(ctx context.Context, waypoints []Waypoint, travelMode string, departureTime *time.Time) (*RouteMatrix, error) {
+	all := make([]int, len(waypoints))
+	for i := range all {
+		all[i] = i
+	}
+	return s.ComputeRouteMatrixBlocks(ctx, waypoints, []RouteMatrixBlock{{Origins: all, Destinations: all}}, travelMode, departureTime)
+}
+
+// ComputeRouteMatrixBlocks 全地点間（N×N）の行列のうち、blocksの要素の移動距離・所要時間だけを計算
+// computeRouteMatrixは要素ごとに課金されるため、一部の要素しか使わない場合に使う
+// 計算しない要素はFound=false（同一地点間は移動なし）とする
+// ブロックごとに、要素数の上限を超える場合は出発地点を複数のタイルに分割してリクエストする
+func (s *RouteMatrixService) ComputeRouteMatrixBlocks(ctx context.Context, waypoints []Waypoint, blocks []RouteMatrixBlock, travelMode string, departureTime *time.Time) (*RouteMatrix, error) {
+	if s.apiKey == "" {
+		return nil, errMissingAPIKey()
+	}
+
+	travelMode, err := NormalizeTravelMode(travelMode)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(waypoints)
+	matrix := &RouteMatrix{Elements: make([][]RouteMatrixElement, n)}
+	for i := range matrix.Elements {
+		matrix.Elements[i] = make([]RouteMatrixElement, n)
+		// 同一地点間は移動なしとして扱う
+		matrix.Elements[i][i] = RouteMatrixElement{Found: true}
+	}
+	if n < 2 {
+		return matrix, nil
+	}
+
+	maxElements := MaxRouteMatrixElements
+	if travelMode == TravelModeTransit {
+		maxElements = MaxTransitRouteMatrixElements
+	}
+
+	for _, block := range blocks {
+		for _, index := range append(append([]int(nil), block.Origins...), block.Destinations...) {
+			if index < 0 || index >= n {
+				return nil, fmt.Errorf("route matrix block index out of range: %d (n=%d)", index, n)
+			}
+		}
+		for _, tile := range splitMatrixTiles(len(block.Origins), len(block.Destinations), maxElements) {
+			origins := block.Origins[tile.originStart:tile.originEnd]
+			destinations := block.Destinations[tile.destinationStart:tile.destinationEnd]
+			elements, err := s.computeTile(ctx, selectWaypoints(waypoints, origins), selectWaypoints(waypoints, destinations), travelMode, departureTime)
+			if err != nil {
+				return nil, fmt.Errorf("ルート行列%vx%vの計算に失敗しました: %w", origins, destinations, err)
+			}
+			for _, element := range elements {
+				if element.OriginIndex >= len(origins) || element.DestinationIndex >= len(destinations) {
+					continue
+				}
+				i := origins[element.OriginIndex]
+				j := destinations[element.DestinationIndex]
+				if i == j {
+					continue
+				}
+				matrix.Elements[i][j] = toRouteMatrixElement(element)
+			}
+		}
+	}
+
+	return matrix, nil
+}
+
+// selectWaypoints indexesの位置の地点を順に返す
+func selectWaypoints(waypoints []Waypoint, indexes []int) []Waypoint {
+	selected := make([]Waypoint, len(indexes))
+	for i, index := range indexes {
+		selected[i] = waypoints[index]
+	}
+	return selected
+}
+
+// matrixTile ルート行列を分割した1リクエスト分の範囲（終端は含まない）
+type matrixTile struct {
+	originStart, originEnd           int
+	destinationStart, destinationEnd int
+}
+
+// splitMatrixTiles 出発地点origins×目的地destinationsの行列を1タイルあたりmaxElements要素以下に分割する
+// 目的地をできるだけ1タイルにまとめ、出発地点の行方向に分割することでリクエスト数を抑える
+func splitMatrixTiles(origins, destinations, maxElements int) []matrixTile {
+	if origins == 0 || destinations == 0 {
+		return nil
+	}
+	cols := destinations
+	if cols > maxElements {
+		cols = maxElements
+	}
+	rows := maxElements / cols
+
+	var tiles []matrixTile
+	for oi := 0; oi < origins; oi += rows {
+		for di := 0; di < destinations; di += cols {
+			tiles = append(tiles, matrixTile{
+				originStart:      oi,
+				originEnd:        min(oi+rows, origins),
+				destinationStart: di,
+				destinationEnd:   min(di+cols, destinations),
+			})
+		}
+	}
+	return tiles
+}
+
+// computeTile 1タイル分のルート行列をcomputeRouteMatrixで計算
+func (s *RouteMatrixService) computeTile(ctx context.Context, origins, destinations []Waypoint, travelMode string, departureTime *time.Time) ([]RouteMatrixResponseElement, error) {
+	reqBody := RouteMatrixRequest{
+		Origins:      toRouteMatrixWaypoints(origins),
+		Destinations: toRouteMatrixWaypoints(destinations),
+		TravelMode:   travelMode,
+	}
+	// ComputeRouteと同様に、出発時刻とTRAFFIC_AWAREは対応する移動手段のみ指定する
+	if departureTime != nil && supportsDepartureTime(travelMode) {
+		reqBody.DepartureTime = departureTime.Format(time.RFC3339)
+		if supportsRoutingPreference(travelMode) {
+			reqBody.RoutingPreference = "TRAFFIC_AWARE"
+		}
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/distanceMatrix/v2:computeRouteMatrix?key=%s", s.baseURL, s.apiKey)
+
+	// computeRouteMatrixは要素（出発地点×目的地点）ごとに課金される
+	var elements []RouteMatrixResponseElement
+	err = s.upstream.DoUnits(ctx, len(origins)*len(destinations), func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Goog-FieldMask", "originIndex,destinationIndex,duration,distanceMeters,status,condition")
+		return req, nil
+	}, func(statusCode int, body []byte) error {
+		if statusCode != http.StatusOK {
+			var errResp routeMatrixErrorResponse
+			errorMsg := string(body)
+			if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error.Message != "" {
+				errorMsg = errResp.Error.Message
+			}
+			return routesStatusError(statusCode, errorMsg)
+		}
+
+		// 正常時のレスポンスは要素の配列
+		var resp []RouteMatrixResponseElement
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return fmt.Errorf("failed to parse response: %w, body: %s", err, string(body))
+		}
+		elements = resp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return elements, nil
+}
+
+// toRouteMatrixWaypoints 経由地点をcomputeRouteMatrixのリクエスト形式に変換
+func toRouteMatrixWaypoints(waypoints []Waypoint) []RouteMatrixWaypoint {
+	result := make([]RouteMatrixWaypoint, len(waypoints))
+	for i, w := range waypoints {
+		result[i].Waypoint.Location.LatLng.Latitude = w.Lat
+		result[i].Waypoint.Location.LatLng.Longitude = w.Lng
+	}
+	return result
+}
+
+// toRouteMatrixElement レスポンスの要素をルート行列の要素に変換
+// statusがエラーの場合やルートが存在しない場合はFound=falseとする
+func toRouteMatrixElement(element RouteMatrixResponseElement) RouteMatrixElement {
+	if element.Status.Code != 0 || element.Condition != "ROUTE_EXISTS" {
+		return RouteMatrixElement{}
+	}
+	duration, err := time.ParseDuration(element.Duration)
+	if err != nil {
+		return RouteMatrixElement{}
+	}
+	return RouteMatrixElement{
+		DistanceMeters: element.DistanceMeters,
+		Duration:       duration,
+		Found:          true,
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSplitMatrixTiles(t *testing.T) {
+	tests := []struct {
+		name                  string
+		origins, destinations int
+		maxElements           int
+		want                  []matrixTile
+	}{
+		{"空", 0, 5, 625, nil},
+		{"1タイルに収まる", 3, 4, 625, []matrixTile{{0, 3, 0, 4}}},
+		{"出発地点で分割", 30, 25, 625, []matrixTile{{0, 25, 0, 25}, {25, 30, 0, 25}}},
+		{"目的地も分割", 2, 150, 100, []matrixTile{{0, 1, 0, 100}, {0, 1, 100, 150}, {1, 2, 0, 100}, {1, 2, 100, 150}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMatrixTiles(tt.origins, tt.destinations, tt.maxElements)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitMatrixTiles = %v, want %v", got, tt.want)
+			}
+			elements := 0
+			for _, tile := range got {
+				size := (tile.originEnd - tile.originStart) * (tile.destinationEnd - tile.destinationStart)
+				if size > tt.maxElements {
+					t.Errorf("tile %v has %d elements, max %d", tile, size, tt.maxElements)
+				}
+				elements += size
+			}
+			if elements != tt.origins*tt.destinations {
+				t.Errorf("tiles cover %d elements, want %d", elements, tt.origins*tt.destinations)
+			}
+		})
+	}
+}
+
+// fakeRouteMatrixServer 地点の緯度を地点番号として、距離 = 出発地点番号×100 + 目的地番号 を返すcomputeRouteMatrix
+// リクエストされた (出発地点番号, 目的地番号) を記録する
+type fakeRouteMatrixServer struct {
+	mu        sync.Mutex
+	requested [][2]int
+}
+
+func (f *fakeRouteMatrixServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req RouteMatrixRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var elements []RouteMatrixResponseElement
+	for oi, origin := range req.Origins {
+		for di, destination := range req.Destinations {
+			from := int(origin.Waypoint.Location.LatLng.Latitude)
+			to := int(destination.Waypoint.Location.LatLng.Latitude)
+			f.mu.Lock()
+			f.requested = append(f.requested, [2]int{from, to})
+			f.mu.Unlock()
+			elements = append(elements, RouteMatrixResponseElement{
+				OriginIndex:      oi,
+				DestinationIndex: di,
+				DistanceMeters:   from*100 + to,
+				Duration:         fmt.Sprintf("%ds", from*100+to),
+				Condition:        "ROUTE_EXISTS",
+			})
+		}
+	}
+	json.NewEncoder(w).Encode(elements)
+}
+
+func TestComputeRouteMatrixBlocks(t *testing.T) {
+	t.Setenv("GOOGLE_MAPS_API_KEY", "testkey")
+	fake := &fakeRouteMatrixServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	upstream := NewUpstream("test", "", time.Second, ResilienceConfig{MaxAttempts: 1, FailureThreshold: 5, OpenDuration: time.Second}, nil)
+	svc := NewRouteMatrixService(upstream, server.URL)
+
+	waypoints := make([]Waypoint, 5)
+	for i := range waypoints {
+		waypoints[i] = Waypoint{Lat: float64(i)}
+	}
+	blocks := []RouteMatrixBlock{
+		{Origins: []int{0, 1}, Destinations: []int{3, 4}},
+		{Origins: []int{2}, Destinations: []int{1, 2}},
+	}
+	matrix, err := svc.ComputeRouteMatrixBlocks(context.Background(), waypoints, blocks, TravelModeDrive, nil)
+	if err != nil {
+		t.Fatalf("ComputeRouteMatrixBlocks: %v", err)
+	}
+
+	// ブロックの要素だけをリクエストする（同一地点の要素もリクエストには含まれる）
+	wantRequested := [][2]int{{0, 3}, {0, 4}, {1, 3}, {1, 4}, {2, 1}, {2, 2}}
+	if !reflect.DeepEqual(fake.requested, wantRequested) {
+		t.Errorf("requested = %v, want %v", fake.requested, wantRequested)
+	}
+
+	for i, row := range matrix.Elements {
+		for j, element := range row {
+			wantFound := i == j
+			for _, pair := range wantRequested {
+				wantFound = wantFound || pair == [2]int{i, j}
+			}
+			if element.Found != wantFound {
+				t.Errorf("Elements[%d][%d].Found = %v, want %v", i, j, element.Found, wantFound)
+				continue
+			}
+			if wantFound && i != j && element.DistanceMeters != i*100+j {
+				t.Errorf("Elements[%d][%d].DistanceMeters = %d, want %d", i, j, element.DistanceMeters, i*100+j)
+			}
+		}
+	}
+
+	if _, err := svc.ComputeRouteMatrixBlocks(context.Background(), waypoints, []RouteMatrixBlock{{Origins: []int{0}, Destinations: []int{5}}}, TravelModeDrive, nil); err == nil {
+		t.Error("expected error for out-of-range index")
+	}
+}
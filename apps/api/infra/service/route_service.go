@@ -2,9 +2,13 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/pkg/apperror"
+	"fukuoka-ai-api/pkg/polyline"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
@@ -12,20 +16,22 @@ import (
 
 // IRouteService ルートサービスのインターフェース
 type IRouteService interface {
-	ComputeRoute(originLat, originLng float64, destinationLat, destinationLng float64, intermediates []Waypoint, travelMode string, departureTime *time.Time) (*RouteResponse, error)
+	ComputeRoute(ctx context.Context, originLat, originLng float64, destinationLat, destinationLng float64, intermediates []Waypoint, optimizeWaypointOrder bool, travelMode string, departureTime *time.Time) (*RouteResponse, error)
 }
 
 // RouteService Google Maps Routes APIを使用したルートサービス
 type RouteService struct {
-	apiKey string
-	client *http.Client
+	apiKey   string
+	baseURL  string
+	upstream *Upstream
 }
 
 // Waypoint 経由地点
 type Waypoint struct {
-	PlaceID string  `json:"place_id,omitempty"`
-	Lat     float64 `json:"lat"`
-	Lng     float64 `json:"lng"`
+	PlaceID     string  `json:"place_id,omitempty"`
+	Lat         float64 `json:"lat"`
+	Lng         float64 `json:"lng"`
+	StayMinutes int     `json:"stay_minutes,omitempty"` // 経由地点での滞在時間（分）。区間ごとに計算する場合の出発時刻に使用
 }
 
 // RouteResponse ルートAPIのレスポンス
@@ -44,12 +50,14 @@ type RouteResponse struct {
 					Longitude float64 `json:"longitude"`
 				} `json:"latLng"`
 			} `json:"endLocation"`
-			DistanceMeters int    `json:"distanceMeters"`
-			Duration       string `json:"duration"` // "3600s"形式
+			DistanceMeters int      `json:"distanceMeters"`
+			Duration       string   `json:"duration"` // "3600s"形式
+			Polyline       Polyline `json:"polyline"` // 区間の経路
 		} `json:"legs"`
-		DistanceMeters                      int   `json:"distanceMeters"`
-		Duration                           string `json:"duration"`
-		OptimizedIntermediateWaypointIndex []int `json:"optimizedIntermediateWaypointIndex,omitempty"` // Routes API v2の正しいフィールド名
+		DistanceMeters                     int      `json:"distanceMeters"`
+		Duration                           string   `json:"duration"`
+		Polyline                           Polyline `json:"polyline"`                                     // ルート全体の経路
+		OptimizedIntermediateWaypointIndex []int    `json:"optimizedIntermediateWaypointIndex,omitempty"` // Routes API v2の正しいフィールド名
 	} `json:"routes"`
 	Error struct {
 		Code    int    `json:"code"`
@@ -62,6 +70,14 @@ type RouteResponse struct {
 	} `json:"error,omitempty"`
 }
 
+// MaxIntermediates Routes APIの1リクエストで指定できる経由地点の上限
+const MaxIntermediates = 25
+
+// Polyline Routes APIのエンコード済みポリライン
+type Polyline struct {
+	EncodedPolyline string `json:"encodedPolyline"`
+}
+
 // IntermediateWaypoint 経由地点
 type IntermediateWaypoint struct {
 	Location struct {
@@ -90,26 +106,44 @@ type RouteRequest struct {
 			} `json:"latLng"`
 		} `json:"location"`
 	} `json:"destination"`
-	Intermediates        []IntermediateWaypoint `json:"intermediates,omitempty"`
-	TravelMode          string `json:"travelMode"`
-	RoutingPreference   string `json:"routingPreference,omitempty"`
-	OptimizeWaypointOrder bool `json:"optimizeWaypointOrder,omitempty"`
-	DepartureTime       string `json:"departureTime,omitempty"` // RFC3339形式
+	Intermediates         []IntermediateWaypoint `json:"intermediates,omitempty"`
+	TravelMode            string                 `json:"travelMode"`
+	RoutingPreference     string                 `json:"routingPreference,omitempty"`
+	OptimizeWaypointOrder bool                   `json:"optimizeWaypointOrder,omitempty"`
+	DepartureTime         string                 `json:"departureTime,omitempty"` // RFC3339形式
 }
 
 // NewRouteService 新しいRouteServiceを作成
-func NewRouteService() IRouteService {
+// baseURLはRoutes APIのベースURL（Endpoints.RoutesBaseURL）
+func NewRouteService(upstream *Upstream, baseURL string) IRouteService {
 	apiKey := os.Getenv("GOOGLE_MAPS_API_KEY")
 	return &RouteService{
-		apiKey: apiKey,
-		client: &http.Client{},
+		apiKey:   apiKey,
+		baseURL:  baseURL,
+		upstream: upstream,
 	}
 }
 
 // ComputeRoute ルートを計算
-func (s *RouteService) ComputeRoute(originLat, originLng float64, destinationLat, destinationLng float64, intermediates []Waypoint, travelMode string, departureTime *time.Time) (*RouteResponse, error) {
+// optimizeWaypointOrderがfalseの場合は、intermediatesの順序をそのまま維持する
+// travelModeはDRIVE/BICYCLE/WALK/TWO_WHEELER/TRANSIT（空の場合はDRIVE）
+func (s *RouteService) ComputeRoute(ctx context.Context, originLat, originLng float64, destinationLat, destinationLng float64, intermediates []Waypoint, optimizeWaypointOrder bool, travelMode string, departureTime *time.Time) (*RouteResponse, error) {
 	if s.apiKey == "" {
-		return nil, fmt.Errorf("GOOGLE_MAPS_API_KEY is not set")
+		return nil, errMissingAPIKey()
+	}
+
+	travelMode, err := NormalizeTravelMode(travelMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// TRANSITは経由地点・経由地順最適化に対応していないため、区間ごとに計算して結合する
+	// 経由地点が上限を超える場合も、順序を維持したまま複数回に分けて計算して結合する
+	if travelMode == TravelModeTransit && len(intermediates) > 0 {
+		return s.computeSegmentedRoute(ctx, originLat, originLng, destinationLat, destinationLng, intermediates, 0, travelMode, departureTime)
+	}
+	if len(intermediates) > MaxIntermediates {
+		return s.computeSegmentedRoute(ctx, originLat, originLng, destinationLat, destinationLng, intermediates, MaxIntermediates, travelMode, departureTime)
 	}
 
 	// リクエストボディを作成
@@ -189,16 +223,19 @@ func (s *RouteService) ComputeRoute(originLat, originLng float64, destinationLat
 
 		// OptimizeWaypointOrderは、intermediatesが2つ以上ある場合のみ有効化
 		// Routes API v2では、intermediatesが1つ以下の場合、OptimizeWaypointOrderをtrueにするとエラーになる
-		if len(intermediates) >= 2 {
+		if optimizeWaypointOrder && len(intermediates) >= 2 {
 			reqBody.OptimizeWaypointOrder = true
 		}
 	}
 
 	// 出発時刻を設定（指定されている場合）
 	// RoutingPreferenceをTRAFFIC_AWAREに設定する場合、departureTimeが必須
-	if departureTime != nil {
+	// TRAFFIC_AWAREはDRIVE・TWO_WHEELERのみ指定可能、徒歩・自転車は出発時刻も指定しない
+	if departureTime != nil && supportsDepartureTime(travelMode) {
 		reqBody.DepartureTime = departureTime.Format(time.RFC3339)
-		reqBody.RoutingPreference = "TRAFFIC_AWARE"
+		if supportsRoutingPreference(travelMode) {
+			reqBody.RoutingPreference = "TRAFFIC_AWARE"
+		}
 	}
 	// departureTimeが指定されていない場合は、RoutingPreferenceを設定しない（デフォルトのルーティングを使用）
 
@@ -207,70 +244,123 @@ func (s *RouteService) ComputeRoute(originLat, originLng float64, destinationLat
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// デバッグ用：リクエストボディをログに出力
-	fmt.Printf("Routes API Request: %s\n", string(jsonData))
+	slog.DebugContext(ctx, "routes api request", "body", string(jsonData))
 
 	// APIエンドポイント
-	url := fmt.Sprintf("https://routes.googleapis.com/directions/v2:computeRoutes?key=%s", s.apiKey)
+	url := fmt.Sprintf("%s/directions/v2:computeRoutes?key=%s", s.baseURL, s.apiKey)
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
 	// Routes API v2では、optimizeWaypointOrderがtrueの場合、routes.optimized_intermediate_waypoint_indexをフィールドマスクに含める必要がある
 	// legsの距離情報も明示的に指定
-	fieldMask := "routes.duration,routes.distanceMeters,routes.legs.distanceMeters,routes.legs.duration,routes.legs.startLocation,routes.legs.endLocation"
+	// 地図に経路を描画するため、ルート全体と各区間のポリラインも取得する
+	fieldMask := "routes.duration,routes.distanceMeters,routes.polyline.encodedPolyline,routes.legs.distanceMeters,routes.legs.duration,routes.legs.startLocation,routes.legs.endLocation,routes.legs.polyline.encodedPolyline"
 	if reqBody.OptimizeWaypointOrder {
 		fieldMask += ",routes.optimized_intermediate_waypoint_index"
 	}
-	req.Header.Set("X-Goog-FieldMask", fieldMask)
 
-	resp, err := s.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to call Google Routes API: %w", err)
-	}
-	defer resp.Body.Close()
+	var result RouteResponse
+	err = s.upstream.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Goog-FieldMask", fieldMask)
+		return req, nil
+	}, func(statusCode int, body []byte) error {
+		// エラーレスポンスの詳細をログに出力（デバッグ用）
+		if statusCode != http.StatusOK {
+			slog.DebugContext(ctx, "routes api error response", "status", statusCode, "body", string(body))
+		}
+
+		var resp RouteResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return fmt.Errorf("failed to parse response: %w, body: %s", err, string(body))
+		}
 
-	body, err := io.ReadAll(resp.Body)
+		if statusCode != http.StatusOK {
+			errorMsg := resp.Error.Message
+			if errorMsg == "" && resp.Error.Status != "" {
+				errorMsg = resp.Error.Status
+			}
+			if errorMsg == "" {
+				errorMsg = string(body)
+			}
+			// エラーの詳細情報を追加
+			if len(resp.Error.Details) > 0 {
+				details := ""
+				for _, detail := range resp.Error.Details {
+					details += fmt.Sprintf(" [%s: %s]", detail.Type, detail.Field)
+				}
+				errorMsg += details
+			}
+			return routesStatusError(statusCode, errorMsg)
+		}
+		result = resp
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
-	// エラーレスポンスの詳細をログに出力（デバッグ用）
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Routes API Error Response: %s\n", string(body))
+	if len(result.Routes) == 0 {
+		return nil, apperror.New(apperror.ErrUpstream, "no routes found").WithCode(routesErrorCode)
 	}
 
-	var result RouteResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w, body: %s", err, string(body))
-	}
+	return &result, nil
+}
+
+// computeSegmentedRoute 地点列を1リクエストあたり最大maxIntermediates個の経由地点に分割して計算し、1つのルートに結合する
+// 経由地点の順序は最適化せず、指定された順序のまま計算する
+// 2つ目以降の区間は、前の区間までの所要時間と経由地点での滞在時間だけ出発時刻を進めて計算する
+func (s *RouteService) computeSegmentedRoute(ctx context.Context, originLat, originLng float64, destinationLat, destinationLng float64, intermediates []Waypoint, maxIntermediates int, travelMode string, departureTime *time.Time) (*RouteResponse, error) {
+	points := make([]Waypoint, 0, len(intermediates)+2)
+	points = append(points, Waypoint{Lat: originLat, Lng: originLng})
+	points = append(points, intermediates...)
+	points = append(points, Waypoint{Lat: destinationLat, Lng: destinationLng})
 
-	if resp.StatusCode != http.StatusOK {
-		errorMsg := result.Error.Message
-		if errorMsg == "" && result.Error.Status != "" {
-			errorMsg = result.Error.Status
+	var merged *RouteResponse
+	var totalSeconds float64
+	var elapsed time.Duration // 出発から区間の出発地点を出発するまでの時間
+	var path []models.Coordinate
+	for i := 0; i < len(points)-1; i += maxIntermediates + 1 {
+		last := i + maxIntermediates + 1
+		if last > len(points)-1 {
+			last = len(points) - 1
 		}
-		if errorMsg == "" {
-			errorMsg = string(body)
+		var segmentDeparture *time.Time
+		if departureTime != nil {
+			t := departureTime.Add(elapsed)
+			segmentDeparture = &t
 		}
-		// エラーの詳細情報を追加
-		if len(result.Error.Details) > 0 {
-			details := ""
-			for _, detail := range result.Error.Details {
-				details += fmt.Sprintf(" [%s: %s]", detail.Type, detail.Field)
+		from, to := points[i], points[last]
+		result, err := s.ComputeRoute(ctx, from.Lat, from.Lng, to.Lat, to.Lng, points[i+1:last], false, travelMode, segmentDeparture)
+		if err != nil {
+			return nil, fmt.Errorf("区間[%d-%d]の計算に失敗しました: %w", i, last, err)
+		}
+		route := result.Routes[0]
+
+		if d, err := time.ParseDuration(route.Duration); err == nil {
+			totalSeconds += d.Seconds()
+			elapsed += d
+		}
+		for _, point := range points[i+1 : last+1] {
+			elapsed += time.Duration(point.StayMinutes) * time.Minute
+		}
+		if route.Polyline.EncodedPolyline != "" {
+			if decoded, err := polyline.Decode(route.Polyline.EncodedPolyline); err == nil {
+				path = append(path, decoded...)
 			}
-			errorMsg += details
 		}
-		return nil, fmt.Errorf("Google Routes API error: status %d, message: %s", resp.StatusCode, errorMsg)
-	}
 
-	if len(result.Routes) == 0 {
-		return nil, fmt.Errorf("no routes found")
+		if merged == nil {
+			merged = result
+			continue
+		}
+		merged.Routes[0].Legs = append(merged.Routes[0].Legs, route.Legs...)
+		merged.Routes[0].DistanceMeters += route.DistanceMeters
 	}
 
-	return &result, nil
+	merged.Routes[0].Duration = fmt.Sprintf("%.0fs", totalSeconds)
+	merged.Routes[0].Polyline.EncodedPolyline = polyline.Encode(path)
+	return merged, nil
 }
-
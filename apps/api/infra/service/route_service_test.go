@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRouteServer 区間数（経由地点の数+1）×600秒の所要時間を返すcomputeRoutes
+// リクエストされた出発時刻を記録する
+type fakeRouteServer struct {
+	mu             sync.Mutex
+	departureTimes []string
+}
+
+func (f *fakeRouteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req RouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.departureTimes = append(f.departureTimes, req.DepartureTime)
+	f.mu.Unlock()
+
+	type leg struct {
+		Duration string `json:"duration"`
+	}
+	legs := make([]leg, len(req.Intermediates)+1)
+	for i := range legs {
+		legs[i] = leg{Duration: "600s"}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"routes": []interface{}{map[string]interface{}{
+			"legs":     legs,
+			"duration": fmt.Sprintf("%ds", len(legs)*600),
+		}},
+	})
+}
+
+func TestComputeSegmentedRouteDepartureTime(t *testing.T) {
+	t.Setenv("GOOGLE_MAPS_API_KEY", "testkey")
+	departure := time.Date(2025, 4, 5, 10, 0, 0, 0, time.UTC)
+
+	// 26個の経由地点（滞在時間は各10分）は上限で2回に分けて計算する
+	manyIntermediates := make([]Waypoint, MaxIntermediates+1)
+	for i := range manyIntermediates {
+		manyIntermediates[i] = Waypoint{StayMinutes: 10}
+	}
+
+	tests := []struct {
+		name          string
+		travelMode    string
+		intermediates []Waypoint
+		departureTime *time.Time
+		want          []time.Duration // 各区間の出発時刻（departureからの経過時間）
+	}{
+		{
+			name:          "TRANSITは区間ごとに所要時間と滞在時間だけ出発時刻を進める",
+			travelMode:    TravelModeTransit,
+			intermediates: []Waypoint{{StayMinutes: 30}, {StayMinutes: 45}},
+			departureTime: &departure,
+			want:          []time.Duration{0, 10*time.Minute + 30*time.Minute, 20*time.Minute + 75*time.Minute},
+		},
+		{
+			name:          "滞在時間0の経由地点は所要時間だけ進める",
+			travelMode:    TravelModeTransit,
+			intermediates: []Waypoint{{}},
+			departureTime: &departure,
+			want:          []time.Duration{0, 10 * time.Minute},
+		},
+		{
+			name:          "経由地点の上限で分割した2つ目の区間は1つ目の区間の経由地点の滞在時間を含める",
+			travelMode:    TravelModeDrive,
+			intermediates: manyIntermediates,
+			departureTime: &departure,
+			want:          []time.Duration{0, 26*10*time.Minute + 26*10*time.Minute},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeRouteServer{}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+			upstream := NewUpstream("test", "", time.Second, ResilienceConfig{MaxAttempts: 1, FailureThreshold: 5, OpenDuration: time.Second}, nil)
+			svc := NewRouteService(upstream, server.URL)
+
+			if _, err := svc.ComputeRoute(context.Background(), 0, 0, 1, 1, tt.intermediates, false, tt.travelMode, tt.departureTime); err != nil {
+				t.Fatalf("ComputeRoute: %v", err)
+			}
+			var want []string
+			for _, d := range tt.want {
+				want = append(want, departure.Add(d).Format(time.RFC3339))
+			}
+			if !reflect.DeepEqual(fake.departureTimes, want) {
+				t.Errorf("departureTimes = %v, want %v", fake.departureTimes, want)
+			}
+		})
+	}
+
+	// 出発時刻を指定しない場合はどの区間にも指定しない
+	fake := &fakeRouteServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	upstream := NewUpstream("test", "", time.Second, ResilienceConfig{MaxAttempts: 1, FailureThreshold: 5, OpenDuration: time.Second}, nil)
+	if _, err := NewRouteService(upstream, server.URL).ComputeRoute(context.Background(), 0, 0, 1, 1, []Waypoint{{StayMinutes: 30}}, false, TravelModeTransit, nil); err != nil {
+		t.Fatalf("ComputeRoute: %v", err)
+	}
+	if want := []string{"", ""}; !reflect.DeepEqual(fake.departureTimes, want) {
+		t.Errorf("departureTimes = %q, want %q", fake.departureTimes, want)
+	}
+}
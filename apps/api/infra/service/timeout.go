@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Timeouts 外部APIごとの1回のHTTPリクエストのタイムアウト
+// 呼び出し元のcontextの期限の方が早い場合はそちらが優先される
+type Timeouts struct {
+	Geocoding    time.Duration // Text Search（UPSTREAM_TIMEOUT_GEOCODING、デフォルト: 5s）
+	NearbySearch time.Duration // Nearby Search（UPSTREAM_TIMEOUT_NEARBY、デフォルト: 5s）
+	PlaceDetails time.Duration // Place Details（UPSTREAM_TIMEOUT_DETAILS、デフォルト: 5s）
+	Routes       time.Duration // Routes API computeRoutes（UPSTREAM_TIMEOUT_ROUTES、デフォルト: 10s）
+	RouteMatrix  time.Duration // Routes API computeRouteMatrix（UPSTREAM_TIMEOUT_ROUTE_MATRIX、デフォルト: 10s）
+	ML           time.Duration // MLサービスのfree_textの解釈（UPSTREAM_TIMEOUT_ML、デフォルト: 3s）
+}
+
+// LoadTimeouts 環境変数から外部APIごとのタイムアウトを読み込む（未設定の項目は既定値）
+func LoadTimeouts() (Timeouts, error) {
+	t := Timeouts{
+		Geocoding:    5 * time.Second,
+		NearbySearch: 5 * time.Second,
+		PlaceDetails: 5 * time.Second,
+		Routes:       10 * time.Second,
+		RouteMatrix:  10 * time.Second,
+		ML:           3 * time.Second,
+	}
+
+	for _, item := range []struct {
+		name string
+		dest *time.Duration
+	}{
+		{"UPSTREAM_TIMEOUT_GEOCODING", &t.Geocoding},
+		{"UPSTREAM_TIMEOUT_NEARBY", &t.NearbySearch},
+		{"UPSTREAM_TIMEOUT_DETAILS", &t.PlaceDetails},
+		{"UPSTREAM_TIMEOUT_ROUTES", &t.Routes},
+		{"UPSTREAM_TIMEOUT_ROUTE_MATRIX", &t.RouteMatrix},
+		{"UPSTREAM_TIMEOUT_ML", &t.ML},
+	} {
+		v := os.Getenv(item.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return t, fmt.Errorf("%s must be a positive duration (e.g. \"5s\"): %s", item.name, v)
+		}
+		*item.dest = d
+	}
+	return t, nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Routes APIの移動手段（RouteTravelMode）
+const (
+	TravelModeDrive      = "DRIVE"       // 自動車
+	TravelModeBicycle    = "BICYCLE"     // 自転車
+	TravelModeWalk       = "WALK"        // 徒歩
+	TravelModeTwoWheeler = "TWO_WHEELER" // 二輪車
+	TravelModeTransit    = "TRANSIT"     // 公共交通機関
+)
+
+// NormalizeTravelMode 移動手段を検証し、Routes APIの表記（大文字）に変換する
+// 空文字列の場合はDRIVEを返す。"walk"や"two-wheeler"のような表記も受け付ける
+func NormalizeTravelMode(mode string) (string, error) {
+	normalized := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(mode), "-", "_"))
+	switch normalized {
+	case "":
+		return TravelModeDrive, nil
+	case TravelModeDrive, TravelModeBicycle, TravelModeWalk, TravelModeTwoWheeler, TravelModeTransit:
+		return normalized, nil
+	default:
+		return "", fmt.Errorf("unsupported travel mode: %s", mode)
+	}
+}
+
+// supportsRoutingPreference 移動手段がroutingPreference（TRAFFIC_AWARE）を指定できるか
+// Routes APIではDRIVEとTWO_WHEELER以外でroutingPreferenceを指定するとエラーになる
+func supportsRoutingPreference(mode string) bool {
+	return mode == TravelModeDrive || mode == TravelModeTwoWheeler
+}
+
+// supportsDepartureTime 移動手段がdepartureTimeを指定できるか
+// 徒歩・自転車は交通状況や時刻表の影響を受けないため指定しない
+func supportsDepartureTime(mode string) bool {
+	return mode != TravelModeWalk && mode != TravelModeBicycle
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fukuoka-ai-api/infra/usage"
+	"fukuoka-ai-api/pkg/apperror"
+	"fukuoka-ai-api/pkg/logging"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ResilienceConfig 外部API呼び出しの再試行・サーキットブレーカーの設定
+type ResilienceConfig struct {
+	MaxAttempts      int           // 1回の呼び出しあたりの最大試行回数（RETRY_MAX_ATTEMPTS、デフォルト: 3）
+	BaseDelay        time.Duration // 最初の再試行までの待ち時間（RETRY_BASE_DELAY、デフォルト: 200ms）
+	MaxDelay         time.Duration // 再試行までの待ち時間の上限（RETRY_MAX_DELAY、デフォルト: 2s）
+	RetryBudget      time.Duration // 1回の呼び出しで再試行のために待つ時間の合計の上限（RETRY_BUDGET、デフォルト: 3s）
+	FailureThreshold int           // サーキットブレーカーがopenになる連続失敗回数（BREAKER_FAILURE_THRESHOLD、デフォルト: 5）
+	OpenDuration     time.Duration // サーキットブレーカーがopenのまま失敗させる時間（BREAKER_OPEN_DURATION、デフォルト: 30s）
+}
+
+// LoadResilienceConfig 環境変数から再試行・サーキットブレーカーの設定を読み込む（未設定の項目は既定値）
+func LoadResilienceConfig() (ResilienceConfig, error) {
+	cfg := ResilienceConfig{
+		MaxAttempts:      3,
+		BaseDelay:        200 * time.Millisecond,
+		MaxDelay:         2 * time.Second,
+		RetryBudget:      3 * time.Second,
+		FailureThreshold: 5,
+		OpenDuration:     30 * time.Second,
+	}
+
+	for _, item := range []struct {
+		name string
+		dest *int
+	}{
+		{"RETRY_MAX_ATTEMPTS", &cfg.MaxAttempts},
+		{"BREAKER_FAILURE_THRESHOLD", &cfg.FailureThreshold},
+	} {
+		v := os.Getenv(item.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("%s must be a positive integer: %s", item.name, v)
+		}
+		*item.dest = n
+	}
+
+	for _, item := range []struct {
+		name string
+		dest *time.Duration
+	}{
+		{"RETRY_BASE_DELAY", &cfg.BaseDelay},
+		{"RETRY_MAX_DELAY", &cfg.MaxDelay},
+		{"RETRY_BUDGET", &cfg.RetryBudget},
+		{"BREAKER_OPEN_DURATION", &cfg.OpenDuration},
+	} {
+		v := os.Getenv(item.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("%s must be a positive duration (e.g. \"500ms\"): %s", item.name, v)
+		}
+		*item.dest = d
+	}
+	return cfg, nil
+}
+
+// retryableError 再試行の対象になる一時的な失敗
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// retryable errを再試行の対象としてマークする
+func retryable(err error) error {
+	return &retryableError{err: err}
+}
+
+// isRetryable errが再試行の対象かを返す
+func isRetryable(err error) bool {
+	var r *retryableError
+	return errors.As(err, &r)
+}
+
+// isRetryablePlacesStatus Places APIのstatusが一時的な失敗を表すかを返す
+func isRetryablePlacesStatus(status string) bool {
+	return status == "OVER_QUERY_LIMIT" || status == "UNKNOWN_ERROR"
+}
+
+// placesStatusError Places APIのstatusをエラーの種類に分類する（一時的な失敗は再試行の対象としてマークする）
+func placesStatusError(status, message string) error {
+	var kind error
+	switch status {
+	case "ZERO_RESULTS", "NOT_FOUND":
+		kind = apperror.ErrPlaceNotFound
+	case "OVER_QUERY_LIMIT":
+		kind = apperror.ErrUpstreamQuota
+	case "REQUEST_DENIED":
+		kind = apperror.ErrConfiguration
+	default:
+		kind = apperror.ErrUpstream
+	}
+	err := apperror.New(kind, "Google Places API error: %s - %s", status, message).WithDetails("%s", status)
+	if kind == apperror.ErrUpstream {
+		err.Code = placesErrorCode
+	}
+	if isRetryablePlacesStatus(status) {
+		return retryable(err)
+	}
+	return err
+}
+
+// routesStatusError Routes APIのエラーレスポンスをHTTPステータスコードでエラーの種類に分類する
+func routesStatusError(statusCode int, message string) error {
+	var kind error
+	switch statusCode {
+	case http.StatusTooManyRequests:
+		kind = apperror.ErrUpstreamQuota
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = apperror.ErrConfiguration
+	default:
+		kind = apperror.ErrUpstream
+	}
+	err := apperror.New(kind, "Google Routes API error: status %d, message: %s", statusCode, message).
+		WithDetails("HTTP %d", statusCode)
+	if kind == apperror.ErrUpstream {
+		err.Code = routesErrorCode
+	}
+	return err
+}
+
+// 外部APIの失敗を表すエラーコード
+const (
+	placesErrorCode    = "PLACES_API_ERROR"
+	routesErrorCode    = "ROUTES_API_ERROR"
+	mlServiceErrorCode = "ML_SERVICE_ERROR"
+)
+
+// upstreamErrorCode skuの外部APIの失敗を表すエラーコード
+func upstreamErrorCode(sku usage.SKU) string {
+	switch sku {
+	case usage.SKUComputeRoutes, usage.SKUComputeRouteMatrix:
+		return routesErrorCode
+	case "":
+		// 課金単位のない外部サービス（MLサービス）
+		return mlServiceErrorCode
+	}
+	return placesErrorCode
+}
+
+// errMissingAPIKey GOOGLE_MAPS_API_KEYが設定されていない場合のエラー
+func errMissingAPIKey() error {
+	return apperror.New(apperror.ErrConfiguration, "GOOGLE_MAPS_API_KEY is not set").WithDetails("GOOGLE_MAPS_API_KEY")
+}
+
+// Upstream 外部APIごとのHTTPクライアント
+// 1回の呼び出しの中で一時的な失敗（通信エラー、HTTP 429・5xx、checkが再試行の対象とした失敗）を
+// ジッター付きの指数バックオフで再試行し、失敗が続く場合はサーキットブレーカーで呼び出さずに失敗させる
+// 再試行を含むすべてのHTTPリクエストをmeterにskuの呼び出しとして記録し、予算の上限に近い場合は呼び出さずに失敗させる
+type Upstream struct {
+	name    string
+	sku     usage.SKU
+	client  *http.Client
+	timeout time.Duration // 1回のHTTPリクエストのタイムアウト
+	config  ResilienceConfig
+	breaker *CircuitBreaker
+	meter   *usage.Meter
+}
+
+// NewUpstream 新しいUpstreamを作成
+// nameはログ・エラーメッセージに使用する外部APIの名前、skuは呼び出し回数を記録する課金単位
+// meterがnilの場合は呼び出し回数を記録しない
+func NewUpstream(name string, sku usage.SKU, timeout time.Duration, config ResilienceConfig, meter *usage.Meter) *Upstream {
+	return &Upstream{
+		name:    name,
+		sku:     sku,
+		client:  &http.Client{},
+		timeout: timeout,
+		config:  config,
+		breaker: NewCircuitBreaker(name, config.FailureThreshold, config.OpenDuration),
+		meter:   meter,
+	}
+}
+
+// Do buildで作成したリクエストを送信し、レスポンスをcheckで検査する
+// buildは試行ごとに呼び出される（リクエストボディは試行ごとに作り直す）
+// checkはステータスコードとボディを受け取ってレスポンスを解釈し、再試行すべき失敗はretryableで包んで返す
+// 試行回数・待ち時間の合計が上限に達した場合は最後のエラーを返す
+func (u *Upstream) Do(ctx context.Context, build func(ctx context.Context) (*http.Request, error), check func(statusCode int, body []byte) error) error {
+	return u.DoUnits(ctx, 1, build, check)
+}
+
+// DoUnits Doと同じだが、1回のHTTPリクエストをunits回分の呼び出しとして記録する
+// 要素数で課金されるAPI（computeRouteMatrixなど）に使用する
+// 失敗の種類が分かっていない場合は外部APIの失敗（apperror.ErrUpstream）として返す
+func (u *Upstream) DoUnits(ctx context.Context, units int, build func(ctx context.Context) (*http.Request, error), check func(statusCode int, body []byte) error) error {
+	return u.classify(u.do(ctx, units, build, check))
+}
+
+// classify 種類が分かっていない失敗を外部APIの失敗として分類する
+// 期限切れ・キャンセル、サーキットブレーカー・予算による失敗はコントローラーが個別に扱うためそのまま返す
+func (u *Upstream) classify(err error) error {
+	switch {
+	case err == nil,
+		errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, ErrCircuitOpen),
+		errors.Is(err, usage.ErrBudgetExceeded),
+		errors.As(err, new(*apperror.Error)):
+		return err
+	}
+	return apperror.Wrap(apperror.ErrUpstream, err, "%sの呼び出しに失敗しました", u.name).WithCode(upstreamErrorCode(u.sku))
+}
+
+// do 再試行・サーキットブレーカー・呼び出し回数の記録を行いながらリクエストを送信する
+func (u *Upstream) do(ctx context.Context, units int, build func(ctx context.Context) (*http.Request, error), check func(statusCode int, body []byte) error) error {
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		if !u.breaker.Allow() {
+			return fmt.Errorf("%s: %w", u.name, ErrCircuitOpen)
+		}
+		if err := u.meter.Use(ctx, u.sku, units); err != nil {
+			u.breaker.Release()
+			return fmt.Errorf("%s: %w", u.name, err)
+		}
+
+		err := u.attempt(ctx, attempt, build, check)
+		switch {
+		case err == nil:
+			u.breaker.Success()
+			return nil
+		case ctx.Err() != nil:
+			// 呼び出し元のキャンセル・期限切れは外部APIの障害として数えない
+			u.breaker.Release()
+			return err
+		case !isRetryable(err):
+			// 外部APIは応答しているため、障害としては数えない
+			u.breaker.Success()
+			return err
+		}
+		u.breaker.Failure()
+
+		if attempt >= u.config.MaxAttempts {
+			return fmt.Errorf("%s: %d回試行しましたが失敗しました: %w", u.name, attempt, err)
+		}
+		delay := u.backoff(attempt)
+		if waited+delay > u.config.RetryBudget {
+			return fmt.Errorf("%s: 再試行の待ち時間の上限に達しました: %w", u.name, err)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("%s: 再試行する前に期限に達します: %w", u.name, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		waited += delay
+	}
+}
+
+// attempt 1回分のHTTPリクエストを送信し、所要時間とステータスコードをログに出力する
+func (u *Upstream) attempt(ctx context.Context, attempt int, build func(ctx context.Context) (*http.Request, error), check func(statusCode int, body []byte) error) (err error) {
+	start := time.Now()
+	statusCode := 0
+	defer func() {
+		u.logAttempt(ctx, attempt, statusCode, time.Since(start), err)
+	}()
+
+	reqCtx, cancel := context.WithTimeout(ctx, u.timeout)
+	defer cancel()
+
+	req, err := build(reqCtx)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return retryable(fmt.Errorf("failed to call %s: %w", u.name, redactURLError(err)))
+	}
+	defer resp.Body.Close()
+	statusCode = resp.StatusCode
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return retryable(fmt.Errorf("failed to read response: %w", redactURLError(err)))
+	}
+
+	err = check(resp.StatusCode, body)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		// ボディがJSONでない場合もあるため、ステータスコードを含めて再試行の対象にする
+		if err == nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				return retryable(apperror.New(apperror.ErrUpstreamQuota, "%s: HTTP %d", u.name, resp.StatusCode).WithDetails("HTTP %d", resp.StatusCode))
+			}
+			return retryable(fmt.Errorf("%s: HTTP %d", u.name, resp.StatusCode))
+		}
+		return retryable(fmt.Errorf("%s: HTTP %d: %w", u.name, resp.StatusCode, err))
+	}
+	return err
+}
+
+// logAttempt 1回分のHTTPリクエストの結果をログに出力する（成功はdebug、失敗はwarn）
+// statusCodeはレスポンスを受け取れなかった場合は0
+func (u *Upstream) logAttempt(ctx context.Context, attempt int, statusCode int, duration time.Duration, err error) {
+	attrs := []slog.Attr{
+		slog.String("upstream", u.name),
+		slog.String("sku", string(u.sku)),
+		slog.Int("attempt", attempt),
+		slog.Int("status", statusCode),
+		slog.Int64("duration_ms", duration.Milliseconds()),
+	}
+	level := slog.LevelDebug
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	slog.LogAttrs(ctx, level, "upstream call", attrs...)
+}
+
+// redactURLError HTTPクライアントのエラーに含まれるURLからAPIキーを伏せる
+func redactURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = logging.Redact(urlErr.URL)
+	}
+	return err
+}
+
+// backoff attempt回目の失敗後に待つ時間（指数バックオフに0.5〜1倍のジッターをかける）
+func (u *Upstream) backoff(attempt int) time.Duration {
+	delay := u.config.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > u.config.MaxDelay {
+		delay = u.config.MaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,121 @@
+// Package tags 興味タグの定義（データファイルから起動時に読み込む）
+// 周辺検索のタイプ・キーワード、関連性スコアの重み、フロントエンドのタグの選択肢はすべてこの定義を参照する
+package tags
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"fukuoka-ai-api/models"
+	"os"
+	"strings"
+)
+
+// defaultData 組み込みのタグ定義（TAGS_FILEが未設定の場合に使用）
+//
+//go:embed tags.json
+var defaultData []byte
+
+// Registry 興味タグの定義
+// タグはID・日本語/英語の表示名・別名のいずれでも引ける（大文字・小文字、前後の空白は区別しない）
+type Registry struct {
+	tags     []models.Tag
+	byName   map[string]int // 正規化した名前 → tagsのインデックス
+	keywords []string       // 自由記述から拾う追加の検索キーワード
+}
+
+// Load pathのタグ定義を読み込む（pathが空の場合は組み込みの定義）
+func Load(path string) (*Registry, error) {
+	if path == "" {
+		return Parse(defaultData)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tags file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse JSON形式のタグ定義を読み込む
+// IDと日本語の表示名は必須で、名前（ID・表示名・別名）が複数のタグで重複する場合はエラーを返す
+func Parse(data []byte) (*Registry, error) {
+	var file struct {
+		FreeTextKeywords []string     `json:"free_text_keywords"`
+		Tags             []models.Tag `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tags: %w", err)
+	}
+	if len(file.Tags) == 0 {
+		return nil, fmt.Errorf("no tags are defined")
+	}
+
+	r := &Registry{tags: file.Tags, byName: make(map[string]int), keywords: file.FreeTextKeywords}
+	for i, tag := range file.Tags {
+		if tag.ID == "" || tag.Labels.Ja == "" {
+			return nil, fmt.Errorf("tag[%d]: id and labels.ja are required", i)
+		}
+		for w, weight := range tag.TypeWeights {
+			if weight < 0 {
+				return nil, fmt.Errorf("tag %s: type_weights.%s must not be negative", tag.ID, w)
+			}
+		}
+		names := append([]string{tag.ID, tag.Labels.Ja, tag.Labels.En}, tag.Synonyms...)
+		for _, name := range names {
+			key := normalize(name)
+			if key == "" {
+				continue
+			}
+			if j, exists := r.byName[key]; exists && j != i {
+				return nil, fmt.Errorf("tag %s: name %q is already used by tag %s", tag.ID, name, file.Tags[j].ID)
+			}
+			r.byName[key] = i
+		}
+	}
+	return r, nil
+}
+
+// Tags 定義順のすべてのタグを返す
+func (r *Registry) Tags() []models.Tag {
+	return r.tags
+}
+
+// FreeTextKeywords 自由記述に含まれる場合に追加の検索キーワードとして使う語（定義順）
+func (r *Registry) FreeTextKeywords() []string {
+	if r == nil {
+		return nil
+	}
+	return r.keywords
+}
+
+// Lookup ID・表示名・別名からタグを引く（nilのRegistryでは常に見つからない）
+func (r *Registry) Lookup(name string) (models.Tag, bool) {
+	if r == nil {
+		return models.Tag{}, false
+	}
+	i, ok := r.byName[normalize(name)]
+	if !ok {
+		return models.Tag{}, false
+	}
+	return r.tags[i], true
+}
+
+// SearchQuery Nearby Searchで検索するタイプまたはキーワードを返す
+// 定義されていないタグ、タイプもキーワードもないタグは、タグ自体をキーワードとして検索する
+func (r *Registry) SearchQuery(name string) (keyword string, placeType string) {
+	tag, ok := r.Lookup(name)
+	switch {
+	case !ok:
+		return name, ""
+	case len(tag.PlaceTypes) > 0:
+		return "", tag.PlaceTypes[0]
+	case len(tag.Keywords) > 0:
+		return tag.Keywords[0], ""
+	}
+	return tag.Labels.Ja, ""
+}
+
+// normalize 名前の比較に使う形（前後の空白を除いて小文字にする）
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
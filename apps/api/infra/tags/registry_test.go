@@ -0,0 +1,115 @@
+package tags
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	registry, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		lookup string
+		wantID string
+		wantOK bool
+	}{
+		{"ID", "cafe", "cafe", true},
+		{"日本語の表示名", "神社", "shrine", true},
+		{"英語の表示名", "Yatai food stalls", "yatai", true},
+		{"日本語の別名", "喫茶店", "cafe", true},
+		{"英語の別名", "coffee", "cafe", true},
+		{"大文字・小文字を区別しない", "COFFEE", "cafe", true},
+		{"前後の空白を無視する", "  ラーメン ", "ramen", true},
+		{"定義されていないタグ", "サウナ", "", false},
+		{"空文字列", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, ok := registry.Lookup(tt.lookup)
+			if ok != tt.wantOK || tag.ID != tt.wantID {
+				t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.lookup, tag.ID, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+
+	// nilのRegistryでは常に見つからない
+	var empty *Registry
+	if tag, ok := empty.Lookup("cafe"); ok {
+		t.Errorf("nil Registry Lookup = %q, true, want false", tag.ID)
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	registry, err := Parse([]byte(`{"tags": [
+		{"id": "cafe", "labels": {"ja": "カフェ"}, "synonyms": ["coffee"], "place_types": ["cafe", "bakery"], "keywords": ["喫茶"]},
+		{"id": "ramen", "labels": {"ja": "ラーメン"}, "keywords": ["とんこつ", "ラーメン"]},
+		{"id": "retro", "labels": {"ja": "レトロ", "en": "Retro"}}
+	]}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		tag           string
+		wantKeyword   string
+		wantPlaceType string
+	}{
+		{"タイプは先頭を使いキーワードより優先する", "cafe", "", "cafe"},
+		{"別名から引く", "Coffee", "", "cafe"},
+		{"タイプがない場合はキーワードの先頭", "ラーメン", "とんこつ", ""},
+		{"タイプもキーワードもない場合は日本語の表示名", "retro", "レトロ", ""},
+		{"定義されていないタグはタグ自体をキーワードにする", "温泉", "温泉", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyword, placeType := registry.SearchQuery(tt.tag)
+			if keyword != tt.wantKeyword || placeType != tt.wantPlaceType {
+				t.Errorf("SearchQuery(%q) = %q, %q, want %q, %q", tt.tag, keyword, placeType, tt.wantKeyword, tt.wantPlaceType)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"最小の定義", `{"tags": [{"id": "cafe", "labels": {"ja": "カフェ"}}]}`, false},
+		{"別名が同じタグの名前と重なるのは許す", `{"tags": [{"id": "cafe", "labels": {"ja": "カフェ", "en": "cafe"}, "synonyms": ["Cafe"]}]}`, false},
+		{"JSONではない", `tags`, true},
+		{"タグがない", `{"tags": []}`, true},
+		{"IDがない", `{"tags": [{"labels": {"ja": "カフェ"}}]}`, true},
+		{"日本語の表示名がない", `{"tags": [{"id": "cafe", "labels": {"en": "Cafe"}}]}`, true},
+		{"負の加点", `{"tags": [{"id": "cafe", "labels": {"ja": "カフェ"}, "type_weights": {"cafe": -1}}]}`, true},
+		{"名前が別のタグと重なる", `{"tags": [{"id": "cafe", "labels": {"ja": "カフェ"}}, {"id": "kissa", "labels": {"ja": "喫茶店"}, "synonyms": ["CAFE"]}]}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	registry, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(registry.Tags()) == 0 || len(registry.FreeTextKeywords()) == 0 {
+		t.Errorf("Load = %d tags, %d keywords, want built-in definitions", len(registry.Tags()), len(registry.FreeTextKeywords()))
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Load(missing) error = nil, want error")
+	}
+}
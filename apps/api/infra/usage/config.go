@@ -0,0 +1,63 @@
+package usage
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Budget SKUごとの予算（呼び出し回数、0の場合は無制限）
+type Budget struct {
+	Daily   int64 // 1日（日本時間）の予算（USAGE_BUDGET_<SKU>_DAILY）
+	Monthly int64 // 1か月（日本時間）の予算（USAGE_BUDGET_<SKU>_MONTHLY）
+}
+
+// Config 呼び出し回数の予算の設定
+type Config struct {
+	Budgets   map[SKU]Budget // SKUごとの予算（未設定のSKUは無制限）
+	Threshold float64        // 予算に対してキャッシュのみの応答に切り替える割合（USAGE_BUDGET_THRESHOLD、デフォルト: 0.9）
+}
+
+// LoadConfig 環境変数から予算の設定を読み込む（未設定の項目は既定値）
+// 予算の環境変数名はSKUを大文字にしたもの（例: USAGE_BUDGET_NEARBY_SEARCH_DAILY）
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		Budgets:   make(map[SKU]Budget),
+		Threshold: 0.9,
+	}
+
+	if v := os.Getenv("USAGE_BUDGET_THRESHOLD"); v != "" {
+		threshold, err := strconv.ParseFloat(v, 64)
+		if err != nil || threshold <= 0 || threshold > 1 {
+			return cfg, fmt.Errorf("USAGE_BUDGET_THRESHOLD must be a number in (0, 1]: %s", v)
+		}
+		cfg.Threshold = threshold
+	}
+
+	for _, sku := range SKUs {
+		prefix := "USAGE_BUDGET_" + strings.ToUpper(string(sku))
+		var budget Budget
+		for _, item := range []struct {
+			name string
+			dest *int64
+		}{
+			{prefix + "_DAILY", &budget.Daily},
+			{prefix + "_MONTHLY", &budget.Monthly},
+		} {
+			v := os.Getenv(item.name)
+			if v == "" {
+				continue
+			}
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return cfg, fmt.Errorf("%s must be a non-negative integer: %s", item.name, v)
+			}
+			*item.dest = n
+		}
+		if budget.Daily > 0 || budget.Monthly > 0 {
+			cfg.Budgets[sku] = budget
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,189 @@
+// Package usage 外部APIの呼び出し回数を課金単位（SKU）ごとに計測する
+//
+// 呼び出し回数は起動後の合計と、日本時間の日・月ごとに集計する。
+// SKUごとに日・月の予算を設定でき、予算の上限に近づいたSKUは外部APIを呼び出さずにErrBudgetExceededを返す。
+// キャッシュ済みの結果は外部APIを呼び出さずに返すため、予算の上限に近づいた後はキャッシュのみで応答する。
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SKU 外部APIの課金単位
+type SKU string
+
+const (
+	SKUTextSearch         SKU = "text_search"          // Places API Text Search（ジオコーディング）
+	SKUNearbySearch       SKU = "nearby_search"        // Places API Nearby Search（ページごとに1回）
+	SKUPlaceDetails       SKU = "place_details"        // Places API Place Details
+	SKUPlacePhoto         SKU = "place_photo"          // Places API Place Photo（クライアントに返した写真URLの数）
+	SKUComputeRoutes      SKU = "compute_routes"       // Routes API computeRoutes
+	SKUComputeRouteMatrix SKU = "compute_route_matrix" // Routes API computeRouteMatrix（要素数 = 出発地点数×目的地点数）
+)
+
+// SKUs 計測対象のSKU（統計・ヘッダーの表示順）
+var SKUs = []SKU{
+	SKUTextSearch,
+	SKUNearbySearch,
+	SKUPlaceDetails,
+	SKUPlacePhoto,
+	SKUComputeRoutes,
+	SKUComputeRouteMatrix,
+}
+
+// ErrBudgetExceeded 予算の上限に近づいたため、外部APIを呼び出さずに失敗させた
+var ErrBudgetExceeded = errors.New("api usage budget exceeded")
+
+// location 日・月の集計に使用するタイムゾーン（日本時間）
+var location = time.FixedZone("JST", 9*60*60)
+
+// Meter 外部APIの呼び出し回数の計測と予算の判定
+type Meter struct {
+	budgets   map[SKU]Budget
+	threshold float64
+	store     IStore // nilの場合は再起動で集計が失われる
+
+	mu      sync.Mutex
+	day     string // 集計中の日（2006-01-02）
+	month   string // 集計中の月（2006-01）
+	total   map[SKU]int64
+	daily   map[SKU]int64
+	monthly map[SKU]int64
+	now     func() time.Time
+}
+
+// Stats SKUごとの呼び出し回数と予算
+type Stats struct {
+	Day       string     `json:"day"`       // 集計中の日（日本時間）
+	Month     string     `json:"month"`     // 集計中の月（日本時間）
+	Threshold float64    `json:"threshold"` // 予算に対してキャッシュのみの応答に切り替える割合
+	SKUs      []SKUStats `json:"skus"`
+}
+
+// SKUStats 1つのSKUの呼び出し回数と予算
+type SKUStats struct {
+	SKU           SKU   `json:"sku"`
+	Total         int64 `json:"total"`                    // 起動後の呼び出し回数
+	Daily         int64 `json:"daily"`                    // 今日の呼び出し回数
+	Monthly       int64 `json:"monthly"`                  // 今月の呼び出し回数
+	DailyBudget   int64 `json:"daily_budget,omitempty"`   // 1日の予算（未設定の場合は省略）
+	MonthlyBudget int64 `json:"monthly_budget,omitempty"` // 1か月の予算（未設定の場合は省略）
+	CacheOnly     bool  `json:"cache_only"`               // 予算の上限に近いため外部APIを呼び出していないか
+}
+
+// NewMeter 新しいMeterを作成
+// storeが指定されている場合は今日・今月の呼び出し回数を読み込み、以降の呼び出しも記録する
+func NewMeter(config Config, store IStore) (*Meter, error) {
+	m := &Meter{
+		budgets:   config.Budgets,
+		threshold: config.Threshold,
+		store:     store,
+		total:     make(map[SKU]int64),
+		daily:     make(map[SKU]int64),
+		monthly:   make(map[SKU]int64),
+		now:       time.Now,
+	}
+	m.day, m.month = periods(m.now())
+
+	if store != nil {
+		var err error
+		if m.daily, err = store.Load(m.day); err != nil {
+			return nil, err
+		}
+		if m.monthly, err = store.Load(m.month); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Use skuの外部APIをunits回分呼び出すことを記録する
+// 予算の上限に近い場合は記録せずにErrBudgetExceededを返す（呼び出し側は外部APIを呼び出さない）
+// ctxにRecorderが設定されている場合は、そのリクエストの呼び出し回数にも加える
+// mがnilの場合は何もしない
+func (m *Meter) Use(ctx context.Context, sku SKU, units int) error {
+	if m == nil {
+		return nil
+	}
+	n := int64(units)
+
+	m.mu.Lock()
+	m.rollover()
+	budget := m.budgets[sku]
+	if m.exceeds(m.daily[sku]+n, budget.Daily) || m.exceeds(m.monthly[sku]+n, budget.Monthly) {
+		m.mu.Unlock()
+		return fmt.Errorf("%s: %w", sku, ErrBudgetExceeded)
+	}
+	m.total[sku] += n
+	m.daily[sku] += n
+	m.monthly[sku] += n
+	day, month := m.day, m.month
+	m.mu.Unlock()
+
+	if r := recorderFrom(ctx); r != nil {
+		r.add(sku, n)
+	}
+	if m.store != nil {
+		// 記録に失敗しても外部APIの呼び出しは続ける（メモリ上の集計で予算を判定する）
+		if err := m.store.Add(sku, []string{day, month}, n); err != nil {
+			slog.WarnContext(ctx, "failed to record api usage", "sku", string(sku), "error", err)
+		}
+	}
+	return nil
+}
+
+// Stats SKUごとの呼び出し回数と予算を返す
+func (m *Meter) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rollover()
+
+	stats := Stats{
+		Day:       m.day,
+		Month:     m.month,
+		Threshold: m.threshold,
+		SKUs:      make([]SKUStats, 0, len(SKUs)),
+	}
+	for _, sku := range SKUs {
+		budget := m.budgets[sku]
+		stats.SKUs = append(stats.SKUs, SKUStats{
+			SKU:           sku,
+			Total:         m.total[sku],
+			Daily:         m.daily[sku],
+			Monthly:       m.monthly[sku],
+			DailyBudget:   budget.Daily,
+			MonthlyBudget: budget.Monthly,
+			CacheOnly:     m.exceeds(m.daily[sku]+1, budget.Daily) || m.exceeds(m.monthly[sku]+1, budget.Monthly),
+		})
+	}
+	return stats
+}
+
+// rollover 日・月が変わった場合に集計をリセットする（m.muを保持した状態で呼び出す）
+func (m *Meter) rollover() {
+	day, month := periods(m.now())
+	if day != m.day {
+		m.day = day
+		m.daily = make(map[SKU]int64)
+	}
+	if month != m.month {
+		m.month = month
+		m.monthly = make(map[SKU]int64)
+	}
+}
+
+// exceeds 呼び出し回数usedが予算limitの上限（limit×threshold）を超えるかを返す（limitが0の場合は無制限）
+func (m *Meter) exceeds(used, limit int64) bool {
+	return limit > 0 && float64(used) > float64(limit)*m.threshold
+}
+
+// periods 時刻tの日と月の集計期間を返す
+func periods(t time.Time) (day, month string) {
+	t = t.In(location)
+	return t.Format("2006-01-02"), t.Format("2006-01")
+}
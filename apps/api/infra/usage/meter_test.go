@@ -0,0 +1,176 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// memoryStore テスト用のメモリ上のストア
+type memoryStore struct {
+	counts map[string]map[SKU]int64
+}
+
+func (s *memoryStore) Load(period string) (map[SKU]int64, error) {
+	counts := make(map[SKU]int64)
+	for sku, n := range s.counts[period] {
+		counts[sku] = n
+	}
+	return counts, nil
+}
+
+func (s *memoryStore) Add(sku SKU, periods []string, n int64) error {
+	for _, period := range periods {
+		if s.counts[period] == nil {
+			s.counts[period] = make(map[SKU]int64)
+		}
+		s.counts[period][sku] += n
+	}
+	return nil
+}
+
+// meterUse Meter.Useの呼び出し
+type meterUse struct {
+	units   int
+	wantErr bool
+}
+
+func TestMeterBudget(t *testing.T) {
+	tests := []struct {
+		name          string
+		budget        Budget
+		threshold     float64
+		uses          []meterUse
+		wantDaily     int64
+		wantCacheOnly bool // 最後の呼び出し後にcache_onlyになっているか
+	}{
+		{
+			name:      "予算なしは無制限",
+			threshold: 0.9,
+			uses:      []meterUse{{100, false}, {100, false}},
+			wantDaily: 200,
+		},
+		{
+			name:          "1日の予算に達すると失敗させる",
+			budget:        Budget{Daily: 3},
+			threshold:     1,
+			uses:          []meterUse{{1, false}, {1, false}, {1, false}, {1, true}},
+			wantDaily:     3,
+			wantCacheOnly: true,
+		},
+		{
+			name:          "閾値で予算より手前で失敗させる",
+			budget:        Budget{Daily: 10},
+			threshold:     0.5,
+			uses:          []meterUse{{5, false}, {1, true}},
+			wantDaily:     5,
+			wantCacheOnly: true,
+		},
+		{
+			name:          "予算を超える呼び出しは記録せず、収まる呼び出しは続ける",
+			budget:        Budget{Daily: 10},
+			threshold:     1,
+			uses:          []meterUse{{8, false}, {3, true}, {2, false}},
+			wantDaily:     10,
+			wantCacheOnly: true,
+		},
+		{
+			name:          "1か月の予算",
+			budget:        Budget{Daily: 100, Monthly: 4},
+			threshold:     1,
+			uses:          []meterUse{{4, false}, {1, true}},
+			wantDaily:     4,
+			wantCacheOnly: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMeter(Config{Budgets: map[SKU]Budget{SKUNearbySearch: tt.budget}, Threshold: tt.threshold}, nil)
+			if err != nil {
+				t.Fatalf("NewMeter: %v", err)
+			}
+			ctx, recorder := WithRecorder(context.Background())
+			for i, use := range tt.uses {
+				err := m.Use(ctx, SKUNearbySearch, use.units)
+				if use.wantErr != (err != nil) {
+					t.Fatalf("uses[%d]: Use = %v, wantErr %v", i, err, use.wantErr)
+				}
+				if err != nil && !errors.Is(err, ErrBudgetExceeded) {
+					t.Errorf("uses[%d]: Use = %v, want ErrBudgetExceeded", i, err)
+				}
+			}
+
+			stats := m.Stats().SKUs[1]
+			if stats.SKU != SKUNearbySearch {
+				t.Fatalf("stats SKU = %s, want %s", stats.SKU, SKUNearbySearch)
+			}
+			if stats.Daily != tt.wantDaily || stats.Total != tt.wantDaily {
+				t.Errorf("daily, total = %d, %d, want %d", stats.Daily, stats.Total, tt.wantDaily)
+			}
+			if stats.CacheOnly != tt.wantCacheOnly {
+				t.Errorf("CacheOnly = %v, want %v", stats.CacheOnly, tt.wantCacheOnly)
+			}
+			// 失敗させた呼び出しはリクエストの呼び出し回数にも数えない
+			if got := recorder.Counts()[SKUNearbySearch]; got != tt.wantDaily {
+				t.Errorf("recorder = %d, want %d", got, tt.wantDaily)
+			}
+		})
+	}
+}
+
+func TestMeterRollover(t *testing.T) {
+	store := &memoryStore{counts: map[string]map[SKU]int64{
+		"2025-04-30": {SKUPlaceDetails: 2},
+		"2025-04":    {SKUPlaceDetails: 5},
+	}}
+	m, err := NewMeter(Config{Budgets: map[SKU]Budget{SKUPlaceDetails: {Daily: 3, Monthly: 100}}, Threshold: 1}, store)
+	if err != nil {
+		t.Fatalf("NewMeter: %v", err)
+	}
+	// NewMeterは現在時刻の集計を読み込むため、2025-04-30 23:59（日本時間）の集計を読み込み直す
+	now := time.Date(2025, 4, 30, 14, 59, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	m.day, m.month = periods(now)
+	m.daily, _ = store.Load(m.day)
+	m.monthly, _ = store.Load(m.month)
+
+	ctx := context.Background()
+	if err := m.Use(ctx, SKUPlaceDetails, 1); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if err := m.Use(ctx, SKUPlaceDetails, 1); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Use = %v, want ErrBudgetExceeded (daily budget)", err)
+	}
+
+	// 日本時間で日付が変わると1日の予算が戻る
+	now = now.Add(2 * time.Minute)
+	if err := m.Use(ctx, SKUPlaceDetails, 1); err != nil {
+		t.Fatalf("Use after rollover: %v", err)
+	}
+	stats := m.Stats()
+	if stats.Day != "2025-05-01" || stats.Month != "2025-05" {
+		t.Errorf("period = %s, %s, want 2025-05-01, 2025-05", stats.Day, stats.Month)
+	}
+	want := map[string]map[SKU]int64{
+		"2025-04-30": {SKUPlaceDetails: 3},
+		"2025-04":    {SKUPlaceDetails: 6},
+		"2025-05-01": {SKUPlaceDetails: 1},
+		"2025-05":    {SKUPlaceDetails: 1},
+	}
+	if !reflect.DeepEqual(store.counts, want) {
+		t.Errorf("store = %v, want %v", store.counts, want)
+	}
+}
+
+func TestMeterNil(t *testing.T) {
+	var m *Meter
+	ctx, recorder := WithRecorder(context.Background())
+	if err := m.Use(ctx, SKUTextSearch, 1); err != nil {
+		t.Errorf("Use = %v, want nil", err)
+	}
+	if got := recorder.String(); got != "none" {
+		t.Errorf("recorder = %q, want none", got)
+	}
+}
@@ -0,0 +1,108 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// recorderKey contextにRecorderを保持するためのキー
+type recorderKey struct{}
+
+// Recorder 1回のリクエストで行った外部APIの呼び出し回数
+type Recorder struct {
+	mu        sync.Mutex
+	counts    map[SKU]int64
+	coalesced map[SKU]int64 // 同時に行われた他のリクエストの呼び出しを共有した回数（課金されていない）
+}
+
+// WithRecorder 新しいRecorderを設定したcontextを返す
+// 返したcontext（とそこから派生したcontext）でMeter.Useを呼び出すと、Recorderにも記録される
+func WithRecorder(ctx context.Context) (context.Context, *Recorder) {
+	r := &Recorder{counts: make(map[SKU]int64), coalesced: make(map[SKU]int64)}
+	return context.WithValue(ctx, recorderKey{}, r), r
+}
+
+// Record ctxに設定されたRecorderに、別のRecorderで計測した呼び出し回数countsを加える
+// Meter.Useで記録済みの呼び出しを、そのリクエストの呼び出しとして数える場合に使う（Meterの集計には加えない）
+func Record(ctx context.Context, counts map[SKU]int64) {
+	if r := recorderFrom(ctx); r != nil {
+		for sku, n := range counts {
+			r.add(sku, n)
+		}
+	}
+}
+
+// RecordCoalesced ctxに設定されたRecorderに、他のリクエストと共有した呼び出しの回数countsを加える
+// 共有した呼び出しは他のリクエストで課金されているため、呼び出し回数とは別に数える
+func RecordCoalesced(ctx context.Context, counts map[SKU]int64) {
+	r := recorderFrom(ctx)
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for sku, n := range counts {
+		r.coalesced[sku] += n
+	}
+}
+
+// recorderFrom ctxに設定されたRecorderを返す（設定されていない場合はnil）
+func recorderFrom(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// add 呼び出し回数を加える
+func (r *Recorder) add(sku SKU, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[sku] += n
+}
+
+// Counts SKUごとの呼び出し回数を返す
+func (r *Recorder) Counts() map[SKU]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[SKU]int64, len(r.counts))
+	for sku, n := range r.counts {
+		counts[sku] = n
+	}
+	return counts
+}
+
+// Coalesced SKUごとの他のリクエストと共有した呼び出しの回数を返す
+func (r *Recorder) Coalesced() map[SKU]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	coalesced := make(map[SKU]int64, len(r.coalesced))
+	for sku, n := range r.coalesced {
+		coalesced[sku] = n
+	}
+	return coalesced
+}
+
+// String レスポンスヘッダー用の表記（"text_search=1, nearby_search=4"、呼び出していない場合は"none"）
+// 他のリクエストと共有した呼び出しがある場合は "; coalesced: place_details=1" のように続ける
+func (r *Recorder) String() string {
+	s := formatCounts(r.Counts())
+	if s == "" {
+		s = "none"
+	}
+	if coalesced := formatCounts(r.Coalesced()); coalesced != "" {
+		s += "; coalesced: " + coalesced
+	}
+	return s
+}
+
+// formatCounts SKUの表示順に "text_search=1, nearby_search=4" の形式にする（0回のSKUは含めない）
+func formatCounts(counts map[SKU]int64) string {
+	var parts []string
+	for _, sku := range SKUs {
+		if n := counts[sku]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s=%d", sku, n))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,62 @@
+package usage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// IStore 呼び出し回数を再起動後も保持するストアのインターフェース
+type IStore interface {
+	Load(period string) (map[SKU]int64, error)
+	Add(sku SKU, periods []string, n int64) error
+}
+
+// SQLiteStore SQLiteのapi_usageテーブルを使用したストア
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore 新しいSQLiteStoreを作成
+func NewSQLiteStore(db *sql.DB) IStore {
+	return &SQLiteStore{
+		db: db,
+	}
+}
+
+// Load 集計期間のSKUごとの呼び出し回数を取得する
+func (s *SQLiteStore) Load(period string) (map[SKU]int64, error) {
+	rows, err := s.db.Query(`SELECT sku, count FROM api_usage WHERE period = ?`, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load api usage: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[SKU]int64)
+	for rows.Next() {
+		var sku string
+		var count int64
+		if err := rows.Scan(&sku, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan api usage: %w", err)
+		}
+		counts[SKU(sku)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load api usage: %w", err)
+	}
+	return counts, nil
+}
+
+// Add 各集計期間の呼び出し回数にnを加える
+func (s *SQLiteStore) Add(sku SKU, periods []string, n int64) error {
+	for _, period := range periods {
+		_, err := s.db.Exec(
+			`INSERT INTO api_usage (sku, period, count) VALUES (?, ?, ?)
+			ON CONFLICT(sku, period) DO UPDATE SET count = count + excluded.count`,
+			string(sku), period, n,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to add api usage: %w", err)
+		}
+	}
+	return nil
+}
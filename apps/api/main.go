@@ -1,13 +1,23 @@
 package main
 
 import (
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"fukuoka-ai-api/controllers"
+	"fukuoka-ai-api/infra/cache"
+	"fukuoka-ai-api/infra/database"
+	"fukuoka-ai-api/infra/repository"
 	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/infra/tags"
+	"fukuoka-ai-api/infra/usage"
+	"fukuoka-ai-api/pkg/logging"
 	"fukuoka-ai-api/usecase"
+	"fukuoka-ai-api/usecase/rerank"
+	"fukuoka-ai-api/usecase/scoring"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -16,33 +26,57 @@ import (
 func main() {
 	// .envファイルを読み込む（複数のパスを試す）
 	envPaths := []string{
-		filepath.Join("..", "..", ".env"),  // apps/api から実行する場合
-		".env",                               // プロジェクトルートから実行する場合
-		filepath.Join("..", ".env"),         // apps から実行する場合
+		filepath.Join("..", "..", ".env"), // apps/api から実行する場合
+		".env",                            // プロジェクトルートから実行する場合
+		filepath.Join("..", ".env"),       // apps から実行する場合
 	}
-	
+
 	loaded := false
 	for _, envPath := range envPaths {
 		if err := godotenv.Load(envPath); err == nil {
-			log.Printf("Loaded .env file from: %s", envPath)
+			slog.Info("loaded .env file", "path", envPath)
 			loaded = true
 			break
 		}
 	}
-	
+
 	if !loaded {
 		// .envファイルが見つからない場合でも続行（環境変数が直接設定されているか、Docker Compose経由で渡されている可能性がある）
-		log.Printf("Warning: .env file not found, using system environment variables")
+		slog.Warn(".env file not found, using system environment variables")
+	}
+
+	// 構造化ログの設定（LOG_FORMAT・LOG_LEVEL）
+	// APIキーはすべてのログとエラーレスポンスから伏せる
+	logConfig, err := logging.LoadConfig()
+	if err != nil {
+		fatal("invalid log configuration", "error", err)
+	}
+	logging.Setup(logConfig, os.Getenv("GOOGLE_MAPS_API_KEY"))
+
+	// データベースの初期化（マイグレーションも実行）
+	dbPath := os.Getenv("DB_PATH")
+	if dbPath == "" {
+		dbPath = "fukuoka_ai.db"
 	}
+	db, err := database.NewSQLiteDB(dbPath)
+	if err != nil {
+		fatal("failed to initialize database", "error", err)
+	}
+	defer db.Close()
+	slog.Info("database opened", "path", dbPath)
 
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Recovery())
+	// リクエストIDの発行とアクセスログ（クエリ文字列は出力しない）
+	router.Use(controllers.RequestLogger())
 
 	// CORS設定
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-User-Id")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-User-Id, X-Request-Id")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Upstream-Usage, X-Partial-Result, X-Request-Id")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -51,35 +85,222 @@ func main() {
 
 		c.Next()
 	})
+	// リクエストごとの外部APIの呼び出し回数をX-Upstream-Usageヘッダーで返す
+	router.Use(controllers.UsageHeader())
+
+	// 永続化の依存関係
+	tripRepository := repository.NewTripRepository(db)
+
+	// 外部API呼び出しを並列に行う際の同時実行数（FANOUT_CONCURRENCY、デフォルト: 4）
+	fanoutConcurrency := 4
+	if v := os.Getenv("FANOUT_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			fatal("FANOUT_CONCURRENCY must be a positive integer", "value", v)
+		}
+		fanoutConcurrency = n
+	}
+
+	// 外部APIごとの1回のHTTPリクエストのタイムアウト（UPSTREAM_TIMEOUT_*）
+	timeouts, err := service.LoadTimeouts()
+	if err != nil {
+		fatal("invalid upstream timeout configuration", "error", err)
+	}
+	// 一時的な失敗の再試行と、外部APIごとのサーキットブレーカーの設定（RETRY_*、BREAKER_*）
+	resilienceConfig, err := service.LoadResilienceConfig()
+	if err != nil {
+		fatal("invalid retry configuration", "error", err)
+	}
+
+	// Nearby Searchのページングの設定（NEARBY_*）
+	nearbyPagination, err := service.LoadNearbyPaginationConfig()
+	if err != nil {
+		fatal("invalid nearby search pagination configuration", "error", err)
+	}
+
+	// 外部APIのベースURL（GOOGLE_PLACES_BASE_URL、GOOGLE_ROUTES_BASE_URL）
+	// ローカルの偽サーバー（cmd/fakegmaps）に向けるとオフラインで動作を確認できる
+	endpoints, err := service.LoadEndpoints()
+	if err != nil {
+		fatal("invalid upstream endpoint configuration", "error", err)
+	}
+
+	// 外部APIの呼び出し回数の計測と、SKUごとの予算（USAGE_BUDGET_*）
+	// 呼び出し回数は再起動後も保持し、予算の上限に近づいたSKUはキャッシュのみで応答する
+	usageConfig, err := usage.LoadConfig()
+	if err != nil {
+		fatal("invalid usage budget configuration", "error", err)
+	}
+	meter, err := usage.NewMeter(usageConfig, usage.NewSQLiteStore(db))
+	if err != nil {
+		fatal("failed to load api usage", "error", err)
+	}
+
+	// 興味タグの定義（TAGS_FILE、未設定の場合は組み込みのinfra/tags/tags.json）
+	// 周辺検索のタイプ・キーワード、スコアの重み、フロントエンドの選択肢（GET /tags）で共有する
+	tagRegistry, err := tags.Load(os.Getenv("TAGS_FILE"))
+	if err != nil {
+		fatal("failed to load interest tags", "error", err)
+	}
 
 	// リコメンド機能の依存関係
-	geocodingService := service.NewGeocodingService()
-	nearbySearchService := service.NewNearbySearchService()
-	placeDetailsService := service.NewPlaceDetailsService()
-	routeService := service.NewRouteService()
-	recommendUsecase := usecase.NewRecommendUsecase(geocodingService, nearbySearchService, placeDetailsService)
-	resultUsecase := usecase.NewResultUsecase(geocodingService, placeDetailsService, routeService)
+	geocodingService := service.NewGeocodingService(service.NewUpstream("Google Places API (Text Search)", usage.SKUTextSearch, timeouts.Geocoding, resilienceConfig, meter), endpoints.PlacesBaseURL)
+	nearbySearchService := service.NewNearbySearchService(service.NewUpstream("Google Places API (Nearby Search)", usage.SKUNearbySearch, timeouts.NearbySearch, resilienceConfig, meter), endpoints.PlacesBaseURL, fanoutConcurrency, nearbyPagination, tagRegistry)
+	placeDetailsService := service.NewPlaceDetailsService(service.NewUpstream("Google Places API (Place Details)", usage.SKUPlaceDetails, timeouts.PlaceDetails, resilienceConfig, meter), endpoints.PlacesBaseURL)
+	routeService := service.NewRouteService(service.NewUpstream("Google Routes API (computeRoutes)", usage.SKUComputeRoutes, timeouts.Routes, resilienceConfig, meter), endpoints.RoutesBaseURL)
+	routeMatrixService := service.NewRouteMatrixService(service.NewUpstream("Google Routes API (computeRouteMatrix)", usage.SKUComputeRouteMatrix, timeouts.RouteMatrix, resilienceConfig, meter), endpoints.RoutesBaseURL)
+
+	// free_textの解釈（ML_SERVICE_URLが設定されている場合はMLサービス、使えない場合は辞書で推定する）
+	// 辞書による推定に切り替えられるため、MLサービスの呼び出しは再試行しない
+	var interestExtractor service.IInterestExtractor = service.NewDictionaryInterestExtractor(tagRegistry)
+	if endpoints.MLBaseURL != "" {
+		mlResilience := resilienceConfig
+		mlResilience.MaxAttempts = 1
+		mlExtractor := service.NewMLInterestExtractor(service.NewUpstream("ML service (extract-interests)", "", timeouts.ML, mlResilience, nil), endpoints.MLBaseURL, tagRegistry)
+		interestExtractor = service.NewFallbackInterestExtractor(mlExtractor, interestExtractor)
+	}
+
+	// 候補の関連性スコアの計算方法と重み（SCORER、SCORE_*）
+	scoringConfig, err := scoring.LoadConfig()
+	if err != nil {
+		fatal("invalid scoring configuration", "error", err)
+	}
+	scorer, err := scoring.New(scoringConfig, tagRegistry)
+	if err != nil {
+		fatal("failed to create scorer", "error", err)
+	}
+
+	// 表示する候補の関連性と多様性のバランス（RERANK_LAMBDA）
+	rerankConfig, err := rerank.LoadConfig()
+	if err != nil {
+		fatal("invalid rerank configuration", "error", err)
+	}
+
+	// 寄り道の距離を道路の距離（ルート行列）で求め直す上位の候補数（DETOUR_ROAD_CANDIDATES、デフォルト: 8、0の場合は直線距離のみ）
+	detourRoadCandidates := 8
+	if v := os.Getenv("DETOUR_ROAD_CANDIDATES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			fatal("DETOUR_ROAD_CANDIDATES must be a non-negative integer", "value", v)
+		}
+		detourRoadCandidates = n
+	}
+
+	// 同時に行われた同じ条件の呼び出しを1回のHTTPリクエストにまとめる（キャッシュミスの同時発生もまとめるためキャッシュの内側に置く）
+	geocodingService = service.NewCoalescedGeocodingService(geocodingService)
+	nearbySearchService = service.NewCoalescedNearbySearchService(nearbySearchService)
+	placeDetailsService = service.NewCoalescedPlaceDetailsService(placeDetailsService)
+
+	// 外部APIの結果をキャッシュする（CACHE_ENABLED=falseで無効化、CACHE_PERSIST=trueで再起動後も保持）
+	cacheConfig, err := cache.LoadConfig()
+	if err != nil {
+		fatal("invalid cache configuration", "error", err)
+	}
+	var caches []*cache.Cache
+	if cacheConfig.Enabled {
+		var cacheStore cache.IStore
+		if cacheConfig.Persist {
+			cacheStore = cache.NewSQLiteStore(db)
+			if err := cacheStore.DeleteExpired(); err != nil {
+				slog.Warn("failed to delete expired cache entries", "error", err)
+			}
+		}
+		geocodingCache := cache.New("geocoding", cacheConfig.GeocodingTTL, cacheConfig.Capacity, cacheStore)
+		nearbySearchCache := cache.New("nearby_search", cacheConfig.NearbyTTL, cacheConfig.Capacity, cacheStore)
+		placeDetailsCache := cache.New("place_details", cacheConfig.DetailsTTL, cacheConfig.Capacity, cacheStore)
+		geocodingService = service.NewCachedGeocodingService(geocodingService, geocodingCache)
+		nearbySearchService = service.NewCachedNearbySearchService(nearbySearchService, nearbySearchCache)
+		placeDetailsService = service.NewCachedPlaceDetailsService(placeDetailsService, placeDetailsCache)
+		caches = append(caches, geocodingCache, nearbySearchCache, placeDetailsCache)
+	}
+
+	recommendUsecase := usecase.NewRecommendUsecase(usecase.RecommendDeps{
+		GeocodingService:     geocodingService,
+		NearbySearchService:  nearbySearchService,
+		PlaceDetailsService:  placeDetailsService,
+		TripRepository:       tripRepository,
+		Concurrency:          fanoutConcurrency,
+		Pagination:           nearbyPagination,
+		Meter:                meter,
+		TagRegistry:          tagRegistry,
+		InterestExtractor:    interestExtractor,
+		Scorer:               scorer,
+		Reranker:             rerank.NewMMR(rerankConfig),
+		RouteMatrixService:   routeMatrixService,
+		DetourRoadCandidates: detourRoadCandidates,
+	})
+	addUsecase := usecase.NewAddUsecase(placeDetailsService, tripRepository)
+	resultUsecase := usecase.NewResultUsecase(geocodingService, placeDetailsService, routeService, routeMatrixService, tripRepository)
+	tripUsecase := usecase.NewTripUsecase(tripRepository, recommendUsecase, resultUsecase, routeService, placeDetailsService)
 	recommendController := controllers.NewRecommendController(recommendUsecase)
-	addController := controllers.NewAddController()
+	addController := controllers.NewAddController(addUsecase)
 	resultController := controllers.NewResultController(resultUsecase)
 	geocodingController := controllers.NewGeocodingController(geocodingService)
+	shareUsecase := usecase.NewShareUsecase(tripRepository)
+	tripController := controllers.NewTripController(tripUsecase)
+	shareController := controllers.NewShareController(shareUsecase)
+	cacheController := controllers.NewCacheController(caches...)
+	usageController := controllers.NewUsageController(meter)
+	tagController := controllers.NewTagController(tagRegistry)
+
+	// 外部APIを呼び出すエンドポイント全体の処理時間の上限（REQUEST_TIMEOUT_*）
+	// 期限を過ぎた場合やクライアントが切断した場合は、実行中の外部API呼び出しも打ち切る
+	recommendTimeout := envDuration("REQUEST_TIMEOUT_RECOMMEND", 20*time.Second)
+	resultTimeout := envDuration("REQUEST_TIMEOUT_RESULT", 30*time.Second)
+	placeTimeout := envDuration("REQUEST_TIMEOUT_PLACE", 10*time.Second)
+	tripTimeout := envDuration("REQUEST_TIMEOUT_TRIP", 45*time.Second)
 
 	// リコメンド機能のエンドポイント
-	router.POST("/recommend", recommendController.Recommend)
+	router.POST("/recommend", controllers.Deadline(recommendTimeout), recommendController.Recommend)
 	// 場所追加機能のエンドポイント
-	router.POST("/add/:place_id", addController.AddPlace)
+	router.POST("/add/:place_id", controllers.Deadline(placeTimeout), addController.AddPlace)
 	// ルート提案機能のエンドポイント
-	router.POST("/result", resultController.Result)
+	router.POST("/result", controllers.Deadline(resultTimeout), resultController.Result)
 	// ジオコーディング機能のエンドポイント（場所名からplace_idを取得）
-	router.POST("/geocoding", geocodingController.GetPlaceID)
+	router.POST("/geocoding", controllers.Deadline(placeTimeout), geocodingController.GetPlaceID)
+	// 旅程生成機能のエンドポイント（リコメンド＋ルート計算を1回で実行）
+	router.POST("/v1/trips", controllers.Deadline(tripTimeout), tripController.CreateTrip)
+	// 保存済み旅程の取得エンドポイント
+	router.GET("/v1/trips/:trip_id", tripController.GetTrip)
+	// 旅程の再計算エンドポイント（ユーザー指定の順序を維持）
+	router.POST("/v1/trips/:trip_id/recompute", controllers.Deadline(resultTimeout), tripController.RecomputeTrip)
+	// 旅程共有機能のエンドポイント（発行・停止は所有者のみ、取得はログイン不要）
+	router.POST("/v1/trips/:trip_id/share", shareController.CreateShare)
+	router.DELETE("/v1/trips/:trip_id/share", shareController.RevokeShare)
+	router.GET("/v1/shares/:share_id", shareController.GetShare)
+	// キャッシュのヒット/ミス統計のエンドポイント
+	router.GET("/v1/cache/stats", cacheController.GetStats)
+	// 外部APIの呼び出し回数と予算のエンドポイント
+	router.GET("/v1/usage/stats", usageController.GetStats)
+	// 興味タグの一覧のエンドポイント（フロントエンドのタグの選択肢）
+	router.GET("/tags", tagController.ListTags)
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
+	slog.Info("server starting", "port", port)
 	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		fatal("failed to start server", "error", err)
 	}
 }
+
+// envDuration 環境変数を期間として読み込む（"30s"など、未設定の場合は既定値、不正な値の場合は起動を中止する）
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		fatal(name+" must be a positive duration (e.g. \"30s\")", "value", v)
+	}
+	return d
+}
+
+// fatal エラーをログに出力して起動を中止する
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
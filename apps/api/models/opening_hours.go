@@ -0,0 +1,34 @@
+package models
+
+// OpeningHours 週単位の営業時間
+type OpeningHours struct {
+	AlwaysOpen  bool            `json:"always_open,omitempty"`  // 24時間営業
+	Periods     []OpeningPeriod `json:"periods"`                // 営業時間帯（曜日ごと）
+	WeekdayText []string        `json:"weekday_text,omitempty"` // 表示用の営業時間（例: "月曜日: 9時00分～17時00分"）
+}
+
+// OpeningPeriod 営業時間帯
+// 曜日は0=日曜日〜6=土曜日、時刻は0時からの分数
+// 深夜営業の場合はCloseDayがOpenDayの翌日になる
+type OpeningPeriod struct {
+	OpenDay     int `json:"open_day"`
+	OpenMinute  int `json:"open_minute"`
+	CloseDay    int `json:"close_day"`
+	CloseMinute int `json:"close_minute"`
+}
+
+// UnvisitablePlaceの理由
+const (
+	UnvisitableReasonClosed           = "closed"             // 旅行日が定休日
+	UnvisitableReasonOutsideHours     = "outside_hours"      // 営業時間内に訪問できる順序がない
+	UnvisitableReasonClosesBeforeStay = "closes_before_stay" // 実際の所要時間では滞在中に閉店する
+)
+
+// UnvisitablePlace 営業時間内に訪問できない場所
+type UnvisitablePlace struct {
+	ID           string   `json:"id,omitempty"` // 旅程に保存したスポットのID（trip_places.id）
+	PlaceID      string   `json:"place_id"`
+	Name         string   `json:"name"`
+	Reason       string   `json:"reason"`                  // closed/outside_hours/closes_before_stay
+	OpeningHours []string `json:"opening_hours,omitempty"` // 表示用の営業時間
+}
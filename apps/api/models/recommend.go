@@ -4,8 +4,15 @@ package models
 type RecommendRequest struct {
 	MustPlaces   []string `json:"must_places" binding:"required"`   // 寄りたい場所（リスト）
 	InterestTags []string `json:"interest_tags" binding:"required"` // 興味タグ（リスト）
+	FreeText     string   `json:"free_text,omitempty"`              // 自由記述（オプション、興味タグ・検索キーワードを推定する）
 	StartPlace   string   `json:"start_place,omitempty"`            // 出発地点（オプション、デフォルトは博多駅）
 	GoalPlace    string   `json:"goal_place,omitempty"`             // ゴール地点（オプション）
+	TripID       string   `json:"trip_id,omitempty"`                // 既存の旅程ID（オプション、省略時は新規作成）
+	SearchPages  int      `json:"search_pages,omitempty"`           // 周辺検索で興味タグごとに取得するページ数（オプション、1ページ最大20件）
+	TravelMode   string   `json:"travel_mode,omitempty"`            // 移動手段（オプション、デフォルトはDRIVE）。寄り道の所要時間の計算に使用
+	StartTime    string   `json:"start_time,omitempty"`             // 開始時刻（オプション、省略時は旅程の開始時刻）。寄り道の交通状況の出発時刻に使用
+	Date         string   `json:"date,omitempty"`                   // 旅行日（オプション、省略時は旅程の旅行日、未保存の場合は今日）
+	UserID       string   `json:"-"`                                // X-User-Idヘッダーから設定
 }
 
 // Place 場所情報
@@ -20,12 +27,44 @@ type Place struct {
 	Category       string  `json:"category,omitempty"`
 	Address        string  `json:"address,omitempty"`
 	RelevanceScore float64 `json:"relevance_score,omitempty"` // 関連性スコア
+	Detour         *Detour `json:"detour,omitempty"`          // 旅程の地点の間に立ち寄る場合の寄り道（リコメンド時のみ）
+	StayMinutes    int     `json:"stay_minutes,omitempty"`    // 滞在時間（分、ルート提案時のみ）
+	TimeRange      string  `json:"time_range,omitempty"`      // 滞在時間帯（例: "10:00-11:00"、ルート提案時のみ）
 }
 
 // RecommendResponse リコメンド機能のレスポンス
 type RecommendResponse struct {
 	Places           []Place `json:"places"`             // 推薦場所（最大10件、レビュー順）
 	MaxPossibleScore float64 `json:"max_possible_score"` // 理論的最大スコア
+	TripID           string  `json:"trip_id"`            // 保存された旅程ID
+
+	ExtractedInterests *ExtractedInterests `json:"extracted_interests,omitempty"` // free_textから推定した興味（free_textが指定された場合のみ）
+}
+
+// ExtractedInterests 自由記述から推定した興味
+type ExtractedInterests struct {
+	Tags     []WeightedTag `json:"tags"`     // 興味タグ（タグ定義のID）と重み
+	Keywords []string      `json:"keywords"` // タグ定義にない追加の検索キーワード
+}
+
+// WeightedTag 重み付きの興味タグ
+type WeightedTag struct {
+	ID     string  `json:"id"`     // タグ定義のID
+	Weight float64 `json:"weight"` // 重み（0〜1、興味タグとして直接指定した場合は1）
+}
+
+// 寄り道の距離・所要時間の求め方
+const (
+	DetourSourceHaversine = "haversine" // 直線距離（所要時間は移動手段の平均速度からの見積もり）
+	DetourSourceRoad      = "road"      // 道路の距離・所要時間（ルート行列、リクエストの移動手段）
+)
+
+// Detour 旅程の地点（出発地点・寄りたい場所・ゴール地点）の間に候補を立ち寄らせた場合に増える移動
+// 地点を直線距離で最短になる順にたどり、増える距離が最も小さい区間に立ち寄らせた場合の値
+type Detour struct {
+	DistanceMeters  float64 `json:"distance_meters"`  // 増える移動距離（メートル）
+	DurationSeconds float64 `json:"duration_seconds"` // 増える所要時間（秒）
+	Source          string  `json:"source"`           // 求め方（"haversine" / "road"）
 }
 
 // Coordinate 座標情報
@@ -3,27 +3,45 @@ package models
 // ResultRequest ルート提案機能のリクエスト
 type ResultRequest struct {
 	Places []string `json:"places" binding:"required"` // 場所IDのリスト
+	TripID string   `json:"trip_id,omitempty"`         // 保存先の旅程ID（オプション、省略時は新規作成）
+	UserID string   `json:"-"`                         // X-User-Idヘッダーから設定
+
+	StartTime      string         `json:"start_time,omitempty"`       // 開始時刻（例: "10:00"、省略時は旅程の開始時刻）
+	StayMinutesMap map[string]int `json:"stay_minutes_map,omitempty"` // place_id → 滞在時間（分）
+	TravelMode     string         `json:"travel_mode,omitempty"`      // 移動手段（DRIVE/WALK/BICYCLE/TWO_WHEELER/TRANSIT、省略時はDRIVE）
+	Date           string         `json:"date,omitempty"`             // 旅行日（例: "2025-04-01"、省略時は旅程の旅行日、未保存の場合は今日）。営業時間の判定に使用
 }
 
 // RouteLeg ルートの区間情報
 type RouteLeg struct {
-	StartLocation  Coordinate `json:"start_location"`
-	EndLocation    Coordinate `json:"end_location"`
-	DistanceMeters int        `json:"distance_meters"` // メートル単位
-	Duration       string     `json:"duration"`        // 所要時間（例: "3600s"）
+	StartLocation  Coordinate   `json:"start_location"`
+	EndLocation    Coordinate   `json:"end_location"`
+	DistanceMeters int          `json:"distance_meters"`    // メートル単位
+	Duration       string       `json:"duration"`           // 所要時間（例: "3600s"）
+	Polyline       string       `json:"polyline,omitempty"` // 区間のエンコード済みポリライン
+	Path           []Coordinate `json:"path,omitempty"`     // ポリラインをデコードした座標列
 }
 
 // Route ルート情報
 type Route struct {
-	Legs           []RouteLeg `json:"legs"`
-	DistanceMeters int        `json:"distance_meters"` // 総距離（メートル単位）
-	Duration       string     `json:"duration"`        // 総所要時間（例: "3600s"）
-	OptimizedOrder []int      `json:"optimized_order"` // 最適化された順序
+	Legs           []RouteLeg   `json:"legs"`
+	DistanceMeters int          `json:"distance_meters"`        // 総距離（メートル単位）
+	Duration       string       `json:"duration"`               // 総所要時間（例: "3600s"）
+	OptimizedOrder []int        `json:"optimized_order"`        // 最適化された順序
+	TravelMode     string       `json:"travel_mode"`            // 移動手段
+	Polyline       string       `json:"polyline,omitempty"`     // ルート全体のエンコード済みポリライン
+	Path           []Coordinate `json:"path,omitempty"`         // ポリラインをデコードした座標列
+	WaitMinutes    []int        `json:"wait_minutes,omitempty"` // 各スポットで営業開始を待つ時間（分、旅程順）
 }
 
 // ResultResponse ルート提案機能のレスポンス
 type ResultResponse struct {
-	Places []Place `json:"places"` // 最適化された順序の場所リスト
-	Route  Route   `json:"route"`  // ルート情報
-}
+	Places []Place `json:"places"`  // 最適化された順序の場所リスト
+	Route  Route   `json:"route"`   // ルート情報
+	TripID string  `json:"trip_id"` // 保存された旅程ID
 
+	StartTime string `json:"start_time"` // 開始時刻（例: "10:00"）
+	EndTime   string `json:"end_time"`   // 最後の場所を出発する時刻（例: "17:30"）
+
+	Unvisitable []UnvisitablePlace `json:"unvisitable"` // 営業時間内に訪問できない場所
+}
@@ -0,0 +1,22 @@
+package models
+
+// Tag 興味タグ
+type Tag struct {
+	ID          string             `json:"id"`                     // タグID（例: "cafe"）
+	Labels      TagLabels          `json:"labels"`                 // 表示名
+	Synonyms    []string           `json:"synonyms,omitempty"`     // 同じタグとして扱う別名
+	PlaceTypes  []string           `json:"place_types,omitempty"`  // Nearby Searchで検索するPlaces APIのタイプ（先頭を使用）
+	Keywords    []string           `json:"keywords,omitempty"`     // タイプがない場合にNearby Searchで検索するキーワード（先頭を使用）
+	TypeWeights map[string]float64 `json:"type_weights,omitempty"` // 候補のPlaces APIのタイプごとの関連性スコアの加点
+}
+
+// TagLabels 興味タグの言語ごとの表示名
+type TagLabels struct {
+	Ja string `json:"ja"` // 日本語
+	En string `json:"en"` // 英語
+}
+
+// TagsResponse 興味タグ一覧のレスポンス
+type TagsResponse struct {
+	Tags []Tag `json:"tags"`
+}
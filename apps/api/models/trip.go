@@ -0,0 +1,135 @@
+package models
+
+// TripPlaceの種類
+const (
+	TripPlaceKindStart       = "start"       // 出発地点
+	TripPlaceKindMust        = "must"        // 寄りたい場所
+	TripPlaceKindRecommended = "recommended" // リコメンドから追加した場所
+	TripPlaceKindGoal        = "goal"        // ゴール地点
+)
+
+// DefaultStayMinutes 滞在時間が指定されていない場合の既定値（分）
+const DefaultStayMinutes = 60
+
+// DefaultTripStartTime 旅程の開始時刻の既定値
+const DefaultTripStartTime = "10:00"
+
+// User ユーザー情報
+type User struct {
+	ID        string `json:"id"`
+	CreatedAt string `json:"created_at"` // ISO8601
+}
+
+// Trip 旅程情報
+type Trip struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id,omitempty"`
+	Title     string `json:"title"`
+	StartTime string `json:"start_time"`     // 開始時刻（例: "10:00"）
+	Date      string `json:"date,omitempty"` // 旅行日（例: "2025-04-01"、未指定の場合は空）。営業時間の判定に使用
+	CreatedAt string `json:"created_at"`     // ISO8601
+}
+
+// TripPlace 旅程に含まれるスポット
+type TripPlace struct {
+	ID            string  `json:"id"`
+	TripID        string  `json:"trip_id,omitempty"` // 共有された旅程の取得では含めない
+	PlaceID       string  `json:"place_id"`
+	Name          string  `json:"name"`
+	Lat           float64 `json:"lat"`
+	Lng           float64 `json:"lng"`
+	Kind          string  `json:"kind"`         // start/must/recommended/goal
+	StayMinutes   int     `json:"stay_minutes"` // 滞在時間（分）
+	OrderIndex    int     `json:"order_index"`  // 順序
+	Reason        string  `json:"reason,omitempty"`
+	ReviewSummary string  `json:"review_summary,omitempty"`
+	PhotoURL      string  `json:"photo_url,omitempty"`
+	TimeRange     string  `json:"time_range,omitempty"` // 滞在時間帯（例: "10:00-11:00"、保存されたルートから計算）
+
+	// UnvisitableReason 営業時間内に訪問できずルートから除外した理由（closed/outside_hours、訪問するスポットは空）
+	// 除外したスポットは訪問するスポットの後に並べて保存する
+	UnvisitableReason string `json:"unvisitable_reason,omitempty"`
+}
+
+// Share 旅程の共有情報
+type Share struct {
+	ShareID   string `json:"share_id"`
+	TripID    string `json:"trip_id"`
+	CreatedAt string `json:"created_at"` // ISO8601
+}
+
+// TripResponse 保存済み旅程の取得レスポンス
+type TripResponse struct {
+	Trip      Trip        `json:"trip"`
+	Itinerary []TripPlace `json:"itinerary"`       // order_index順
+	Route     *Route      `json:"route,omitempty"` // 最後に計算したルート
+	EndTime   string      `json:"end_time,omitempty"`
+}
+
+// TripHeader 公開用の旅程ヘッダー（ユーザー情報・旅程IDを含まない）
+// 旅程IDを知っていると共有を停止した後も旅程を取得できるため、共有された旅程には含めない
+type TripHeader struct {
+	Title     string `json:"title"`
+	StartTime string `json:"start_time"`
+}
+
+// ShareResponse 共有された旅程の取得レスポンス
+type ShareResponse struct {
+	Trip      TripHeader  `json:"trip"`
+	Itinerary []TripPlace `json:"itinerary"`       // order_index順
+	Route     *Route      `json:"route,omitempty"` // 最後に計算したルート
+	EndTime   string      `json:"end_time,omitempty"`
+}
+
+// AddRequest 場所追加機能のリクエスト（ボディは省略可能）
+type AddRequest struct {
+	TripID string `json:"trip_id,omitempty"` // 追加先の旅程ID
+	UserID string `json:"-"`                 // X-User-Idヘッダーから設定
+}
+
+// CreateTripRequest 旅程生成機能のリクエスト
+type CreateTripRequest struct {
+	MustPlaces   []string `json:"must_places" binding:"required"`   // 寄りたい場所（リスト）
+	InterestTags []string `json:"interest_tags" binding:"required"` // 興味タグ（リスト）
+	FreeText     string   `json:"free_text,omitempty"`              // 自由記述（オプション、興味タグ・検索キーワードを推定する）
+	StartPlace   string   `json:"start_place,omitempty"`            // 出発地点（オプション、デフォルトは博多駅）
+	GoalPlace    string   `json:"goal_place,omitempty"`             // ゴール地点（オプション）
+	StartTime    string   `json:"start_time,omitempty"`             // 開始時刻（オプション、デフォルトは"10:00"）
+	TravelMode   string   `json:"travel_mode,omitempty"`            // 移動手段（オプション、デフォルトはDRIVE）
+	Date         string   `json:"date,omitempty"`                   // 旅行日（オプション、デフォルトは今日）。営業時間の判定に使用
+	SearchPages  int      `json:"search_pages,omitempty"`           // 周辺検索で興味タグごとに取得するページ数（オプション、1ページ最大20件）
+	UserID       string   `json:"-"`                                // X-User-Idヘッダーから設定
+}
+
+// CreateTripResponse 旅程生成機能のレスポンス
+type CreateTripResponse struct {
+	TripID     string      `json:"trip_id"`
+	ShareID    string      `json:"share_id,omitempty"` // 共有ID（ユーザーに紐づく旅程のみ）
+	Itinerary  []TripPlace `json:"itinerary"`          // 最適化された順序のスポット
+	Candidates []Place     `json:"candidates"`         // 追加候補のリコメンド
+	Route      Route       `json:"route"`              // ルート情報
+	StartTime  string      `json:"start_time"`
+	EndTime    string      `json:"end_time"`
+
+	Unvisitable []UnvisitablePlace `json:"unvisitable"` // 営業時間内に訪問できない場所
+}
+
+// RecomputeRequest 旅程の再計算リクエスト
+type RecomputeRequest struct {
+	OrderedPlaceIDs []string       `json:"ordered_place_ids" binding:"required"` // trip_places.idの訪問順リスト
+	StayMinutesMap  map[string]int `json:"stay_minutes_map,omitempty"`           // trip_places.id → 滞在時間（分）
+	StartTime       string         `json:"start_time,omitempty"`                 // 開始時刻（オプション、省略時は旅程の開始時刻）
+	TravelMode      string         `json:"travel_mode,omitempty"`                // 移動手段（オプション、省略時は前回の計算と同じ）
+	Date            string         `json:"date,omitempty"`                       // 旅行日（オプション、省略時は旅程の旅行日、未保存の場合は今日）
+	UserID          string         `json:"-"`                                    // X-User-Idヘッダーから設定
+}
+
+// RecomputeResponse 旅程の再計算レスポンス
+type RecomputeResponse struct {
+	Itinerary []TripPlace `json:"itinerary"` // 指定された順序のスポット
+	Route     Route       `json:"route"`     // ルート情報
+	StartTime string      `json:"start_time"`
+	EndTime   string      `json:"end_time"`
+
+	Unvisitable []UnvisitablePlace `json:"unvisitable"` // 営業時間内に訪問できない場所
+}
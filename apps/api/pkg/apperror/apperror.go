@@ -0,0 +1,143 @@
+// Package apperror ユースケース・外部APIサービスが返すエラーの種類
+// コントローラーはエラーメッセージの文言ではなく種類（errors.Is）でHTTPステータスとエラーコードを決める
+package apperror
+
+import (
+	"errors"
+	"fmt"
+)
+
+// エラーの種類（errors.Isで判定する）
+var (
+	// ErrInvalidInput リクエストの内容が不正
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrPlaceNotFound 指定された場所が見つからない
+	ErrPlaceNotFound = errors.New("place not found")
+	// ErrUpstreamQuota 外部APIの利用上限（クォータ・レート制限）に達した
+	ErrUpstreamQuota = errors.New("upstream quota exceeded")
+	// ErrUpstream 外部APIの呼び出しに失敗した
+	ErrUpstream = errors.New("upstream failure")
+	// ErrConfiguration サーバーの設定（APIキーなど）が不正
+	ErrConfiguration = errors.New("configuration error")
+	// ErrPartialResult 一部の処理に失敗したが、残りの結果は返せる
+	ErrPartialResult = errors.New("partial result")
+)
+
+// Error 種類・エラーコード・詳細情報を持つエラー
+type Error struct {
+	Kind    error  // エラーの種類（ErrInvalidInputなど）
+	Code    string // レスポンスのエラーコード（空の場合は種類ごとの既定のコード）
+	Message string // エラーメッセージ
+	Details string // レスポンスのdetailsに設定する詳細情報（原因となった項目や外部APIのstatusなど）
+	Err     error  // 原因となったエラー
+}
+
+// New 種類kindのエラーを作成
+func New(kind error, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap errを原因とする種類kindのエラーを作成
+func Wrap(kind error, err error, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Partial 一部の処理の失敗を表すエラーを作成（codeはX-Partial-Resultヘッダーで返すコード）
+func Partial(code string, err error, format string, args ...interface{}) *Error {
+	return &Error{Kind: ErrPartialResult, Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// WithCode エラーコードを設定する
+func (e *Error) WithCode(code string) *Error {
+	e.Code = code
+	return e
+}
+
+// WithDetails 詳細情報を設定する
+func (e *Error) WithDetails(format string, args ...interface{}) *Error {
+	e.Details = fmt.Sprintf(format, args...)
+	return e
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is エラーの種類との比較（errors.Is(err, ErrInvalidInput)など）
+func (e *Error) Is(target error) bool { return target == e.Kind }
+
+// Code errに含まれる最初のエラーコードを返す（設定されていない場合は空文字列）
+func Code(err error) string {
+	if e := find(err, func(e *Error) bool { return e.Code != "" }); e != nil {
+		return e.Code
+	}
+	return ""
+}
+
+// Details errに含まれる最初の詳細情報を返す（設定されていない場合は空文字列）
+func Details(err error) string {
+	if e := find(err, func(e *Error) bool { return e.Details != "" }); e != nil {
+		return e.Details
+	}
+	return ""
+}
+
+// find errに含まれる*Errorを外側から順にたどり、matchを満たす最初のものを返す
+func find(err error, match func(e *Error) bool) *Error {
+	for err != nil {
+		var e *Error
+		if !errors.As(err, &e) {
+			return nil
+		}
+		if match(e) {
+			return e
+		}
+		err = e.Err
+	}
+	return nil
+}
+
+// IsPartial errが一部の処理の失敗だけからなるかを返す
+// 結果とともに返されたerrがtrueの場合、呼び出し側は結果を使って処理を続けられる
+func IsPartial(err error) bool {
+	if err == nil {
+		return false
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		for _, e := range errs {
+			if !IsPartial(e) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	}
+	e, ok := err.(*Error)
+	return ok && e.Kind == ErrPartialResult
+}
+
+// PartialCodes errに含まれる一部の処理の失敗のエラーコードを重複を除いて返す
+func PartialCodes(err error) []string {
+	var codes []string
+	seen := make(map[string]bool)
+	var walk func(err error)
+	walk = func(err error) {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range joined.Unwrap() {
+				walk(e)
+			}
+			return
+		}
+		if e, ok := err.(*Error); ok && e.Kind == ErrPartialResult && !seen[e.Code] {
+			seen[e.Code] = true
+			codes = append(codes, e.Code)
+		}
+	}
+	walk(err)
+	return codes
+}
@@ -0,0 +1,133 @@
+// Package logging 構造化ログ（log/slog）の設定
+// リクエストIDをcontextで受け渡してログに付与し、APIキーらしき文字列はすべてのログから伏せる
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Config ログの設定
+type Config struct {
+	Format string     // 出力形式 "text" または "json"（LOG_FORMAT、デフォルト: text）
+	Level  slog.Level // 出力する最低レベル（LOG_LEVEL、debug/info/warn/error、デフォルト: info）
+}
+
+// LoadConfig 環境変数からログの設定を読み込む（未設定の項目は既定値）
+func LoadConfig() (Config, error) {
+	cfg := Config{Format: "text", Level: slog.LevelInfo}
+
+	if v := os.Getenv("LOG_FORMAT"); v != "" {
+		v = strings.ToLower(v)
+		if v != "text" && v != "json" {
+			return cfg, fmt.Errorf("LOG_FORMAT must be \"text\" or \"json\": %s", v)
+		}
+		cfg.Format = v
+	}
+	if v := os.Getenv("LOG_LEVEL"); v != "" {
+		if err := cfg.Level.UnmarshalText([]byte(v)); err != nil {
+			return cfg, fmt.Errorf("LOG_LEVEL must be one of debug, info, warn, error: %s", v)
+		}
+	}
+	return cfg, nil
+}
+
+// Setup 標準エラー出力に書き出すロガーを作成し、slog・logパッケージの既定のロガーにする
+// secretsはログ・エラーメッセージから伏せる文字列（APIキーなど）
+func Setup(cfg Config, secrets ...string) *slog.Logger {
+	SetSecrets(secrets...)
+	logger := New(os.Stderr, cfg)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// New wに書き出すロガーを作成
+// ログにはcontextのリクエストIDを付与し、メッセージと属性の値からAPIキーらしき文字列を伏せる
+func New(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	var handler slog.Handler
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(&redactingHandler{inner: handler})
+}
+
+// requestIDKey リクエストIDを保持するcontextのキー
+type requestIDKey struct{}
+
+// WithRequestID リクエストIDを設定したcontextを返す
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID contextに設定されたリクエストIDを返す（設定されていない場合は空文字列）
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// redactingHandler リクエストIDの付与とAPIキーの伏せ字を行うslog.Handler
+type redactingHandler struct {
+	inner slog.Handler
+}
+
+func (h *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	redacted := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	if id := RequestID(ctx); id != "" {
+		redacted.AddAttrs(slog.String("request_id", id))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.inner.Handle(ctx, redacted)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return &redactingHandler{inner: h.inner.WithAttrs(redacted)}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{inner: h.inner.WithGroup(name)}
+}
+
+// redactAttr 属性の値（文字列・エラー・グループ）からAPIキーらしき文字列を伏せる
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			redacted[i] = redactAttr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return slog.String(a.Key, Redact(x.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, Redact(x.String()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// redacted 伏せた文字列の代わりに出力する文字列
+const redacted = "REDACTED"
+
+// minSecretLength 伏せる対象として登録する文字列の最短の長さ
+// 短い文字列（開発用のダミーのキーなど）を登録すると、無関係な部分まで伏せてしまうため
+const minSecretLength = 8
+
+var (
+	// keyParamPattern URLのクエリ文字列のkeyパラメーター（Places APIのAPIキー）
+	keyParamPattern = regexp.MustCompile(`([?&]key=)[^&\s"']+`)
+	// googleAPIKeyPattern Google APIキーの形式（"AIza"で始まる39文字）
+	googleAPIKeyPattern = regexp.MustCompile(`AIza[0-9A-Za-z_\-]{35}`)
+)
+
+var (
+	secretsMu sync.RWMutex
+	secrets   []string
+)
+
+// SetSecrets ログ・エラーメッセージから伏せる文字列を設定する（短すぎる文字列は無視する）
+func SetSecrets(values ...string) {
+	var s []string
+	for _, v := range values {
+		if len(v) >= minSecretLength {
+			s = append(s, v)
+		}
+	}
+	secretsMu.Lock()
+	secrets = s
+	secretsMu.Unlock()
+}
+
+// Redact sからAPIキーらしき文字列（設定された文字列、URLのkeyパラメーター、Google APIキーの形式）を伏せる
+func Redact(s string) string {
+	secretsMu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	secretsMu.RUnlock()
+	s = keyParamPattern.ReplaceAllString(s, "${1}"+redacted)
+	return googleAPIKeyPattern.ReplaceAllString(s, redacted)
+}
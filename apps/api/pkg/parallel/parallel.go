@@ -0,0 +1,58 @@
+// Package parallel 同時実行数を制限した並列処理
+package parallel
+
+import (
+	"context"
+	"sync"
+)
+
+// Map itemsの各要素にfnを最大limit個まで並列に適用し、入力と同じ順序で結果とエラーを返す
+// どの呼び出しが先に終わっても結果の順序は変わらないため、呼び出し側は結果を順番にマージすればよい
+// limitが1以下の場合は順番に実行する
+// ctxがキャンセルされた後はまだ開始していない要素のfnを呼び出さず、そのエラーにctx.Err()を設定する
+func Map[T, R any](ctx context.Context, limit int, items []T, fn func(i int, item T) (R, error)) ([]R, []error) {
+	results := make([]R, len(items))
+	errs := make([]error, len(items))
+	if len(items) == 0 {
+		return results, errs
+	}
+
+	if limit <= 1 || len(items) == 1 {
+		for i, item := range items {
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				continue
+			}
+			results[i], errs[i] = fn(i, item)
+		}
+		return results, errs
+	}
+	if limit > len(items) {
+		limit = len(items)
+	}
+
+	// 各結果は自分のインデックスにのみ書き込むため、ロックは不要
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			<-sem
+			errs[i] = err
+			continue
+		}
+		wg.Add(1)
+		go func(i int, item T) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = fn(i, item)
+		}(i, item)
+	}
+	wg.Wait()
+	return results, errs
+}
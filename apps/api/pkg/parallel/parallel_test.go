@@ -0,0 +1,115 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	errOdd := errors.New("odd")
+	tests := []struct {
+		name  string
+		limit int
+		items []int
+	}{
+		{"要素なし", 4, nil},
+		{"1要素", 4, []int{1}},
+		{"順番に実行", 1, []int{1, 2, 3, 4, 5}},
+		{"limitが0以下", 0, []int{1, 2, 3}},
+		{"並列に実行", 3, []int{1, 2, 3, 4, 5, 6, 7}},
+		{"limitが要素数より多い", 10, []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, maxRunning atomic.Int32
+			results, errs := Map(context.Background(), tt.limit, tt.items, func(i int, item int) (int, error) {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				// 後の要素ほど早く終わるようにして、結果の順序が入力の順序になることを確認する
+				time.Sleep(time.Duration(len(tt.items)-i) * time.Millisecond)
+				if item%2 == 1 {
+					return 0, errOdd
+				}
+				return item * 10, nil
+			})
+
+			if len(results) != len(tt.items) || len(errs) != len(tt.items) {
+				t.Fatalf("len = %d, %d, want %d", len(results), len(errs), len(tt.items))
+			}
+			for i, item := range tt.items {
+				if item%2 == 1 {
+					if !errors.Is(errs[i], errOdd) {
+						t.Errorf("errs[%d] = %v, want %v", i, errs[i], errOdd)
+					}
+					continue
+				}
+				if errs[i] != nil || results[i] != item*10 {
+					t.Errorf("[%d] = %d, %v, want %d, nil", i, results[i], errs[i], item*10)
+				}
+			}
+
+			limit := int32(tt.limit)
+			if limit < 1 {
+				limit = 1
+			}
+			if got := maxRunning.Load(); got > limit {
+				t.Errorf("max concurrent calls = %d, want <= %d", got, limit)
+			}
+		})
+	}
+}
+
+func TestMapCanceled(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+	}{
+		{"順番に実行", 1},
+		{"並列に実行", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			items := []int{0, 1, 2, 3, 4, 5}
+			var called []int
+			calls := make(chan int, len(items))
+			_, errs := Map(ctx, tt.limit, items, func(i int, item int) (int, error) {
+				calls <- i
+				// 最初の呼び出しでキャンセルし、まだ開始していない要素は呼び出さない
+				cancel()
+				return item, nil
+			})
+			close(calls)
+			for i := range calls {
+				called = append(called, i)
+			}
+
+			if len(called) > tt.limit {
+				t.Errorf("called %v after cancel, want at most %d calls", called, tt.limit)
+			}
+			wasCalled := make(map[int]bool)
+			for _, i := range called {
+				wasCalled[i] = true
+			}
+			for i := range items {
+				if wasCalled[i] {
+					if errs[i] != nil {
+						t.Errorf("errs[%d] = %v, want nil", i, errs[i])
+					}
+				} else if !errors.Is(errs[i], context.Canceled) {
+					t.Errorf("errs[%d] = %v, want context.Canceled", i, errs[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Package polyline Google Encoded Polyline Algorithm Formatのエンコード/デコード
+// https://developers.google.com/maps/documentation/utilities/polylinealgorithm
+package polyline
+
+import (
+	"fmt"
+	"fukuoka-ai-api/models"
+	"math"
+	"strings"
+)
+
+// precision Google Maps APIが使用する精度（小数点以下5桁）
+const precision = 1e5
+
+// Encode 座標列をエンコード済みポリライン文字列に変換
+func Encode(points []models.Coordinate) string {
+	var sb strings.Builder
+	var prevLat, prevLng int64
+	for _, p := range points {
+		lat := int64(math.Round(p.Lat * precision))
+		lng := int64(math.Round(p.Lng * precision))
+		encodeValue(&sb, lat-prevLat)
+		encodeValue(&sb, lng-prevLng)
+		prevLat, prevLng = lat, lng
+	}
+	return sb.String()
+}
+
+// encodeValue 差分値を1つエンコードして書き込む
+func encodeValue(sb *strings.Builder, value int64) {
+	// 符号を最下位ビットに移し、負数の場合はビットを反転する
+	v := value << 1
+	if value < 0 {
+		v = ^v
+	}
+	// 5ビットずつ下位から出力し、続きがある場合は0x20を立てる
+	for v >= 0x20 {
+		sb.WriteByte(byte((0x20 | (v & 0x1f)) + 63))
+		v >>= 5
+	}
+	sb.WriteByte(byte(v + 63))
+}
+
+// Decode エンコード済みポリライン文字列を座標列に変換
+func Decode(encoded string) ([]models.Coordinate, error) {
+	points := make([]models.Coordinate, 0, len(encoded)/4)
+	var lat, lng int64
+	index := 0
+	for index < len(encoded) {
+		dLat, next, err := decodeValue(encoded, index)
+		if err != nil {
+			return nil, err
+		}
+		dLng, next, err := decodeValue(encoded, next)
+		if err != nil {
+			return nil, err
+		}
+		index = next
+
+		lat += dLat
+		lng += dLng
+		points = append(points, models.Coordinate{
+			Lat: float64(lat) / precision,
+			Lng: float64(lng) / precision,
+		})
+	}
+	return points, nil
+}
+
+// decodeValue indexの位置から差分値を1つデコードし、次の位置を返す
+func decodeValue(encoded string, index int) (int64, int, error) {
+	var result int64
+	var shift uint
+	for {
+		if index >= len(encoded) {
+			return 0, 0, fmt.Errorf("invalid polyline: unexpected end of string")
+		}
+		b := int64(encoded[index]) - 63
+		if b < 0 || b > 0x3f {
+			return 0, 0, fmt.Errorf("invalid polyline: unexpected character %q at %d", encoded[index], index)
+		}
+		index++
+		result |= (b & 0x1f) << shift
+		shift += 5
+		if b < 0x20 {
+			break
+		}
+		if shift > 60 {
+			return 0, 0, fmt.Errorf("invalid polyline: value too long at %d", index)
+		}
+	}
+
+	if result&1 != 0 {
+		return ^(result >> 1), index, nil
+	}
+	return result >> 1, index, nil
+}
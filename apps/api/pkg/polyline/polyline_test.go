@@ -0,0 +1,91 @@
+package polyline
+
+import (
+	"fukuoka-ai-api/models"
+	"reflect"
+	"testing"
+)
+
+// googleExample Encoded Polyline Algorithm Formatの説明にある例
+const googleExample = "_p~iF~ps|U_ulLnnqC_mqNvxq`@"
+
+var googleExamplePoints = []models.Coordinate{
+	{Lat: 38.5, Lng: -120.2},
+	{Lat: 40.7, Lng: -120.95},
+	{Lat: 43.252, Lng: -126.453},
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []models.Coordinate
+		want   string
+	}{
+		{"座標なし", nil, ""},
+		{"Googleの例", googleExamplePoints, googleExample},
+		{"小数点以下6桁目は四捨五入する", []models.Coordinate{{Lat: 38.500004, Lng: -120.200006}}, "_p~iF`qs|U"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Encode(tt.points); got != tt.want {
+				t.Errorf("Encode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		want    []models.Coordinate
+		wantErr bool
+	}{
+		{"空文字列", "", []models.Coordinate{}, false},
+		{"Googleの例", googleExample, googleExamplePoints, false},
+		{"値の途中で終わる", googleExample[:len(googleExample)-2], nil, true},
+		{"経度がない", "_p~iF", nil, true},
+		{"範囲外の文字", "_p~iF ps|U", nil, true},
+		{"値が長すぎる", "~~~~~~~~~~~~~~?", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.encoded)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Decode = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []models.Coordinate
+	}{
+		{"1地点", []models.Coordinate{{Lat: 33.58993, Lng: 130.42055}}},
+		{"福岡市内の経路", []models.Coordinate{{Lat: 33.58993, Lng: 130.42055}, {Lat: 33.59099, Lng: 130.39884}, {Lat: 33.58612, Lng: 130.37642}}},
+		{"負の座標と0", []models.Coordinate{{Lat: -33.86882, Lng: 151.20929}, {Lat: 0, Lng: 0}, {Lat: 51.50735, Lng: -0.12776}}},
+		{"同じ地点の繰り返し", []models.Coordinate{{Lat: 1, Lng: 2}, {Lat: 1, Lng: 2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(Encode(tt.points))
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.points) {
+				t.Errorf("Decode(Encode) = %v, want %v", got, tt.points)
+			}
+		})
+	}
+}
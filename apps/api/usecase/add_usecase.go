@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"fukuoka-ai-api/infra/repository"
+	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/models"
+)
+
+// IAddUsecase 場所追加機能のユースケースインターフェース
+type IAddUsecase interface {
+	AddPlace(ctx context.Context, tripID string, placeID string, userID string) (*models.TripPlace, error)
+}
+
+// AddUsecase 場所追加機能のユースケース実装
+type AddUsecase struct {
+	placeDetailsService service.IPlaceDetailsService
+	tripRepository      repository.ITripRepository
+}
+
+// NewAddUsecase 新しいAddUsecaseを作成
+func NewAddUsecase(
+	placeDetailsService service.IPlaceDetailsService,
+	tripRepository repository.ITripRepository,
+) IAddUsecase {
+	return &AddUsecase{
+		placeDetailsService: placeDetailsService,
+		tripRepository:      tripRepository,
+	}
+}
+
+// AddPlace リコメンドされた場所を旅程に追加して保存する
+// 旅程の所有者以外（userIDが一致しない場合）はErrForbiddenを返す
+func (u *AddUsecase) AddPlace(ctx context.Context, tripID string, placeID string, userID string) (*models.TripPlace, error) {
+	if _, err := authorizeTrip(u.tripRepository, tripID, userID); err != nil {
+		return nil, err
+	}
+
+	details, err := u.placeDetailsService.GetPlaceDetails(ctx, placeID, "", service.PlaceDetailsFields)
+	if err != nil {
+		return nil, fmt.Errorf("場所 (place_id: %s) の詳細取得に失敗しました: %w", placeID, err)
+	}
+
+	place := &models.TripPlace{
+		TripID:        tripID,
+		PlaceID:       placeID,
+		Name:          details.Name,
+		Lat:           details.Lat,
+		Lng:           details.Lng,
+		Kind:          models.TripPlaceKindRecommended,
+		StayMinutes:   models.DefaultStayMinutes,
+		ReviewSummary: details.ReviewSummary,
+		PhotoURL:      details.PhotoURL,
+	}
+	if err := u.tripRepository.AddTripPlace(place); err != nil {
+		return nil, fmt.Errorf("旅程の保存に失敗しました: %w", err)
+	}
+
+	return place, nil
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/usecase/solver"
+	"math"
+	"time"
+)
+
+// tripPath 旅程の地点を訪問する順に並べる
+// pointsは [出発地点, 寄りたい場所..., ゴール地点] の順で、出発地点とゴール地点を固定して直線距離で最短になる順にする
+func tripPath(points []models.Coordinate) []models.Coordinate {
+	if len(points) < 3 {
+		return points
+	}
+	cost := make([][]float64, len(points))
+	for i, from := range points {
+		cost[i] = make([]float64, len(points))
+		for j, to := range points {
+			if i != j {
+				cost[i][j] = haversineDistance(from.Lat, from.Lng, to.Lat, to.Lng)
+			}
+		}
+	}
+	order, _, err := solver.SolvePath(cost, 0, len(points)-1)
+	if err != nil {
+		return points
+	}
+	path := make([]models.Coordinate, len(order))
+	for i, index := range order {
+		path[i] = points[index]
+	}
+	return path
+}
+
+// haversineDetour pathの区間のいずれかに(lat, lng)を立ち寄らせた場合に増える直線距離の最小値
+// 所要時間は移動手段の平均速度から見積もる
+func haversineDetour(path []models.Coordinate, lat, lng float64, travelMode string) models.Detour {
+	best := math.Inf(1)
+	switch len(path) {
+	case 0:
+		best = 0
+	case 1:
+		// 区間がない場合は往復する
+		best = 2 * haversineDistance(path[0].Lat, path[0].Lng, lat, lng)
+	}
+	for i := 0; i+1 < len(path); i++ {
+		a, b := path[i], path[i+1]
+		extra := haversineDistance(a.Lat, a.Lng, lat, lng) +
+			haversineDistance(lat, lng, b.Lat, b.Lng) -
+			haversineDistance(a.Lat, a.Lng, b.Lat, b.Lng)
+		best = math.Min(best, extra)
+	}
+	best = math.Max(best, 0)
+	return models.Detour{
+		DistanceMeters:  best,
+		DurationSeconds: best / estimatedSpeed(travelMode),
+		Source:          models.DetourSourceHaversine,
+	}
+}
+
+// roadDetours ルート行列（travelMode、departureTimeの交通状況）でpathの区間のいずれかに各候補を立ち寄らせた場合に増える道路の距離・所要時間を求める
+// 増える距離が最も小さい区間の値を返し、ルートが見つからない候補はnil
+// ルート行列は要素ごとに課金されるため、区間の始点→候補・候補→区間の終点・区間の始点→終点の要素だけを求める
+// 要素数は 区間数 × (2 × 候補数 + 1) になる
+func (u *RecommendUsecase) roadDetours(ctx context.Context, path []models.Coordinate, candidates []service.PlaceResult, travelMode string, departureTime *time.Time) ([]*models.Detour, error) {
+	detours := make([]*models.Detour, len(candidates))
+	if len(path) < 2 || len(candidates) == 0 {
+		return detours, nil
+	}
+
+	// waypointsは [pathの地点..., 候補...] の順
+	waypoints := make([]service.Waypoint, 0, len(path)+len(candidates))
+	for _, point := range path {
+		waypoints = append(waypoints, service.Waypoint{Lat: point.Lat, Lng: point.Lng})
+	}
+	for _, candidate := range candidates {
+		waypoints = append(waypoints, service.Waypoint{Lat: candidate.Lat, Lng: candidate.Lng})
+	}
+	var segmentStarts, segmentEnds, candidateIndexes []int
+	for i := 0; i+1 < len(path); i++ {
+		segmentStarts = append(segmentStarts, i)
+		segmentEnds = append(segmentEnds, i+1)
+	}
+	for k := range candidates {
+		candidateIndexes = append(candidateIndexes, len(path)+k)
+	}
+	blocks := []service.RouteMatrixBlock{
+		{Origins: segmentStarts, Destinations: candidateIndexes},
+		{Origins: candidateIndexes, Destinations: segmentEnds},
+	}
+	for i := 0; i+1 < len(path); i++ {
+		blocks = append(blocks, service.RouteMatrixBlock{Origins: []int{i}, Destinations: []int{i + 1}})
+	}
+	matrix, err := u.routeMatrixService.ComputeRouteMatrixBlocks(ctx, waypoints, blocks, travelMode, departureTime)
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range candidates {
+		c := len(path) + k
+		for i := 0; i+1 < len(path); i++ {
+			toCandidate, fromCandidate, direct := matrix.Elements[i][c], matrix.Elements[c][i+1], matrix.Elements[i][i+1]
+			if !toCandidate.Found || !fromCandidate.Found || !direct.Found {
+				continue
+			}
+			distance := float64(toCandidate.DistanceMeters + fromCandidate.DistanceMeters - direct.DistanceMeters)
+			if detours[k] != nil && distance >= detours[k].DistanceMeters {
+				continue
+			}
+			duration := toCandidate.Duration + fromCandidate.Duration - direct.Duration
+			detours[k] = &models.Detour{
+				DistanceMeters:  math.Max(distance, 0),
+				DurationSeconds: math.Max(duration.Seconds(), 0),
+				Source:          models.DetourSourceRoad,
+			}
+		}
+	}
+	return detours, nil
+}
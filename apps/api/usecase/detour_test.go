@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/models"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTripPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []models.Coordinate
+		want   []models.Coordinate
+	}{
+		{"地点なし", nil, nil},
+		{"出発地点とゴール地点のみ", []models.Coordinate{{Lng: 0}, {Lng: 4}}, []models.Coordinate{{Lng: 0}, {Lng: 4}}},
+		{
+			"寄りたい場所を直線距離で最短になる順に並べる",
+			[]models.Coordinate{{Lng: 0}, {Lng: 0.03}, {Lng: 0.01}, {Lng: 0.02}, {Lng: 0.04}},
+			[]models.Coordinate{{Lng: 0}, {Lng: 0.01}, {Lng: 0.02}, {Lng: 0.03}, {Lng: 0.04}},
+		},
+		{
+			"出発地点とゴール地点は固定する",
+			[]models.Coordinate{{Lng: 0.02}, {Lng: 0}, {Lng: 0.01}},
+			[]models.Coordinate{{Lng: 0.02}, {Lng: 0}, {Lng: 0.01}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tripPath(tt.points); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tripPath = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHaversineDetour(t *testing.T) {
+	a := models.Coordinate{Lat: 33.59, Lng: 130.40}
+	b := models.Coordinate{Lat: 33.59, Lng: 130.42}
+	off := models.Coordinate{Lat: 33.60, Lng: 130.41}
+	extra := haversineDistance(a.Lat, a.Lng, off.Lat, off.Lng) + haversineDistance(off.Lat, off.Lng, b.Lat, b.Lng) - haversineDistance(a.Lat, a.Lng, b.Lat, b.Lng)
+	roundTrip := 2 * haversineDistance(a.Lat, a.Lng, off.Lat, off.Lng)
+
+	tests := []struct {
+		name         string
+		path         []models.Coordinate
+		point        models.Coordinate
+		travelMode   string
+		wantDistance float64
+	}{
+		{"地点なし", nil, off, service.TravelModeDrive, 0},
+		{"区間がない場合は往復する", []models.Coordinate{a}, off, service.TravelModeDrive, roundTrip},
+		{"区間上の地点は寄り道なし", []models.Coordinate{a, b}, models.Coordinate{Lat: 33.59, Lng: 130.41}, service.TravelModeDrive, 0},
+		{"区間から外れた地点", []models.Coordinate{a, b}, off, service.TravelModeDrive, extra},
+		{"最も近い区間の値を使う", []models.Coordinate{{Lat: 33.50, Lng: 130.30}, a, b}, off, service.TravelModeDrive, extra},
+		{"徒歩は徒歩の速度で所要時間を見積もる", []models.Coordinate{a, b}, off, service.TravelModeWalk, extra},
+		{"自転車は自転車の速度で所要時間を見積もる", []models.Coordinate{a, b}, off, service.TravelModeBicycle, extra},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := haversineDetour(tt.path, tt.point.Lat, tt.point.Lng, tt.travelMode)
+			if math.Abs(got.DistanceMeters-tt.wantDistance) > 1e-3 {
+				t.Errorf("DistanceMeters = %v, want %v", got.DistanceMeters, tt.wantDistance)
+			}
+			wantDuration := tt.wantDistance / estimatedSpeed(tt.travelMode)
+			if math.Abs(got.DurationSeconds-wantDuration) > 1e-3 {
+				t.Errorf("DurationSeconds = %v, want %v", got.DurationSeconds, wantDuration)
+			}
+			if got.Source != models.DetourSourceHaversine {
+				t.Errorf("Source = %s, want %s", got.Source, models.DetourSourceHaversine)
+			}
+		})
+	}
+}
+
+// fakeRouteMatrixService 地点間の距離を |緯度の差| + |経度の差|（×1000メートル）、所要時間を距離と同じ秒数とするルート行列
+// 経度が5の地点へのルートは見つからないものとする
+// 呼び出し時の移動手段・出発時刻・要素数を記録する
+type fakeRouteMatrixService struct {
+	err           error
+	travelMode    string
+	departureTime *time.Time
+	elements      int
+}
+
+func (f *fakeRouteMatrixService) ComputeRouteMatrix(ctx context.Context, waypoints []service.Waypoint, travelMode string, departureTime *time.Time) (*service.RouteMatrix, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeRouteMatrixService) ComputeRouteMatrixBlocks(ctx context.Context, waypoints []service.Waypoint, blocks []service.RouteMatrixBlock, travelMode string, departureTime *time.Time) (*service.RouteMatrix, error) {
+	f.travelMode, f.departureTime = travelMode, departureTime
+	if f.err != nil {
+		return nil, f.err
+	}
+	matrix := &service.RouteMatrix{Elements: make([][]service.RouteMatrixElement, len(waypoints))}
+	for i := range matrix.Elements {
+		matrix.Elements[i] = make([]service.RouteMatrixElement, len(waypoints))
+	}
+	for _, block := range blocks {
+		for _, i := range block.Origins {
+			for _, j := range block.Destinations {
+				f.elements++
+				from, to := waypoints[i], waypoints[j]
+				if to.Lng == 5 {
+					continue
+				}
+				distance := int((math.Abs(from.Lat-to.Lat) + math.Abs(from.Lng-to.Lng)) * 1000)
+				matrix.Elements[i][j] = service.RouteMatrixElement{
+					DistanceMeters: distance,
+					Duration:       time.Duration(distance) * time.Second,
+					Found:          true,
+				}
+			}
+		}
+	}
+	return matrix, nil
+}
+
+func TestRoadDetours(t *testing.T) {
+	departure := time.Date(2025, 4, 5, 10, 0, 0, 0, time.UTC)
+	road := func(meters float64) *models.Detour {
+		return &models.Detour{DistanceMeters: meters, DurationSeconds: meters, Source: models.DetourSourceRoad}
+	}
+
+	tests := []struct {
+		name         string
+		path         []models.Coordinate
+		candidates   []service.PlaceResult
+		travelMode   string
+		want         []*models.Detour
+		wantElements int
+	}{
+		{
+			name:       "区間が1つ",
+			path:       []models.Coordinate{{Lat: 0}, {Lat: 2}},
+			candidates: []service.PlaceResult{{Lat: 1, Lng: 1}},
+			travelMode: service.TravelModeDrive,
+			want:       []*models.Detour{road(2000)},
+			// 区間数1 × (2 × 候補数1 + 1)
+			wantElements: 3,
+		},
+		{
+			name:         "増える距離が最も小さい区間の値を使う",
+			path:         []models.Coordinate{{Lat: 0}, {Lat: 2}, {Lat: 4}},
+			candidates:   []service.PlaceResult{{Lat: 3, Lng: 0.5}, {Lat: 1}},
+			travelMode:   service.TravelModeWalk,
+			want:         []*models.Detour{road(1000), road(0)},
+			wantElements: 10,
+		},
+		{
+			name:         "ルートが見つからない候補はnil",
+			path:         []models.Coordinate{{Lat: 0}, {Lat: 2}},
+			candidates:   []service.PlaceResult{{Lat: 1, Lng: 5}, {Lat: 1, Lng: 1}},
+			travelMode:   service.TravelModeTransit,
+			want:         []*models.Detour{nil, road(2000)},
+			wantElements: 5,
+		},
+		{
+			name:       "区間がない場合はルート行列を使わない",
+			path:       []models.Coordinate{{Lat: 0}},
+			candidates: []service.PlaceResult{{Lat: 1, Lng: 1}},
+			travelMode: service.TravelModeDrive,
+			want:       []*models.Detour{nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeRouteMatrixService{}
+			u := &RecommendUsecase{routeMatrixService: fake}
+			got, err := u.roadDetours(context.Background(), tt.path, tt.candidates, tt.travelMode, &departure)
+			if err != nil {
+				t.Fatalf("roadDetours: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("roadDetours = %v, want %v", got, tt.want)
+			}
+			if fake.elements != tt.wantElements {
+				t.Errorf("elements = %d, want %d", fake.elements, tt.wantElements)
+			}
+			if tt.wantElements > 0 && (fake.travelMode != tt.travelMode || fake.departureTime != &departure) {
+				t.Errorf("travelMode, departureTime = %s, %v, want %s, %v", fake.travelMode, fake.departureTime, tt.travelMode, departure)
+			}
+		})
+	}
+
+	// ルート行列の取得に失敗した場合はエラーを返す
+	errMatrix := errors.New("matrix failed")
+	u := &RecommendUsecase{routeMatrixService: &fakeRouteMatrixService{err: errMatrix}}
+	if _, err := u.roadDetours(context.Background(), []models.Coordinate{{Lat: 0}, {Lat: 2}}, []service.PlaceResult{{Lat: 1}}, service.TravelModeDrive, nil); !errors.Is(err, errMatrix) {
+		t.Errorf("roadDetours error = %v, want %v", err, errMatrix)
+	}
+}
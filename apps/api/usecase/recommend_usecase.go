@@ -1,76 +1,239 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"fukuoka-ai-api/infra/repository"
 	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/infra/tags"
+	"fukuoka-ai-api/infra/usage"
 	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/pkg/apperror"
+	"fukuoka-ai-api/pkg/parallel"
+	"fukuoka-ai-api/usecase/rerank"
+	"fukuoka-ai-api/usecase/scoring"
+	"log/slog"
 	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+// maxFreeTextLength free_textの最大文字数
+const maxFreeTextLength = 500
+
 // IRecommendUsecase リコメンド機能のユースケースインターフェース
 type IRecommendUsecase interface {
-	Recommend(req *models.RecommendRequest) (*models.RecommendResponse, error)
+	Recommend(ctx context.Context, req *models.RecommendRequest) (*models.RecommendResponse, error)
 }
 
 // RecommendUsecase リコメンド機能のユースケース実装
 type RecommendUsecase struct {
-	geocodingService    service.IGeocodingService
-	nearbySearchService service.INearbySearchService
-	placeDetailsService service.IPlaceDetailsService
+	geocodingService     service.IGeocodingService
+	nearbySearchService  service.INearbySearchService
+	placeDetailsService  service.IPlaceDetailsService
+	tripRepository       repository.ITripRepository
+	concurrency          int // 外部API呼び出しの同時実行数
+	pagination           service.NearbyPaginationConfig
+	meter                *usage.Meter
+	tagRegistry          *tags.Registry
+	interestExtractor    service.IInterestExtractor
+	scorer               scoring.Scorer
+	reranker             rerank.IReranker
+	routeMatrixService   service.IRouteMatrixService
+	detourRoadCandidates int // 寄り道の距離を道路の距離で求め直す候補数（0の場合は直線距離のみ）
+}
+
+// RecommendDeps RecommendUsecaseの依存関係と設定
+type RecommendDeps struct {
+	GeocodingService    service.IGeocodingService
+	NearbySearchService service.INearbySearchService
+	PlaceDetailsService service.IPlaceDetailsService
+	TripRepository      repository.ITripRepository
+	Concurrency         int                            // ジオコーディング・周辺検索・詳細取得それぞれの同時実行数（1以下の場合は順番に実行）
+	Pagination          service.NearbyPaginationConfig // 周辺検索で取得するページ数の既定値・上限
+	Meter               *usage.Meter                   // クライアントに返す写真URLの数（Place Photoの呼び出し回数）の記録に使用する
+	TagRegistry         *tags.Registry                 // 興味タグと推定した興味タグの重複の判定に使う
+	InterestExtractor   service.IInterestExtractor     // free_textから興味タグ・追加の検索キーワードを推定する
+	Scorer              scoring.Scorer                 // 候補の関連性スコアを計算する
+	Reranker            rerank.IReranker               // スコアの順に並べた候補から、興味タグ・区間が偏らないように表示する候補を選ぶ
+	// RouteMatrixServiceは上位DetourRoadCandidates件の候補の寄り道の距離を道路の距離で求めるのに使う（nilまたは0の場合は直線距離のみ）
+	RouteMatrixService   service.IRouteMatrixService
+	DetourRoadCandidates int
 }
 
 // NewRecommendUsecase 新しいRecommendUsecaseを作成
-func NewRecommendUsecase(
-	geocodingService service.IGeocodingService,
-	nearbySearchService service.INearbySearchService,
-	placeDetailsService service.IPlaceDetailsService,
-) IRecommendUsecase {
+func NewRecommendUsecase(deps RecommendDeps) IRecommendUsecase {
 	return &RecommendUsecase{
-		geocodingService:    geocodingService,
-		nearbySearchService: nearbySearchService,
-		placeDetailsService: placeDetailsService,
+		geocodingService:     deps.GeocodingService,
+		nearbySearchService:  deps.NearbySearchService,
+		placeDetailsService:  deps.PlaceDetailsService,
+		tripRepository:       deps.TripRepository,
+		concurrency:          deps.Concurrency,
+		pagination:           deps.Pagination,
+		meter:                deps.Meter,
+		tagRegistry:          deps.TagRegistry,
+		interestExtractor:    deps.InterestExtractor,
+		scorer:               deps.Scorer,
+		reranker:             deps.Reranker,
+		routeMatrixService:   deps.RouteMatrixService,
+		detourRoadCandidates: deps.DetourRoadCandidates,
 	}
 }
 
+// geocodeResult ジオコーディングの結果
+type geocodeResult struct {
+	Lat     float64
+	Lng     float64
+	PlaceID string
+}
+
 // Recommend リコメンド機能のメイン処理
-func (u *RecommendUsecase) Recommend(req *models.RecommendRequest) (*models.RecommendResponse, error) {
+// 各段階の並列処理の後にcontextを確認し、キャンセルされた場合は旅程を保存せず以降の外部API呼び出しも行わない
+// 一部の寄りたい場所・区間・候補の取得に失敗した場合は、レスポンスとともにその失敗（apperror.ErrPartialResult）を返す
+func (u *RecommendUsecase) Recommend(ctx context.Context, req *models.RecommendRequest) (*models.RecommendResponse, error) {
+	// 周辺検索で興味タグごとに取得するページ数（多いほど候補が増えるが、APIの呼び出し回数も増える）
+	searchPages := req.SearchPages
+	if searchPages == 0 {
+		searchPages = u.pagination.DefaultPages
+	}
+	if searchPages < 1 || searchPages > u.pagination.MaxPages {
+		return nil, apperror.New(apperror.ErrInvalidInput, "search_pagesは1から%dの範囲で指定してください: %d", u.pagination.MaxPages, req.SearchPages).WithDetails("search_pages")
+	}
+	if utf8.RuneCountInString(req.FreeText) > maxFreeTextLength {
+		return nil, apperror.New(apperror.ErrInvalidInput, "free_textは%d文字以内で指定してください", maxFreeTextLength).WithDetails("free_text")
+	}
+
+	travelMode, err := service.NormalizeTravelMode(req.TravelMode)
+	if err != nil {
+		return nil, apperror.New(apperror.ErrInvalidInput, "移動手段が不正です: %s", req.TravelMode).WithDetails("travel_mode")
+	}
+	startTime, date := req.StartTime, req.Date
+
+	// 既存の旅程に追加する場合は、外部APIを呼び出す前に所有者を確認する
+	// 開始時刻・旅行日が指定されていない場合は旅程の値を使う
+	if req.TripID != "" {
+		trip, err := authorizeTrip(u.tripRepository, req.TripID, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if startTime == "" {
+			startTime = trip.StartTime
+		}
+		if date == "" {
+			date = trip.Date
+		}
+	}
+	if startTime == "" {
+		startTime = models.DefaultTripStartTime
+	}
+	startSeconds, err := parseClock(startTime)
+	if err != nil {
+		return nil, err
+	}
+	tripDate, err := parseTripDate(date)
+	if err != nil {
+		return nil, err
+	}
+	// 寄り道の所要時間は旅行日・開始時刻の交通状況で求める
+	departureTime := tripDepartureTime(tripDate, startSeconds, time.Now())
+
+	// 自由記述から興味タグ・追加の検索キーワードを推定し、興味タグとあわせて周辺検索とスコアに使う
+	var extracted *models.ExtractedInterests
+	if strings.TrimSpace(req.FreeText) != "" {
+		extracted, err = u.interestExtractor.Extract(ctx, req.FreeText)
+		if err != nil {
+			return nil, fmt.Errorf("自由記述の解釈に失敗しました: %w", err)
+		}
+	}
+	searchTerms := buildSearchTerms(req.InterestTags, extracted, u.tagRegistry)
+	searchTags := make([]string, len(searchTerms))
+	for i, term := range searchTerms {
+		searchTags[i] = term.ID
+	}
+
 	// 処理フロー1: 出発地点とゴール地点を指定したのち、それ以外の必ず寄りたい場所の座標を得る
 	startPlace := req.StartPlace
 	if startPlace == "" {
 		startPlace = "Hakata Station"
 	}
 
-	// 出発地点の座標を取得
-	startLat, startLng, _, err := u.geocodingService.GetCoordinates(startPlace)
-	if err != nil {
-		return nil, fmt.Errorf("出発地点の座標取得に失敗しました: %w", err)
+	// 出発地点・ゴール地点（指定されている場合）・寄りたい場所の座標を並列に取得する
+	// 結果は [出発地点, 寄りたい場所..., ゴール地点] の順に並ぶ
+	placeNames := append([]string{startPlace}, req.MustPlaces...)
+	if req.GoalPlace != "" {
+		placeNames = append(placeNames, req.GoalPlace)
+	}
+	geocoded, geocodeErrs := parallel.Map(ctx, u.concurrency, placeNames, func(_ int, name string) (geocodeResult, error) {
+		lat, lng, placeID, err := u.geocodingService.GetCoordinates(ctx, name)
+		return geocodeResult{Lat: lat, Lng: lng, PlaceID: placeID}, err
+	})
+	// 見つからない寄りたい場所はスキップするため、キャンセルによる失敗と区別する
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("座標の取得を中断しました: %w", err)
 	}
 
-	// ゴール地点の座標を取得（指定されている場合）
+	// 出発地点の座標
+	if geocodeErrs[0] != nil {
+		return nil, geocodeError("出発地点", "start_place", geocodeErrs[0])
+	}
+	startLat, startLng, startPlaceID := geocoded[0].Lat, geocoded[0].Lng, geocoded[0].PlaceID
+
+	// ゴール地点の座標（指定されている場合）
 	var goalLat, goalLng float64
+	var goalPlaceID string
 	if req.GoalPlace != "" {
-		goalLat, goalLng, _, err = u.geocodingService.GetCoordinates(req.GoalPlace)
-		if err != nil {
-			return nil, fmt.Errorf("ゴール地点の座標取得に失敗しました: %w", err)
+		last := len(placeNames) - 1
+		if geocodeErrs[last] != nil {
+			return nil, geocodeError("ゴール地点", "goal_place", geocodeErrs[last])
 		}
+		goalLat, goalLng, goalPlaceID = geocoded[last].Lat, geocoded[last].Lng, geocoded[last].PlaceID
 	} else {
 		// ゴール地点が指定されていない場合は出発地点と同じにする
 		goalLat, goalLng = startLat, startLng
 	}
 
-	// 寄りたい場所の座標を取得
+	// 一部の処理に失敗しても結果を返す場合の失敗（レスポンスとともに返す）
+	var partials []error
+
+	// 寄りたい場所の座標（指定順）
 	var mustPlaceCoords []models.Coordinate
-	for _, placeName := range req.MustPlaces {
-		lat, lng, _, err := u.geocodingService.GetCoordinates(placeName)
-		if err != nil {
+	var mustPlaceIDs []string
+	for i, placeName := range req.MustPlaces {
+		if geocodeErrs[i+1] != nil {
 			// 見つからない場所はスキップ
+			partials = append(partials, apperror.Partial("MUST_PLACE_SKIPPED", geocodeErrs[i+1], "寄りたい場所「%s」の座標を取得できなかったため除外しました", placeName))
 			continue
 		}
+		lat, lng, placeID := geocoded[i+1].Lat, geocoded[i+1].Lng, geocoded[i+1].PlaceID
 		mustPlaceCoords = append(mustPlaceCoords, models.Coordinate{
 			Lat:  lat,
 			Lng:  lng,
 			Name: placeName,
 		})
+		mustPlaceIDs = append(mustPlaceIDs, placeID)
+	}
+
+	// 旅程を保存（出発地点、寄りたい場所、ゴール地点）
+	var tripPlaces []models.TripPlace
+	tripPlaces = append(tripPlaces, models.TripPlace{
+		PlaceID: startPlaceID, Name: startPlace, Lat: startLat, Lng: startLng, Kind: models.TripPlaceKindStart,
+	})
+	for i, coord := range mustPlaceCoords {
+		tripPlaces = append(tripPlaces, models.TripPlace{
+			PlaceID: mustPlaceIDs[i], Name: coord.Name, Lat: coord.Lat, Lng: coord.Lng, Kind: models.TripPlaceKindMust,
+			StayMinutes: models.DefaultStayMinutes,
+		})
+	}
+	if req.GoalPlace != "" && goalPlaceID != startPlaceID {
+		tripPlaces = append(tripPlaces, models.TripPlace{
+			PlaceID: goalPlaceID, Name: req.GoalPlace, Lat: goalLat, Lng: goalLng, Kind: models.TripPlaceKindGoal,
+		})
+	}
+	tripID, err := u.saveTrip(req, tripPlaces)
+	if err != nil {
+		return nil, err
 	}
 
 	// 全ての座標をまとめる（出発地点、ゴール地点、寄りたい場所）
@@ -87,10 +250,8 @@ func (u *RecommendUsecase) Recommend(req *models.RecommendRequest) (*models.Reco
 	edges := buildMinimumSpanningTree(allCoordinates)
 
 	// 処理フロー3: 全ての枝で、半径が 枝の長さ/√3 となる円内で、興味タグで検索をnearby search APIで検索する
-	var allCandidates []service.PlaceResult
-	seenPlaceIDs := make(map[string]bool)
-
-	for _, edge := range edges {
+	// 検索は枝ごとに並列に行い、結果は枝の順にマージする
+	resultsByEdge, searchErrs := parallel.Map(ctx, u.concurrency, edges, func(_ int, edge models.Edge) ([]service.PlaceResult, error) {
 		// エッジの中点を計算
 		midLat := (edge.From.Lat + edge.To.Lat) / 2
 		midLng := (edge.From.Lng + edge.To.Lng) / 2
@@ -98,9 +259,26 @@ func (u *RecommendUsecase) Recommend(req *models.RecommendRequest) (*models.Reco
 		// 検索半径を計算（枝の長さ/√3）
 		searchRadius := calculateSearchRadius(edge.Distance)
 
-		// 周辺検索を実行
-		results, err := u.nearbySearchService.SearchNearby(midLat, midLng, searchRadius, req.InterestTags)
-		if err != nil {
+		return u.nearbySearchService.SearchNearby(ctx, midLat, midLng, searchRadius, searchTags, searchPages)
+	})
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("周辺検索を中断しました: %w", err)
+	}
+	// 一部の枝の失敗は無視するが、全ての枝で失敗した場合は外部APIの障害として扱う
+	if len(edges) > 0 && countErrors(searchErrs) == len(edges) {
+		return nil, fmt.Errorf("周辺検索に失敗しました: %w", searchErrs[0])
+	}
+	if failed := countErrors(searchErrs); failed > 0 {
+		partials = append(partials, apperror.Partial("NEARBY_SEARCH_PARTIAL", firstError(searchErrs), "%d/%d件の区間で周辺検索に失敗しました", failed, len(edges)))
+	}
+
+	var allCandidates []service.PlaceResult
+	seenPlaceIDs := make(map[string]bool)
+	candidateEdges := make(map[string]int) // place_id → 候補が見つかった枝のインデックス
+
+	for i, results := range resultsByEdge {
+		if searchErrs[i] != nil {
 			// エラーが発生しても次のエッジで続行
 			continue
 		}
@@ -109,60 +287,85 @@ func (u *RecommendUsecase) Recommend(req *models.RecommendRequest) (*models.Reco
 		for _, result := range results {
 			if !seenPlaceIDs[result.PlaceID] {
 				seenPlaceIDs[result.PlaceID] = true
+				candidateEdges[result.PlaceID] = i
 				allCandidates = append(allCandidates, result)
 			}
 		}
 	}
 
 	// 処理フロー4: その結果を一枚の写真と共にレビューの高い順に合計10件表示する
-	// まず、関連性が低い結果をフィルタリング
+	// まず、関連性が低い結果をフィルタリング（寄り道の距離を含めないスコアで判定する）
 	var filteredCandidates []service.PlaceResult
 	for _, candidate := range allCandidates {
-		score := calculateRelevanceScore(candidate, req.InterestTags)
-		// 関連性スコアが5.0未満の結果は除外（タグと全く関係ない可能性が高い）
-		if score >= 5.0 {
+		// 関連性スコアが下限未満の結果は除外（タグと全く関係ない可能性が高い）
+		if u.scorer.Score(scoring.Candidate{PlaceResult: candidate}, searchTerms) >= u.scorer.MinScore() {
 			filteredCandidates = append(filteredCandidates, candidate)
 		}
 	}
-	
-	// タグとの関連性を考慮してソート
-	sort.Slice(filteredCandidates, func(i, j int) bool {
-		scoreI := calculateRelevanceScore(filteredCandidates[i], req.InterestTags)
-		scoreJ := calculateRelevanceScore(filteredCandidates[j], req.InterestTags)
-		
-		// 関連性スコアが同じ場合は評価で比較
-		if scoreI == scoreJ {
-			return filteredCandidates[i].Rating > filteredCandidates[j].Rating
+
+	// 旅程の地点の間に立ち寄る場合の寄り道の距離（直線距離、所要時間は移動手段の平均速度から見積もる）をスコアに含める
+	path := tripPath(allCoordinates)
+	detours := make(map[string]models.Detour)
+	for _, candidate := range filteredCandidates {
+		detours[candidate.PlaceID] = haversineDetour(path, candidate.Lat, candidate.Lng, travelMode)
+	}
+	candidateScores := u.scoreCandidates(filteredCandidates, searchTerms, detours)
+	sortByScore(filteredCandidates, candidateScores)
+
+	// ルート行列が使える場合は、上位の候補（多様性を考慮して選ぶ）の寄り道の距離を道路の距離で求め直し、その中から選ぶ
+	// 取得できない場合（予算の上限に近い場合を含む）は直線距離のまま選ぶ
+	if u.routeMatrixService != nil && u.detourRoadCandidates > 0 && len(filteredCandidates) > 0 {
+		var shortlist []service.PlaceResult
+		for _, i := range u.reranker.Rerank(rerankItems(filteredCandidates, candidateScores, candidateEdges), req.InterestTags, u.detourRoadCandidates) {
+			shortlist = append(shortlist, filteredCandidates[i])
 		}
-		
-		// 関連性スコアが高いものを優先
-		return scoreI > scoreJ
-	})
-	
-	// フィルタリング後の候補を使用
-	allCandidates = filteredCandidates
+		roadDetours, err := u.roadDetours(ctx, path, shortlist, travelMode, &departureTime)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, fmt.Errorf("寄り道の距離の計算を中断しました: %w", ctxErr)
+			}
+			slog.WarnContext(ctx, "ルート行列の取得に失敗したため直線距離で寄り道の距離を求めます", "error", err)
+			partials = append(partials, apperror.Partial("DETOUR_MATRIX_FALLBACK", err, "ルート行列を取得できなかったため寄り道の距離を直線距離で求めました"))
+		} else {
+			for k, detour := range roadDetours {
+				if detour != nil {
+					detours[shortlist[k].PlaceID] = *detour
+				}
+			}
+			filteredCandidates = shortlist
+			candidateScores = u.scoreCandidates(filteredCandidates, searchTerms, detours)
+			sortByScore(filteredCandidates, candidateScores)
+		}
+	}
 
-	// 最大4件に制限
+	// 関連性と多様性のバランスを取って最大4件を選ぶ
+	// 指定された興味タグはそれぞれ少なくとも1件含め（候補がある場合）、同じ興味タグ・同じ枝の候補ばかりにならないようにする
 	maxResults := 4
-	if len(allCandidates) > maxResults {
-		allCandidates = allCandidates[:maxResults]
+	allCandidates = nil
+	for _, i := range u.reranker.Rerank(rerankItems(filteredCandidates, candidateScores, candidateEdges), req.InterestTags, maxResults) {
+		allCandidates = append(allCandidates, filteredCandidates[i])
 	}
 
-	// 各候補のスコアを計算してマップに保存
-	candidateScores := make(map[string]float64)
-	for _, candidate := range allCandidates {
-		score := calculateRelevanceScore(candidate, req.InterestTags)
-		candidateScores[candidate.PlaceID] = score
+	// Place Details APIで詳細情報を並列に取得する（結果は候補の順）
+	detailsList, detailsErrs := parallel.Map(ctx, u.concurrency, allCandidates, func(_ int, candidate service.PlaceResult) (*service.PlaceDetails, error) {
+		return u.placeDetailsService.GetPlaceDetails(ctx, candidate.PlaceID, candidate.PhotoReference, service.PlaceDetailsFields)
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("詳細情報の取得を中断しました: %w", err)
+	}
+
+	if failed := countErrors(detailsErrs); failed > 0 {
+		partials = append(partials, apperror.Partial("PLACE_DETAILS_PARTIAL", firstError(detailsErrs), "%d/%d件の候補で詳細情報を取得できませんでした", failed, len(allCandidates)))
 	}
 
-	// Place Details APIで詳細情報を取得
 	var places []models.Place
-	for _, candidate := range allCandidates {
-		// スコアを取得
+	for i, candidate := range allCandidates {
+		// スコアと寄り道の距離を取得
 		score := candidateScores[candidate.PlaceID]
+		detour := detours[candidate.PlaceID]
 
-		details, err := u.placeDetailsService.GetPlaceDetails(candidate.PlaceID, candidate.PhotoReference)
-		if err != nil {
+		details := detailsList[i]
+		if detailsErrs[i] != nil {
 			// 詳細取得に失敗した場合は基本情報のみを使用
 			places = append(places, models.Place{
 				PlaceID:        candidate.PlaceID,
@@ -172,88 +375,176 @@ func (u *RecommendUsecase) Recommend(req *models.RecommendRequest) (*models.Reco
 				Rating:         candidate.Rating,
 				PhotoURL:       "", // 写真URLは取得できない
 				RelevanceScore: score,
+				Detour:         &detour,
 			})
 			continue
 		}
 
+		// 写真URLはクライアントが読み込むたびにPlace Photoとして課金されるため、返す数を記録する
+		// 予算の上限に近い場合は写真URLを返さない
+		photoURL := details.PhotoURL
+		if photoURL != "" {
+			if err := u.meter.Use(ctx, usage.SKUPlacePhoto, 1); err != nil {
+				photoURL = ""
+			}
+		}
+
 		places = append(places, models.Place{
 			PlaceID:        details.PlaceID,
 			Name:           details.Name,
 			Lat:            details.Lat,
 			Lng:            details.Lng,
-			PhotoURL:       details.PhotoURL,
+			PhotoURL:       photoURL,
 			Rating:         details.Rating,
 			ReviewSummary:  details.ReviewSummary,
 			Category:       details.Category,
 			Address:        details.Address,
 			RelevanceScore: score,
+			Detour:         &detour,
 		})
 	}
 
-	// 理論的最大スコアを計算
-	// スコア計算式: MatchedTags数×10.0 + Typesマッチング（各タグ最大20.0）+ Rating×0.5（最大2.5）
-	// 理論的最大値 = タグ数 × (10.0 + 20.0) + 2.5 = タグ数 × 30.0 + 2.5
-	tagCount := float64(len(req.InterestTags))
-	maxPossibleScore := tagCount*30.0 + 2.5
+	// 理論的最大スコア（スコアの計算方法ごとに異なる）
+	maxPossibleScore := u.scorer.MaxScore(searchTerms)
 
 	return &models.RecommendResponse{
-		Places:           places,
-		MaxPossibleScore: maxPossibleScore,
-	}, nil
+		Places:             places,
+		MaxPossibleScore:   maxPossibleScore,
+		TripID:             tripID,
+		ExtractedInterests: extracted,
+	}, errors.Join(partials...)
 }
 
-// calculateRelevanceScore 検索結果と検索タグの関連性スコアを計算
-// スコアが高いほど検索タグと関連性が高い
-func calculateRelevanceScore(candidate service.PlaceResult, interestTags []string) float64 {
-	score := 0.0
-	
-	// 1. MatchedTagsの数を考慮（複数のタグで見つかった場合は高スコア）
-	matchedCount := float64(len(candidate.MatchedTags))
-	score += matchedCount * 10.0 // マッチしたタグ数 × 10
-	
-	// 2. Typesフィールドと検索タグのマッピングを比較
-	// タグからGoogle Places APIのタイプへのマッピング
-	tagToTypeMap := map[string][]string{
-		"カフェ":     {"cafe", "cafe", "food", "point_of_interest", "establishment"},
-		"レストラン":  {"restaurant", "food", "point_of_interest", "establishment"},
-		"神社":      {"shrine", "place_of_worship", "point_of_interest", "establishment"},
-		"寺":       {"temple", "place_of_worship", "point_of_interest", "establishment"},
-		"公園":      {"park", "point_of_interest", "establishment"},
-		"自然":      {"park", "natural_feature", "point_of_interest", "establishment"},
-		"観光":      {"tourist_attraction", "tourist_attraction", "point_of_interest", "establishment"},
-		"ショッピング": {"shopping_mall", "store", "point_of_interest", "establishment"},
-		"博物館":     {"museum", "point_of_interest", "establishment"},
-		"美術館":     {"art_gallery", "museum", "point_of_interest", "establishment"},
-	}
-	
-	// 各検索タグについて、Typesとの一致を確認
-	for _, tag := range interestTags {
-		expectedTypes, exists := tagToTypeMap[tag]
-		if !exists {
+// geocodeError 出発地点・ゴール地点の座標取得の失敗を返す
+// 場所が見つからない場合は、detailsにリクエストの項目名を設定する
+func geocodeError(label, field string, err error) error {
+	if errors.Is(err, apperror.ErrPlaceNotFound) {
+		return apperror.Wrap(apperror.ErrPlaceNotFound, err, "%sの座標取得に失敗しました", label).
+			WithCode("GEOCODING_ERROR").
+			WithDetails("%s", field)
+	}
+	return fmt.Errorf("%sの座標取得に失敗しました: %w", label, err)
+}
+
+// saveTrip リコメンド時点の旅程を保存し、旅程IDを返す
+// trip_idが指定されている場合は既存の旅程にスポットを追加する（旅程の所有者以外はErrForbidden）
+func (u *RecommendUsecase) saveTrip(req *models.RecommendRequest, places []models.TripPlace) (string, error) {
+	tripID := req.TripID
+	if tripID != "" {
+		if _, err := authorizeTrip(u.tripRepository, tripID, req.UserID); err != nil {
+			return "", err
+		}
+	} else {
+		trip := &models.Trip{
+			UserID: req.UserID,
+			Title:  buildTripTitle(req.MustPlaces),
+		}
+		if err := u.tripRepository.CreateTrip(trip); err != nil {
+			return "", fmt.Errorf("旅程の保存に失敗しました: %w", err)
+		}
+		tripID = trip.ID
+	}
+
+	for i := range places {
+		// place_idが取得できなかった場所は保存しない
+		if places[i].PlaceID == "" {
 			continue
 		}
-		
-		// Typesフィールドに期待されるタイプが含まれているか確認
-		for _, placeType := range candidate.Types {
-			for _, expectedType := range expectedTypes {
-				if placeType == expectedType {
-					// 主要タイプ（最初の2つ）に一致する場合は高スコア
-					if placeType == expectedTypes[0] {
-						score += 20.0 // 完全一致
-					} else if len(expectedTypes) > 1 && placeType == expectedTypes[1] {
-						score += 15.0 // 準一致
-					} else {
-						score += 5.0 // 部分一致
-					}
-					break
-				}
-			}
+		places[i].TripID = tripID
+		if err := u.tripRepository.AddTripPlace(&places[i]); err != nil {
+			return "", fmt.Errorf("旅程の保存に失敗しました: %w", err)
+		}
+	}
+
+	return tripID, nil
+}
+
+// countErrors nilでないエラーの数を返す
+func countErrors(errs []error) int {
+	count := 0
+	for _, err := range errs {
+		if err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// firstError 最初のnilでないエラーを返す
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
 		}
 	}
-	
-	// 3. 評価も少し考慮（関連性が同じ場合の補助的な要素）
-	score += candidate.Rating * 0.5
-	
-	return score
+	return nil
 }
 
+// buildSearchTerms 周辺検索とスコアに使う検索語（重み付き）を返す
+// 指定された興味タグ（重み1）、自由記述から推定した興味タグ、追加の検索キーワード（重み1）の順に並べる
+// 推定した興味タグが指定された興味タグと同じタグの場合は、指定された方だけを残す
+func buildSearchTerms(interestTags []string, extracted *models.ExtractedInterests, tagRegistry *tags.Registry) []models.WeightedTag {
+	var terms []models.WeightedTag
+	seen := make(map[string]bool)
+	add := func(name string, weight float64) {
+		key := name
+		if tag, ok := tagRegistry.Lookup(name); ok {
+			key = tag.ID
+		}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		terms = append(terms, models.WeightedTag{ID: name, Weight: weight})
+	}
+
+	for _, tag := range interestTags {
+		add(tag, 1)
+	}
+	if extracted != nil {
+		for _, tag := range extracted.Tags {
+			add(tag.ID, tag.Weight)
+		}
+		for _, keyword := range extracted.Keywords {
+			add(keyword, 1)
+		}
+	}
+	return terms
+}
+
+// scoreCandidates 寄り道の距離を含めた各候補のスコアを計算する（place_id → スコア）
+func (u *RecommendUsecase) scoreCandidates(candidates []service.PlaceResult, searchTerms []models.WeightedTag, detours map[string]models.Detour) map[string]float64 {
+	scores := make(map[string]float64, len(candidates))
+	for _, candidate := range candidates {
+		scores[candidate.PlaceID] = u.scorer.Score(scoring.Candidate{
+			PlaceResult:  candidate,
+			DetourMeters: detours[candidate.PlaceID].DistanceMeters,
+		}, searchTerms)
+	}
+	return scores
+}
+
+// sortByScore 候補をスコアの高い順に並べる（スコアが同じ場合は評価の高い順）
+func sortByScore(candidates []service.PlaceResult, scores map[string]float64) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		scoreI := scores[candidates[i].PlaceID]
+		scoreJ := scores[candidates[j].PlaceID]
+		if scoreI == scoreJ {
+			return candidates[i].Rating > candidates[j].Rating
+		}
+		return scoreI > scoreJ
+	})
+}
+
+// rerankItems 並べ替えに使う候補の情報（スコア・見つかった検索語・見つかった枝）
+func rerankItems(candidates []service.PlaceResult, scores map[string]float64, edges map[string]int) []rerank.Item {
+	items := make([]rerank.Item, len(candidates))
+	for i, candidate := range candidates {
+		items[i] = rerank.Item{
+			Score: scores[candidate.PlaceID],
+			Tags:  candidate.MatchedTags,
+			Edge:  edges[candidate.PlaceID],
+		}
+	}
+	return items
+}
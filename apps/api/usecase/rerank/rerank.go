@@ -0,0 +1,186 @@
+// Package rerank 関連性スコアで並べた候補から、関連性と多様性のバランスを取って表示する候補を選ぶ
+//
+// スコアの順に上位を選ぶだけでは、同じ興味タグ・同じ区間（最小全域木の枝）の候補ばかりになりやすい。
+// MMR（Maximal Marginal Relevance）で、選択済みの候補と似ている候補ほど順位を下げて選ぶ。
+package rerank
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Item 並べ替える候補
+type Item struct {
+	Score float64  // 関連性スコア
+	Tags  []string // 候補が見つかった検索語
+	Edge  int      // 候補が見つかった区間（最小全域木の枝）のインデックス
+}
+
+// IReranker 候補を並べ替えるインターフェース
+type IReranker interface {
+	// Rerank itemsから最大k件を選び、表示する順のインデックスを返す
+	// requiredTagsの各検索語は、その検索語で見つかった候補があれば少なくとも1件含める
+	Rerank(items []Item, requiredTags []string, k int) []int
+}
+
+// Config 並べ替えの設定
+type Config struct {
+	Lambda float64 // 関連性の重み（RERANK_LAMBDA、0〜1、デフォルト: 0.7）。1の場合は関連性スコアの順（多様性を考慮しない）
+}
+
+// LoadConfig 環境変数から並べ替えの設定を読み込む（未設定の項目は既定値）
+func LoadConfig() (Config, error) {
+	cfg := Config{Lambda: 0.7}
+	if v := os.Getenv("RERANK_LAMBDA"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 1 {
+			return cfg, fmt.Errorf("RERANK_LAMBDA must be a number between 0 and 1: %s", v)
+		}
+		cfg.Lambda = f
+	}
+	return cfg, nil
+}
+
+// 候補同士の類似度の内訳の重み（合計1）
+const (
+	tagSimilarityWeight  = 0.5 // 見つかった検索語の重なり（Jaccard係数）
+	edgeSimilarityWeight = 0.5 // 同じ区間で見つかったか
+)
+
+// MMR Maximal Marginal Relevanceで候補を選ぶ
+type MMR struct {
+	lambda float64
+}
+
+// NewMMR 新しいMMRを作成
+func NewMMR(cfg Config) IReranker {
+	return &MMR{lambda: cfg.Lambda}
+}
+
+// Rerank 次の順に候補を選ぶ
+//  1. requiredTagsの順に、まだ選んだ候補に含まれない検索語の候補から、MMRの値が最も高いものを選ぶ
+//  2. 残りの枠を、MMRの値 λ×関連性 − (1−λ)×選択済みの候補との最大の類似度 が最も高い候補で埋める
+//
+// 関連性はitemsの最大スコアを1として正規化する。値が同じ場合はitemsの順（スコアの高い順に並べておく）を優先する
+// 選んだ候補はスコアの高い順に並べて返す
+func (m *MMR) Rerank(items []Item, requiredTags []string, k int) []int {
+	k = min(k, len(items))
+	if k <= 0 {
+		return nil
+	}
+
+	maxScore := 0.0
+	for _, item := range items {
+		maxScore = math.Max(maxScore, item.Score)
+	}
+
+	var selected []int
+	chosen := make([]bool, len(items))
+	pick := func(eligible func(i int) bool) bool {
+		best, bestValue := -1, math.Inf(-1)
+		for i, item := range items {
+			if chosen[i] || !eligible(i) {
+				continue
+			}
+			relevance := 0.0
+			if maxScore > 0 {
+				relevance = item.Score / maxScore
+			}
+			maxSimilarity := 0.0
+			for _, j := range selected {
+				maxSimilarity = math.Max(maxSimilarity, similarity(item, items[j]))
+			}
+			value := m.lambda*relevance - (1-m.lambda)*maxSimilarity
+			if value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+		if best < 0 {
+			return false
+		}
+		chosen[best] = true
+		selected = append(selected, best)
+		return true
+	}
+
+	// 1. 各検索語の候補を少なくとも1件含める
+	for _, tag := range requiredTags {
+		if len(selected) >= k {
+			break
+		}
+		covered := false
+		for _, j := range selected {
+			covered = covered || hasTag(items[j], tag)
+		}
+		if !covered {
+			pick(func(i int) bool { return hasTag(items[i], tag) })
+		}
+	}
+
+	// 2. 残りの枠を埋める
+	for len(selected) < k {
+		if !pick(func(int) bool { return true }) {
+			break
+		}
+	}
+
+	// 表示はスコアの高い順（同じスコアの場合はitemsの順）
+	ordered := make([]int, 0, len(selected))
+	for i := range items {
+		if chosen[i] {
+			ordered = append(ordered, i)
+		}
+	}
+	sort.SliceStable(ordered, func(a, b int) bool {
+		return items[ordered[a]].Score > items[ordered[b]].Score
+	})
+	return ordered
+}
+
+// similarity 候補同士の類似度（0〜1）
+func similarity(a, b Item) float64 {
+	s := tagSimilarityWeight * jaccard(a.Tags, b.Tags)
+	if a.Edge == b.Edge {
+		s += edgeSimilarityWeight
+	}
+	return s
+}
+
+// jaccard 検索語の集合のJaccard係数（どちらも空の場合は0）
+func jaccard(a, b []string) float64 {
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+	union := len(set)
+	common := 0
+	seen := make(map[string]bool, len(b))
+	for _, t := range b {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		if set[t] {
+			common++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+// hasTag 候補が検索語tagで見つかったか
+func hasTag(item Item, tag string) bool {
+	for _, t := range item.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package rerank
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMMRRerank(t *testing.T) {
+	// スコアの高い順に並べた候補（0〜2は同じ検索語・同じ区間）
+	items := []Item{
+		{Score: 10, Tags: []string{"cafe"}, Edge: 0},
+		{Score: 9, Tags: []string{"cafe"}, Edge: 0},
+		{Score: 8, Tags: []string{"cafe"}, Edge: 0},
+		{Score: 7, Tags: []string{"view"}, Edge: 1},
+		{Score: 3, Tags: []string{"temple"}, Edge: 0},
+	}
+	tied := []Item{
+		{Score: 5, Tags: []string{"cafe"}, Edge: 0},
+		{Score: 5, Tags: []string{"view"}, Edge: 1},
+		{Score: 5, Tags: []string{"temple"}, Edge: 2},
+	}
+
+	tests := []struct {
+		name         string
+		lambda       float64
+		items        []Item
+		requiredTags []string
+		k            int
+		want         []int
+	}{
+		{"λ=1はスコアの順", 1, items, nil, 3, []int{0, 1, 2}},
+		{"λ=1で全件", 1, items, nil, 5, []int{0, 1, 2, 3, 4}},
+		{"λ=1で同じスコアはitemsの順", 1, tied, nil, 2, []int{0, 1}},
+		{"λ=1でも必須の検索語の候補を含める", 1, items, []string{"cafe", "temple"}, 3, []int{0, 1, 4}},
+		{"上位に含まれる必須の検索語はスコアの順のまま", 1, items, []string{"cafe"}, 3, []int{0, 1, 2}},
+		{"似ている候補の順位を下げる", 0.5, items, nil, 3, []int{0, 1, 3}},
+		{"必須の検索語を全て含める", 0.7, items, []string{"cafe", "view", "temple"}, 3, []int{0, 3, 4}},
+		{"必須の検索語が枠より多い場合は先頭から含める", 0.7, items, []string{"view", "cafe", "temple"}, 1, []int{3}},
+		{"候補のない必須の検索語は無視する", 1, items, []string{"onsen"}, 2, []int{0, 1}},
+		{"kが候補数より多い", 0.7, tied, nil, 10, []int{0, 1, 2}},
+		{"kが0", 0.7, items, []string{"cafe"}, 0, nil},
+		{"候補なし", 0.7, nil, []string{"cafe"}, 3, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewMMR(Config{Lambda: tt.lambda}).Rerank(tt.items, tt.requiredTags, tt.k)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rerank = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Item
+		want float64
+	}{
+		{"同じ検索語・同じ区間", Item{Tags: []string{"cafe"}, Edge: 0}, Item{Tags: []string{"cafe"}, Edge: 0}, 1},
+		{"検索語が半分重なる", Item{Tags: []string{"cafe", "view"}, Edge: 0}, Item{Tags: []string{"cafe"}, Edge: 1}, 0.25},
+		{"重複した検索語は1つと数える", Item{Tags: []string{"cafe"}, Edge: 0}, Item{Tags: []string{"cafe", "cafe"}, Edge: 1}, 0.5},
+		{"検索語なし・異なる区間", Item{Edge: 0}, Item{Edge: 1}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := similarity(tt.a, tt.b); got != tt.want {
+				t.Errorf("similarity = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
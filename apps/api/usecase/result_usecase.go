@@ -1,22 +1,31 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"fukuoka-ai-api/infra/repository"
 	"fukuoka-ai-api/infra/service"
 	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/pkg/apperror"
+	"fukuoka-ai-api/pkg/polyline"
+	"fukuoka-ai-api/usecase/solver"
+	"log/slog"
 	"time"
 )
 
 // IResultUsecase ルート提案機能のユースケースインターフェース
 type IResultUsecase interface {
-	ComputeOptimizedRoute(req *models.ResultRequest) (*models.ResultResponse, error)
+	ComputeOptimizedRoute(ctx context.Context, req *models.ResultRequest) (*models.ResultResponse, error)
 }
 
 // ResultUsecase ルート提案機能のユースケース実装
 type ResultUsecase struct {
-	geocodingService service.IGeocodingService
+	geocodingService    service.IGeocodingService
 	placeDetailsService service.IPlaceDetailsService
-	routeService     service.IRouteService
+	routeService        service.IRouteService
+	routeMatrixService  service.IRouteMatrixService
+	tripRepository      repository.ITripRepository
 }
 
 // NewResultUsecase 新しいResultUsecaseを作成
@@ -24,73 +33,188 @@ func NewResultUsecase(
 	geocodingService service.IGeocodingService,
 	placeDetailsService service.IPlaceDetailsService,
 	routeService service.IRouteService,
+	routeMatrixService service.IRouteMatrixService,
+	tripRepository repository.ITripRepository,
 ) IResultUsecase {
 	return &ResultUsecase{
 		geocodingService:    geocodingService,
 		placeDetailsService: placeDetailsService,
 		routeService:        routeService,
+		routeMatrixService:  routeMatrixService,
+		tripRepository:      tripRepository,
 	}
 }
 
 // ComputeOptimizedRoute ルート提案機能のメイン処理
-func (u *ResultUsecase) ComputeOptimizedRoute(req *models.ResultRequest) (*models.ResultResponse, error) {
+// ルート行列を取得できず直線距離で訪問順序を決めた場合は、レスポンスとともにその失敗（apperror.ErrPartialResult）を返す
+func (u *ResultUsecase) ComputeOptimizedRoute(ctx context.Context, req *models.ResultRequest) (*models.ResultResponse, error) {
 	// 1. 行きたい場所リストから座標を取得
 	if len(req.Places) == 0 {
-		return nil, fmt.Errorf("場所リストが空です")
+		return nil, apperror.New(apperror.ErrInvalidInput, "場所リストが空です").WithDetails("places")
+	}
+	if req.StartTime != "" {
+		if _, err := parseClock(req.StartTime); err != nil {
+			return nil, err
+		}
+	}
+	for placeID, minutes := range req.StayMinutesMap {
+		if minutes < 0 {
+			return nil, apperror.New(apperror.ErrInvalidInput, "滞在時間は0以上で指定してください (place_id: %s)", placeID).WithDetails("stay_minutes_map")
+		}
+	}
+	travelMode, err := service.NormalizeTravelMode(req.TravelMode)
+	if err != nil {
+		return nil, apperror.New(apperror.ErrInvalidInput, "移動手段が不正です: %s", req.TravelMode).WithDetails("travel_mode")
+	}
+	if req.Date != "" {
+		if _, err := parseTripDate(req.Date); err != nil {
+			return nil, err
+		}
+	}
+
+	// 保存先の旅程と保存済みスポット（新規作成の場合はnil）
+	trip, existing, err := u.loadTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	date := req.Date
+	if date == "" && trip != nil {
+		date = trip.Date
+	}
+	tripDate, err := parseTripDate(date)
+	if err != nil {
+		return nil, err
+	}
+	startTime := req.StartTime
+	if startTime == "" && trip != nil {
+		startTime = trip.StartTime
+	}
+	if startTime == "" {
+		startTime = models.DefaultTripStartTime
+	}
+	startSeconds, err := parseClock(startTime)
+	if err != nil {
+		return nil, err
 	}
 
 	// Place Details APIで各場所の詳細情報を取得
 	var places []models.Place
 	var waypoints []service.Waypoint
+	var windows [][]solver.Window
+	var weekdayTexts [][]string
 
 	for i, placeID := range req.Places {
-		details, err := u.placeDetailsService.GetPlaceDetails(placeID, "")
+		details, err := u.placeDetailsService.GetPlaceDetails(ctx, placeID, "", service.PlaceDetailsFieldsWithOpeningHours)
 		if err != nil {
 			// 詳細取得に失敗した場所はエラーメッセージに含める
+			if errors.Is(err, apperror.ErrPlaceNotFound) {
+				return nil, apperror.Wrap(apperror.ErrPlaceNotFound, err, "場所[%d] (place_id: %s) の詳細取得に失敗しました", i, placeID).WithDetails("%s", placeID)
+			}
 			return nil, fmt.Errorf("場所[%d] (place_id: %s) の詳細取得に失敗しました: %w", i, placeID, err)
 		}
 
 		places = append(places, models.Place{
-			PlaceID:  details.PlaceID,
-			Name:     details.Name,
-			Lat:      details.Lat,
-			Lng:      details.Lng,
-			Rating:   details.Rating,
-			Address:  details.Address,
-			PhotoURL: details.PhotoURL,
+			PlaceID:     details.PlaceID,
+			Name:        details.Name,
+			Lat:         details.Lat,
+			Lng:         details.Lng,
+			Rating:      details.Rating,
+			Address:     details.Address,
+			PhotoURL:    details.PhotoURL,
+			StayMinutes: resolveStayMinutes(req, i, placeID, existing),
 		})
 
 		waypoints = append(waypoints, service.Waypoint{
-			PlaceID: placeID,
-			Lat:     details.Lat,
-			Lng:     details.Lng,
+			PlaceID:     placeID,
+			Lat:         details.Lat,
+			Lng:         details.Lng,
+			StayMinutes: places[len(places)-1].StayMinutes,
 		})
+
+		// 出発地点・ゴール地点は営業時間の制約を受けない
+		var placeWindows []solver.Window
+		var weekdayText []string
+		if details.OpeningHours != nil {
+			weekdayText = details.OpeningHours.WeekdayText
+			if i > 0 && i < len(req.Places)-1 {
+				placeWindows = openingWindows(details.OpeningHours, tripDate)
+			}
+		}
+		windows = append(windows, placeWindows)
+		weekdayTexts = append(weekdayTexts, weekdayText)
 	}
 
 	if len(places) == 0 {
-		return nil, fmt.Errorf("有効な場所が見つかりませんでした")
+		return nil, apperror.New(apperror.ErrPlaceNotFound, "有効な場所が見つかりませんでした").WithDetails("places")
+	}
+
+	// 2. 地点間のコスト行列（所要時間）から、営業時間内に訪問できる訪問順序を決定する
+	// 最初の場所を出発地点、最後の場所をゴール地点として固定し、中間の場所の順序を最適化する
+	// 営業時間の判定と同じ旅行日・開始時刻の交通状況で所要時間を求める
+	departureTime := tripDepartureTime(tripDate, startSeconds, time.Now())
+	order := []int{0}
+	var unvisited []int
+	var partial error
+	if len(places) > 1 {
+		var costMatrix [][]float64
+		costMatrix, partial = u.buildCostMatrix(ctx, waypoints, travelMode, &departureTime)
+		if partial != nil && !apperror.IsPartial(partial) {
+			return nil, partial
+		}
+		order, unvisited, err = solveVisitOrder(costMatrix, places, windows, startSeconds)
+		if err != nil {
+			return nil, fmt.Errorf("訪問順序の最適化に失敗しました: %w", err)
+		}
+	}
+
+	// 訪問できない場所はルートから除外し、旅程には訪問する場所の後に残す
+	unvisitable := make([]models.UnvisitablePlace, 0, len(unvisited))
+	skipped := make([]skippedPlace, 0, len(unvisited))
+	for _, idx := range unvisited {
+		reason := models.UnvisitableReasonOutsideHours
+		if isClosedAllDay(windows[idx]) {
+			reason = models.UnvisitableReasonClosed
+		}
+		skipped = append(skipped, skippedPlace{place: places[idx], reason: reason})
+		unvisitable = append(unvisitable, models.UnvisitablePlace{
+			PlaceID:      places[idx].PlaceID,
+			Name:         places[idx].Name,
+			Reason:       reason,
+			OpeningHours: weekdayTexts[idx],
+		})
 	}
 
-	// 出発地点とゴール地点を設定
-	// 最初の場所を出発地点、最後の場所をゴール地点とする
-	originLat := places[0].Lat
-	originLng := places[0].Lng
-	destinationLat := places[len(places)-1].Lat
-	destinationLng := places[len(places)-1].Lng
+	optimizedPlaces := make([]models.Place, 0, len(order))
+	orderedWaypoints := make([]service.Waypoint, 0, len(order))
+	orderedWindows := make([][]solver.Window, 0, len(order))
+	for _, idx := range order {
+		optimizedPlaces = append(optimizedPlaces, places[idx])
+		orderedWaypoints = append(orderedWaypoints, waypoints[idx])
+		orderedWindows = append(orderedWindows, windows[idx])
+	}
 
 	// 経由地点は中間の場所（最初と最後を除く）
+	// OptimizedOrderは経由地点の元のインデックス（リクエスト時の順序）を訪問順に並べたもの
 	var intermediates []service.Waypoint
-	if len(waypoints) > 2 {
-		intermediates = waypoints[1 : len(waypoints)-1]
+	var optimizedOrder []int
+	if len(orderedWaypoints) > 2 {
+		intermediates = orderedWaypoints[1 : len(orderedWaypoints)-1]
+		for _, idx := range order[1 : len(order)-1] {
+			optimizedOrder = append(optimizedOrder, idx-1)
+		}
 	}
 
-	// 2. Routes APIでルートを計算（経由地順最適化を有効にする）
-	departureTime := time.Now().Add(1 * time.Hour) // 1時間後をデフォルトとする
+	// 3. 決定した順序のままRoutes APIで各区間のルートを取得する
+	// 経由地点がRoutes APIの上限を超える場合は、RouteServiceが分割して取得する
+	origin := orderedWaypoints[0]
+	destination := orderedWaypoints[len(orderedWaypoints)-1]
 	routeResp, err := u.routeService.ComputeRoute(
-		originLat, originLng,
-		destinationLat, destinationLng,
+		ctx,
+		origin.Lat, origin.Lng,
+		destination.Lat, destination.Lng,
 		intermediates,
-		"DRIVE",
+		false,
+		travelMode,
 		&departureTime,
 	)
 	if err != nil {
@@ -98,48 +222,132 @@ func (u *ResultUsecase) ComputeOptimizedRoute(req *models.ResultRequest) (*model
 	}
 
 	if len(routeResp.Routes) == 0 {
-		return nil, fmt.Errorf("ルートが見つかりませんでした")
+		return nil, errNoRoutes()
 	}
 
-	routeData := routeResp.Routes[0]
+	// 4. ルート情報を構築
+	route := buildRoute(ctx, routeResp, len(intermediates))
+	route.TravelMode = travelMode
+	if len(optimizedOrder) > 0 {
+		route.OptimizedOrder = optimizedOrder
+	}
 
-	// 3. 最適化された順序に従って場所を並び替え
-	// OptimizedIntermediateWaypointIndexは経由地点（intermediates）の順序のみを表す
-	// origin（最初）とdestination（最後）は順序に含まれない
-	optimizedPlaces := make([]models.Place, 0, len(places))
-	
-	// 最初にorigin（最初の場所）を追加
-	optimizedPlaces = append(optimizedPlaces, places[0])
-	
-	if len(routeData.OptimizedIntermediateWaypointIndex) > 0 && len(intermediates) > 0 {
-		// 最適化された順序を使用して経由地点を追加
-		// OptimizedIntermediateWaypointIndexは経由地点の元のインデックス（リクエスト時の順序）の配列
-		// この配列の順序が最適化された順序を表す
-		// 例: OptimizedIntermediateWaypointIndex = [2, 0, 1] の場合、元の2番目、0番目、1番目の順で訪問
-		for _, originalIntermediateIdx := range routeData.OptimizedIntermediateWaypointIndex {
-			// intermediatesのインデックスをplacesのインデックスに変換（+1はoriginの分）
-			placeIdx := originalIntermediateIdx + 1
-			if placeIdx > 0 && placeIdx < len(places)-1 { // destinationより前
-				optimizedPlaces = append(optimizedPlaces, places[placeIdx])
-			}
+	// 実際の区間の所要時間で営業開始までの待ち時間を求める
+	// コスト行列との誤差で滞在中に閉店してしまう場所は訪問できない場所として併せて返す
+	stayMinutes := make([]int, len(optimizedPlaces))
+	for i, p := range optimizedPlaces {
+		stayMinutes[i] = p.StayMinutes
+	}
+	waits, late := scheduleWaits(startSeconds, stayMinutes, route.Legs, orderedWindows)
+	for _, wait := range waits {
+		if wait > 0 {
+			route.WaitMinutes = waits
+			break
 		}
-	} else if len(intermediates) > 0 {
-		// 最適化されていない場合は元の順序で経由地点を追加
-		for i := 1; i < len(places)-1; i++ {
-			optimizedPlaces = append(optimizedPlaces, places[i])
+	}
+	for _, idx := range late {
+		unvisitable = append(unvisitable, models.UnvisitablePlace{
+			PlaceID:      optimizedPlaces[idx].PlaceID,
+			Name:         optimizedPlaces[idx].Name,
+			Reason:       models.UnvisitableReasonClosesBeforeStay,
+			OpeningHours: weekdayTexts[order[idx]],
+		})
+	}
+
+	// 5. 最適化された順序で旅程を保存
+	trip, tripPlaces, err := u.saveTrip(req, trip, existing, optimizedPlaces, skipped, &route)
+	if err != nil {
+		return nil, err
+	}
+	// 訪問できない場所に保存したスポットのIDを設定（除外した場所、滞在中に閉店する場所の順）
+	for i := range unvisitable {
+		if i < len(skipped) {
+			unvisitable[i].ID = tripPlaces[len(optimizedPlaces)+i].ID
+		} else {
+			unvisitable[i].ID = tripPlaces[late[i-len(skipped)]].ID
 		}
 	}
-	
-	// 最後にdestination（最後の場所）を追加
-	if len(places) > 1 {
-		optimizedPlaces = append(optimizedPlaces, places[len(places)-1])
+
+	// 6. 開始時刻・滞在時間・区間の所要時間から各場所の時間帯を計算
+	endTime, err := applySchedule(trip.StartTime, tripPlaces, &route)
+	if err != nil {
+		return nil, err
+	}
+	for i := range optimizedPlaces {
+		optimizedPlaces[i].StayMinutes = tripPlaces[i].StayMinutes
+		optimizedPlaces[i].TimeRange = tripPlaces[i].TimeRange
 	}
 
-	// 4. ルート情報を構築
+	return &models.ResultResponse{
+		Places:      optimizedPlaces,
+		Route:       route,
+		TripID:      trip.ID,
+		StartTime:   trip.StartTime,
+		EndTime:     endTime,
+		Unvisitable: unvisitable,
+	}, partial
+}
+
+// solveVisitOrder 出発地点（先頭）とゴール地点（末尾）を固定して訪問順序を決定する
+// 営業時間の制約がある場合は時間枠付きで解き、営業時間内に訪問できない場所のインデックスを併せて返す
+func solveVisitOrder(costMatrix [][]float64, places []models.Place, windows [][]solver.Window, startSeconds int) ([]int, []int, error) {
+	hasTimeWindows := false
+	for _, w := range windows {
+		if w != nil {
+			hasTimeWindows = true
+			break
+		}
+	}
+	if !hasTimeWindows {
+		order, _, err := solver.SolvePath(costMatrix, 0, len(places)-1)
+		return order, nil, err
+	}
+
+	staySeconds := make([]float64, len(places))
+	for i, p := range places {
+		staySeconds[i] = float64(p.StayMinutes * 60)
+	}
+	result, err := solver.SolvePathWithTimeWindows(solver.TimeWindowProblem{
+		Cost:      costMatrix,
+		Service:   staySeconds,
+		Windows:   windows,
+		StartTime: float64(startSeconds),
+		Start:     0,
+		End:       len(places) - 1,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Path, result.Unvisited, nil
+}
+
+// resolveStayMinutes リクエストのi番目の場所の滞在時間を決定する
+// 最初と最後は出発地点・ゴール地点として扱う（滞在時間なし）
+// それ以外はstay_minutes_map、保存済みスポットの滞在時間、既定値の順に使用する
+func resolveStayMinutes(req *models.ResultRequest, i int, placeID string, existing map[string][]models.TripPlace) int {
+	if i == 0 || i == len(req.Places)-1 {
+		if minutes, ok := req.StayMinutesMap[placeID]; ok {
+			return minutes
+		}
+		return 0
+	}
+	if minutes, ok := req.StayMinutesMap[placeID]; ok {
+		return minutes
+	}
+	if saved := existing[placeID]; len(saved) > 0 {
+		return saved[0].StayMinutes
+	}
+	return models.DefaultStayMinutes
+}
+
+// buildRoute Routes APIのレスポンスからルート情報を構築
+// intermediateCountは経由地点の数（最適化されていない場合の順序の生成に使用）
+func buildRoute(ctx context.Context, routeResp *service.RouteResponse, intermediateCount int) models.Route {
+	routeData := routeResp.Routes[0]
+
 	var routeLegs []models.RouteLeg
 	for i, leg := range routeData.Legs {
-		// デバッグ: 距離情報を確認
-		fmt.Printf("Leg %d: DistanceMeters=%d, Duration=%s\n", i, leg.DistanceMeters, leg.Duration)
+		slog.DebugContext(ctx, "route leg", "index", i, "distance_meters", leg.DistanceMeters, "duration", leg.Duration)
 		routeLegs = append(routeLegs, models.RouteLeg{
 			StartLocation: models.Coordinate{
 				Lat: leg.StartLocation.LatLng.Latitude,
@@ -151,29 +359,213 @@ func (u *ResultUsecase) ComputeOptimizedRoute(req *models.ResultRequest) (*model
 			},
 			DistanceMeters: leg.DistanceMeters,
 			Duration:       leg.Duration,
+			Polyline:       leg.Polyline.EncodedPolyline,
+			Path:           decodePath(leg.Polyline.EncodedPolyline),
 		})
 	}
 
 	// Routes API v2では、OptimizedIntermediateWaypointIndexから最適化された順序を取得
 	optimizedOrder := routeData.OptimizedIntermediateWaypointIndex
-	if len(optimizedOrder) == 0 && len(intermediates) > 0 {
+	if len(optimizedOrder) == 0 && intermediateCount > 0 {
 		// 最適化されていない場合は経由地点の元の順序（0から始まる連番）
-		optimizedOrder = make([]int, len(intermediates))
+		optimizedOrder = make([]int, intermediateCount)
 		for i := range optimizedOrder {
 			optimizedOrder[i] = i
 		}
 	}
 
-	route := models.Route{
+	return models.Route{
 		Legs:           routeLegs,
 		DistanceMeters: routeData.DistanceMeters,
 		Duration:       routeData.Duration,
 		OptimizedOrder: optimizedOrder,
+		Polyline:       routeData.Polyline.EncodedPolyline,
+		Path:           decodePath(routeData.Polyline.EncodedPolyline),
 	}
+}
 
-	return &models.ResultResponse{
-		Places: optimizedPlaces,
-		Route:  route,
-	}, nil
+// buildCostMatrix 訪問順序の決定に使うコスト行列を作成
+// Routes APIのルート行列（所要時間）を優先し、取得できない場合は直線距離で代用する
+// 直線距離で代用した場合は、コスト行列とともにその失敗（apperror.ErrPartialResult）を返す
+// 呼び出し元のcontextがキャンセルされた場合は代用せずにエラーを返す
+func (u *ResultUsecase) buildCostMatrix(ctx context.Context, waypoints []service.Waypoint, travelMode string, departureTime *time.Time) ([][]float64, error) {
+	matrix, err := u.routeMatrixService.ComputeRouteMatrix(ctx, waypoints, travelMode, departureTime)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("ルート行列の取得を中断しました: %w", ctxErr)
+		}
+		slog.WarnContext(ctx, "ルート行列の取得に失敗したため直線距離で順序を決定します", "error", err)
+		return buildHaversineMatrix(waypoints, travelMode), apperror.Partial("ROUTE_MATRIX_FALLBACK", err, "ルート行列を取得できなかったため直線距離で訪問順序を決定しました")
+	}
+	return matrix.DurationCosts(), nil
+}
+
+// errNoRoutes Routes APIがルートを返さなかった場合のエラー
+func errNoRoutes() error {
+	return apperror.New(apperror.ErrUpstream, "ルートが見つかりませんでした").WithCode("ROUTES_API_ERROR")
+}
+
+// buildHaversineMatrix 地点間の直線距離と移動手段ごとの速度から、所要時間（秒）の見積もりのコスト行列を作成
+func buildHaversineMatrix(waypoints []service.Waypoint, travelMode string) [][]float64 {
+	speed := estimatedSpeed(travelMode)
+	matrix := make([][]float64, len(waypoints))
+	for i, from := range waypoints {
+		matrix[i] = make([]float64, len(waypoints))
+		for j, to := range waypoints {
+			if i != j {
+				matrix[i][j] = haversineDistance(from.Lat, from.Lng, to.Lat, to.Lng) / speed
+			}
+		}
+	}
+	return matrix
+}
+
+// decodePath エンコード済みポリラインを座標列に変換（空や不正な場合はnil）
+func decodePath(encoded string) []models.Coordinate {
+	if encoded == "" {
+		return nil
+	}
+	path, err := polyline.Decode(encoded)
+	if err != nil {
+		return nil
+	}
+	return path
+}
+
+// decodeRoutePaths 保存されたルートの座標列をポリラインから復元する（routeがnilの場合は何もしない）
+func decodeRoutePaths(route *models.Route) {
+	if route == nil {
+		return
+	}
+	route.Path = decodePath(route.Polyline)
+	for i := range route.Legs {
+		route.Legs[i].Path = decodePath(route.Legs[i].Polyline)
+	}
 }
 
+// loadTrip 保存先の旅程と、place_idごとの保存済みスポットを取得する
+// trip_idが指定されていない場合は、旅程はnil（保存時に新規作成）になる
+// 旅程の所有者以外はErrForbiddenを返す
+func (u *ResultUsecase) loadTrip(req *models.ResultRequest) (*models.Trip, map[string][]models.TripPlace, error) {
+	// place_idごとの保存済みスポット（出発地点とゴール地点が同じ場所の場合は複数件になる）
+	existing := make(map[string][]models.TripPlace)
+	if req.TripID == "" {
+		return nil, existing, nil
+	}
+
+	trip, err := authorizeTrip(u.tripRepository, req.TripID, req.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	saved, err := u.tripRepository.ListTripPlaces(trip.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("旅程のスポット取得に失敗しました (trip_id: %s): %w", trip.ID, err)
+	}
+	for _, p := range saved {
+		existing[p.PlaceID] = append(existing[p.PlaceID], p)
+	}
+	return trip, existing, nil
+}
+
+// skippedPlace 営業時間内に訪問できずルートから除外した場所
+type skippedPlace struct {
+	place  models.Place
+	reason string // closed/outside_hours
+}
+
+// saveTrip ルート計算結果の順序で旅程のスポットを保存し、旅程と保存したスポットを返す
+// tripがnilの場合は旅程を新規作成する
+// 既存スポットの種類・理由などを引き継ぎ、滞在時間はorderedPlacesの値（resolveStayMinutesで決定済み）を使用する
+// ルートから除外した場所はunvisitable_reasonを設定して訪問する場所の後に保存する
+// start_time・dateが指定されている場合はその値で上書きする
+func (u *ResultUsecase) saveTrip(req *models.ResultRequest, trip *models.Trip, existing map[string][]models.TripPlace, orderedPlaces []models.Place, skipped []skippedPlace, route *models.Route) (*models.Trip, []models.TripPlace, error) {
+	if trip != nil {
+		if req.StartTime != "" && req.StartTime != trip.StartTime {
+			if err := u.tripRepository.UpdateTripStartTime(trip.ID, req.StartTime); err != nil {
+				return nil, nil, fmt.Errorf("旅程の保存に失敗しました: %w", err)
+			}
+			trip.StartTime = req.StartTime
+		}
+		if req.Date != "" && req.Date != trip.Date {
+			if err := u.tripRepository.UpdateTripDate(trip.ID, req.Date); err != nil {
+				return nil, nil, fmt.Errorf("旅程の保存に失敗しました: %w", err)
+			}
+			trip.Date = req.Date
+		}
+	} else {
+		// 出発地点・ゴール地点を除いたスポット名からタイトルを生成
+		var names []string
+		for i, p := range orderedPlaces {
+			if i > 0 && i < len(orderedPlaces)-1 {
+				names = append(names, p.Name)
+			}
+		}
+		trip = &models.Trip{
+			UserID:    req.UserID,
+			Title:     buildTripTitle(names),
+			StartTime: req.StartTime,
+			Date:      req.Date,
+		}
+		if err := u.tripRepository.CreateTrip(trip); err != nil {
+			return nil, nil, fmt.Errorf("旅程の保存に失敗しました: %w", err)
+		}
+	}
+
+	tripPlaces := make([]models.TripPlace, 0, len(orderedPlaces)+len(skipped))
+	for i, p := range orderedPlaces {
+		tripPlace := newTripPlace(p, existing)
+		// 最初と最後は出発地点・ゴール地点として扱う
+		if i == 0 {
+			tripPlace.Kind = models.TripPlaceKindStart
+		} else if i == len(orderedPlaces)-1 {
+			tripPlace.Kind = models.TripPlaceKindGoal
+		}
+		tripPlaces = append(tripPlaces, tripPlace)
+	}
+	for _, skip := range skipped {
+		tripPlace := newTripPlace(skip.place, existing)
+		if tripPlace.Kind == models.TripPlaceKindStart || tripPlace.Kind == models.TripPlaceKindGoal {
+			tripPlace.Kind = models.TripPlaceKindMust
+		}
+		tripPlace.UnvisitableReason = skip.reason
+		tripPlaces = append(tripPlaces, tripPlace)
+	}
+
+	if err := u.tripRepository.ReplaceTripPlaces(trip.ID, tripPlaces); err != nil {
+		return nil, nil, fmt.Errorf("旅程の保存に失敗しました: %w", err)
+	}
+	if err := u.tripRepository.SaveTripRoute(trip.ID, route); err != nil {
+		return nil, nil, fmt.Errorf("ルートの保存に失敗しました: %w", err)
+	}
+
+	return trip, tripPlaces, nil
+}
+
+// newTripPlace 場所から保存するスポットを作成する
+// 同じplace_idの既存スポットがある場合は、先頭から順にIDと種類・理由などを引き継ぐ
+func newTripPlace(p models.Place, existing map[string][]models.TripPlace) models.TripPlace {
+	tripPlace := models.TripPlace{
+		PlaceID:       p.PlaceID,
+		Name:          p.Name,
+		Lat:           p.Lat,
+		Lng:           p.Lng,
+		Kind:          models.TripPlaceKindMust,
+		StayMinutes:   p.StayMinutes,
+		ReviewSummary: p.ReviewSummary,
+		PhotoURL:      p.PhotoURL,
+	}
+	if queue := existing[p.PlaceID]; len(queue) > 0 {
+		saved := queue[0]
+		existing[p.PlaceID] = queue[1:]
+		tripPlace.ID = saved.ID
+		tripPlace.Kind = saved.Kind
+		tripPlace.Reason = saved.Reason
+		if tripPlace.ReviewSummary == "" {
+			tripPlace.ReviewSummary = saved.ReviewSummary
+		}
+		if tripPlace.PhotoURL == "" {
+			tripPlace.PhotoURL = saved.PhotoURL
+		}
+	}
+	return tripPlace
+}
@@ -0,0 +1,103 @@
+package usecase
+
+import (
+	"fmt"
+	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/pkg/apperror"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseClock "HH:MM"形式の時刻を0時からの秒数に変換
+func parseClock(clock string) (int, error) {
+	parts := strings.Split(clock, ":")
+	if len(parts) != 2 {
+		return 0, apperror.New(apperror.ErrInvalidInput, "開始時刻の形式が不正です (例: \"10:00\"): %s", clock).WithDetails("start_time")
+	}
+	hour, errH := strconv.Atoi(parts[0])
+	minute, errM := strconv.Atoi(parts[1])
+	if errH != nil || errM != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, apperror.New(apperror.ErrInvalidInput, "開始時刻の形式が不正です (例: \"10:00\"): %s", clock).WithDetails("start_time")
+	}
+	return hour*3600 + minute*60, nil
+}
+
+// formatClock 0時からの秒数を"HH:MM"形式に変換（分単位に四捨五入）
+// 日付をまたぐ場合は"25:30"のように24以上の時で表す
+func formatClock(seconds int) string {
+	minutes := (seconds + 30) / 60
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseRouteDuration Routes APIの所要時間（例: "3600s"）を秒数に変換
+func parseRouteDuration(duration string) int {
+	if duration == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0
+	}
+	return int(d.Seconds())
+}
+
+// computeSchedule 開始時刻・各スポットの滞在時間・区間の所要時間から到着/出発時刻を計算
+// stayMinutes[i]はi番目のスポットの滞在時間、legs[i]はi番目からi+1番目への区間
+// waitMinutes[i]はi番目のスポットで営業開始を待つ時間（nilの場合は待ち時間なし）
+// 戻り値は各スポットの"HH:MM-HH:MM"と、最後のスポットを出発する時刻
+func computeSchedule(startTime string, stayMinutes []int, legs []models.RouteLeg, waitMinutes []int) ([]string, string, error) {
+	current, err := parseClock(startTime)
+	if err != nil {
+		return nil, "", err
+	}
+
+	timeRanges := make([]string, len(stayMinutes))
+	for i, stay := range stayMinutes {
+		if i > 0 && i-1 < len(legs) {
+			current += parseRouteDuration(legs[i-1].Duration)
+		}
+		if i < len(waitMinutes) {
+			current += waitMinutes[i] * 60
+		}
+		arrival := current
+		current += stay * 60
+		timeRanges[i] = formatClock(arrival) + "-" + formatClock(current)
+	}
+
+	return timeRanges, formatClock(current), nil
+}
+
+// applySchedule 旅程の各スポットにtime_rangeを設定し、終了時刻を返す
+// ルートから除外したスポット（unvisitable_reasonあり）は時間帯を設定しない
+// ルートが未計算の場合や訪問するスポット数と区間数が一致しない場合は何もしない
+func applySchedule(startTime string, itinerary []models.TripPlace, route *models.Route) (string, error) {
+	visited := make([]int, 0, len(itinerary))
+	for i, p := range itinerary {
+		if p.UnvisitableReason == "" {
+			visited = append(visited, i)
+		}
+	}
+	if route == nil || len(visited) == 0 || len(route.Legs) != len(visited)-1 {
+		return "", nil
+	}
+
+	stayMinutes := make([]int, len(visited))
+	for i, idx := range visited {
+		stayMinutes[i] = itinerary[idx].StayMinutes
+	}
+
+	// 待ち時間はルートと同じ順序で保存されているため、スポット数が一致する場合のみ使用する
+	var waitMinutes []int
+	if len(route.WaitMinutes) == len(visited) {
+		waitMinutes = route.WaitMinutes
+	}
+	timeRanges, endTime, err := computeSchedule(startTime, stayMinutes, route.Legs, waitMinutes)
+	if err != nil {
+		return "", err
+	}
+	for i, idx := range visited {
+		itinerary[idx].TimeRange = timeRanges[i]
+	}
+	return endTime, nil
+}
@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"fukuoka-ai-api/models"
+	"reflect"
+	"testing"
+)
+
+func TestFormatClock(t *testing.T) {
+	tests := []struct {
+		name    string
+		seconds int
+		want    string
+	}{
+		{"0時", 0, "00:00"},
+		{"分単位", 10*3600 + 5*60, "10:05"},
+		{"30秒未満は切り捨てる", 10*3600 + 29, "10:00"},
+		{"30秒以上は切り上げる", 10*3600 + 30, "10:01"},
+		{"切り上げで時が変わる", 10*3600 + 59*60 + 45, "11:00"},
+		{"日付をまたぐ", 25*3600 + 30*60, "25:30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatClock(tt.seconds); got != tt.want {
+				t.Errorf("formatClock(%d) = %s, want %s", tt.seconds, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		name    string
+		clock   string
+		want    int
+		wantErr bool
+	}{
+		{"時刻", "10:30", 10*3600 + 30*60, false},
+		{"1桁の時", "9:05", 9*3600 + 5*60, false},
+		{"区切りがない", "1030", 0, true},
+		{"24時以降", "24:00", 0, true},
+		{"60分以上", "10:60", 0, true},
+		{"数字以外", "aa:bb", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClock(tt.clock)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseClock(%s) error = %v, wantErr %v", tt.clock, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseClock(%s) = %d, want %d", tt.clock, got, tt.want)
+			}
+		})
+	}
+}
+
+// routeLegs 所要時間（Routes APIの形式）から区間を作る
+func routeLegs(durations ...string) []models.RouteLeg {
+	result := make([]models.RouteLeg, len(durations))
+	for i, d := range durations {
+		result[i] = models.RouteLeg{Duration: d}
+	}
+	return result
+}
+
+func TestComputeSchedule(t *testing.T) {
+	tests := []struct {
+		name        string
+		startTime   string
+		stayMinutes []int
+		legs        []models.RouteLeg
+		waitMinutes []int
+		want        []string
+		wantEnd     string
+		wantErr     bool
+	}{
+		{
+			name:        "滞在時間と区間の所要時間を積み上げる",
+			startTime:   "10:00",
+			stayMinutes: []int{0, 60, 30, 0},
+			legs:        routeLegs("600s", "1800s", "900s"),
+			want:        []string{"10:00-10:00", "10:10-11:10", "11:40-12:10", "12:25-12:25"},
+			wantEnd:     "12:25",
+		},
+		{
+			name:        "営業開始の待ち時間は到着時刻に含める",
+			startTime:   "08:30",
+			stayMinutes: []int{0, 60},
+			legs:        routeLegs("1800s"),
+			waitMinutes: []int{0, 30},
+			want:        []string{"08:30-08:30", "09:30-10:30"},
+			wantEnd:     "10:30",
+		},
+		{
+			name:        "日付をまたぐ",
+			startTime:   "22:00",
+			stayMinutes: []int{0, 120, 60},
+			legs:        routeLegs("1800s", "1800s"),
+			want:        []string{"22:00-22:00", "22:30-24:30", "25:00-26:00"},
+			wantEnd:     "26:00",
+		},
+		{
+			name:        "区間がスポットより少ない場合は足りない区間を0秒とする",
+			startTime:   "10:00",
+			stayMinutes: []int{30, 30, 30},
+			legs:        routeLegs("600s"),
+			want:        []string{"10:00-10:30", "10:40-11:10", "11:10-11:40"},
+			wantEnd:     "11:40",
+		},
+		{
+			name:        "区間がスポットより多い場合は余った区間を使わない",
+			startTime:   "10:00",
+			stayMinutes: []int{30},
+			legs:        routeLegs("600s", "600s"),
+			want:        []string{"10:00-10:30"},
+			wantEnd:     "10:30",
+		},
+		{
+			name:        "待ち時間がスポットより少ない",
+			startTime:   "10:00",
+			stayMinutes: []int{0, 30},
+			legs:        routeLegs("600s"),
+			waitMinutes: []int{15},
+			want:        []string{"10:15-10:15", "10:25-10:55"},
+			wantEnd:     "10:55",
+		},
+		{
+			name:        "不正な所要時間は0秒とする",
+			startTime:   "10:00",
+			stayMinutes: []int{0, 30},
+			legs:        routeLegs("abc"),
+			want:        []string{"10:00-10:00", "10:00-10:30"},
+			wantEnd:     "10:30",
+		},
+		{
+			name:      "スポットなし",
+			startTime: "10:00",
+			legs:      routeLegs("600s"),
+			want:      []string{},
+			wantEnd:   "10:00",
+		},
+		{
+			name:        "不正な開始時刻",
+			startTime:   "25:00",
+			stayMinutes: []int{0},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, end, err := computeSchedule(tt.startTime, tt.stayMinutes, tt.legs, tt.waitMinutes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("computeSchedule error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) || end != tt.wantEnd {
+				t.Errorf("computeSchedule = %v, %s, want %v, %s", got, end, tt.want, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestApplySchedule(t *testing.T) {
+	place := func(stay int, unvisitable string) models.TripPlace {
+		return models.TripPlace{StayMinutes: stay, UnvisitableReason: unvisitable}
+	}
+
+	tests := []struct {
+		name      string
+		itinerary []models.TripPlace
+		route     *models.Route
+		want      []string
+		wantEnd   string
+	}{
+		{
+			name:      "各スポットに時間帯を設定する",
+			itinerary: []models.TripPlace{place(0, ""), place(60, ""), place(0, "")},
+			route:     &models.Route{Legs: routeLegs("600s", "1200s")},
+			want:      []string{"10:00-10:00", "10:10-11:10", "11:30-11:30"},
+			wantEnd:   "11:30",
+		},
+		{
+			name:      "ルートから除外したスポットは時間帯を設定しない",
+			itinerary: []models.TripPlace{place(0, ""), place(60, ""), place(0, ""), place(90, "closed"), place(30, "outside_hours")},
+			route:     &models.Route{Legs: routeLegs("600s", "1200s")},
+			want:      []string{"10:00-10:00", "10:10-11:10", "11:30-11:30", "", ""},
+			wantEnd:   "11:30",
+		},
+		{
+			name:      "スポット数と一致する待ち時間を使う",
+			itinerary: []models.TripPlace{place(0, ""), place(60, ""), place(30, "closed")},
+			route:     &models.Route{Legs: routeLegs("600s"), WaitMinutes: []int{0, 20}},
+			want:      []string{"10:00-10:00", "10:30-11:30", ""},
+			wantEnd:   "11:30",
+		},
+		{
+			name:      "スポット数と一致しない待ち時間は使わない",
+			itinerary: []models.TripPlace{place(0, ""), place(60, "")},
+			route:     &models.Route{Legs: routeLegs("600s"), WaitMinutes: []int{0, 20, 10}},
+			want:      []string{"10:00-10:00", "10:10-11:10"},
+			wantEnd:   "11:10",
+		},
+		{
+			name:      "ルートが未計算",
+			itinerary: []models.TripPlace{place(0, ""), place(60, "")},
+			want:      []string{"", ""},
+		},
+		{
+			name:      "区間数がスポット数と一致しない",
+			itinerary: []models.TripPlace{place(0, ""), place(60, ""), place(0, "")},
+			route:     &models.Route{Legs: routeLegs("600s")},
+			want:      []string{"", "", ""},
+		},
+		{
+			name:      "全てのスポットをルートから除外した",
+			itinerary: []models.TripPlace{place(60, "closed")},
+			route:     &models.Route{},
+			want:      []string{""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, err := applySchedule("10:00", tt.itinerary, tt.route)
+			if err != nil {
+				t.Fatalf("applySchedule: %v", err)
+			}
+			got := make([]string, len(tt.itinerary))
+			for i, p := range tt.itinerary {
+				got[i] = p.TimeRange
+			}
+			if !reflect.DeepEqual(got, tt.want) || end != tt.wantEnd {
+				t.Errorf("applySchedule = %v, %s, want %v, %s", got, end, tt.want, tt.wantEnd)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package scoring
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// スコアの計算方法（SCORER）
+const (
+	StrategyBayesian = "bayesian" // 評価を評価件数で補正する（ベイズ平均）
+	StrategyTag      = "tag"      // 評価をそのまま使う
+)
+
+// Weights スコアの各要素の重み
+type Weights struct {
+	MatchedTag float64 // 候補が見つかった検索語1つあたりの加点（SCORE_MATCHED_TAG_WEIGHT、デフォルト: 10）
+	TypeMatch  float64 // タグ定義のtype_weightsにかける倍率（SCORE_TYPE_WEIGHT、デフォルト: 1）
+	Rating     float64 // 評価（0〜5）にかける倍率（SCORE_RATING_WEIGHT、デフォルト: 0.5）
+	Detour     float64 // 寄り道の距離1kmあたりの減点（SCORE_DETOUR_WEIGHT、デフォルト: 2）
+}
+
+// Config スコアの計算の設定
+type Config struct {
+	Strategy     string  // スコアの計算方法（SCORER、bayesian/tag、デフォルト: bayesian）
+	Weights      Weights // スコアの各要素の重み
+	PriorRating  float64 // ベイズ平均の事前の評価（SCORE_PRIOR_RATING、デフォルト: 4.0）
+	PriorReviews float64 // ベイズ平均の事前の評価件数（SCORE_PRIOR_REVIEWS、デフォルト: 50）
+	MinScore     float64 // 候補として残すスコアの下限（SCORE_MIN_RELEVANCE、デフォルト: 5）
+}
+
+// LoadConfig 環境変数からスコアの計算の設定を読み込む（未設定の項目は既定値）
+func LoadConfig() (Config, error) {
+	cfg := Config{
+		Strategy: StrategyBayesian,
+		Weights: Weights{
+			MatchedTag: 10,
+			TypeMatch:  1,
+			Rating:     0.5,
+			Detour:     2,
+		},
+		PriorRating:  4.0,
+		PriorReviews: 50,
+		MinScore:     5,
+	}
+
+	if v := os.Getenv("SCORER"); v != "" {
+		v = strings.ToLower(v)
+		if v != StrategyBayesian && v != StrategyTag {
+			return cfg, fmt.Errorf("SCORER must be %q or %q: %s", StrategyBayesian, StrategyTag, v)
+		}
+		cfg.Strategy = v
+	}
+
+	for _, item := range []struct {
+		name string
+		dest *float64
+	}{
+		{"SCORE_MATCHED_TAG_WEIGHT", &cfg.Weights.MatchedTag},
+		{"SCORE_TYPE_WEIGHT", &cfg.Weights.TypeMatch},
+		{"SCORE_RATING_WEIGHT", &cfg.Weights.Rating},
+		{"SCORE_DETOUR_WEIGHT", &cfg.Weights.Detour},
+		{"SCORE_PRIOR_REVIEWS", &cfg.PriorReviews},
+		{"SCORE_MIN_RELEVANCE", &cfg.MinScore},
+	} {
+		v := os.Getenv(item.name)
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return cfg, fmt.Errorf("%s must be a non-negative number: %s", item.name, v)
+		}
+		*item.dest = f
+	}
+
+	if v := os.Getenv("SCORE_PRIOR_RATING"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > MaxRating {
+			return cfg, fmt.Errorf("SCORE_PRIOR_RATING must be a number between 0 and %g: %s", MaxRating, v)
+		}
+		cfg.PriorRating = f
+	}
+	return cfg, nil
+}
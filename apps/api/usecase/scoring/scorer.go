@@ -0,0 +1,156 @@
+// Package scoring 周辺検索の候補と検索語（興味タグ・検索キーワード）の関連性スコア
+//
+// スコアは次の要素の合計で、各要素の重みは設定（Config）で変えられる。
+//   - 候補が見つかった検索語ごとに 検索語の重み × MatchedTag
+//   - 検索語のタグ定義のtype_weightsのうち、候補のタイプに一致するものごとに 検索語の重み × type_weights × TypeMatch
+//   - 評価 × Rating（評価の扱いはScorerの実装ごとに異なる）
+//   - 寄り道の距離（km） × Detour の減点
+package scoring
+
+import (
+	"fmt"
+	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/infra/tags"
+	"fukuoka-ai-api/models"
+)
+
+// MaxRating Places APIの評価の最大値
+const MaxRating = 5.0
+
+// Candidate スコアを計算する候補
+type Candidate struct {
+	service.PlaceResult
+	DetourMeters float64 // 旅程の地点の間に立ち寄る場合に増える移動距離（メートル、求めていない場合は0）
+}
+
+// Scorer 候補の関連性スコアの計算方法
+type Scorer interface {
+	// Score 候補と検索語の関連性スコア（高いほど関連性が高い）
+	Score(candidate Candidate, searchTerms []models.WeightedTag) float64
+	// MaxScore 検索語に対してScoreが取りうる最大値（寄り道の距離が0の場合）
+	MaxScore(searchTerms []models.WeightedTag) float64
+	// MinScore 候補として残すスコアの下限（これ未満の候補は検索語と関係ないとみなす）
+	// 寄り道の距離を求める前（DetourMetersが0）のスコアと比べる
+	MinScore() float64
+}
+
+// New 設定のスコアの計算方法のScorerを作成
+// tagRegistryは検索語のタグ定義（type_weights）を引くのに使う
+func New(cfg Config, tagRegistry *tags.Registry) (Scorer, error) {
+	base := relevance{weights: cfg.Weights, minScore: cfg.MinScore, tagRegistry: tagRegistry}
+	switch cfg.Strategy {
+	case StrategyTag:
+		return &TagScorer{relevance: base}, nil
+	case StrategyBayesian:
+		return &BayesianScorer{relevance: base, priorRating: cfg.PriorRating, priorReviews: cfg.PriorReviews}, nil
+	}
+	return nil, fmt.Errorf("unknown scorer: %s", cfg.Strategy)
+}
+
+// relevance 評価以外の要素（検索語との一致）のスコア
+type relevance struct {
+	weights     Weights
+	minScore    float64
+	tagRegistry *tags.Registry
+}
+
+// score 候補が見つかった検索語と、候補のタイプと検索語のタグ定義のtype_weightsの一致によるスコア
+// タグ定義のない検索語（追加の検索キーワードなど）はタイプによる加点をしない
+func (r relevance) score(candidate service.PlaceResult, searchTerms []models.WeightedTag) float64 {
+	score := 0.0
+	for _, matched := range candidate.MatchedTags {
+		score += termWeight(searchTerms, matched) * r.weights.MatchedTag
+	}
+	for _, term := range searchTerms {
+		tag, ok := r.tagRegistry.Lookup(term.ID)
+		if !ok {
+			continue
+		}
+		for _, placeType := range candidate.Types {
+			score += term.Weight * tag.TypeWeights[placeType] * r.weights.TypeMatch
+		}
+	}
+	return score
+}
+
+// detourPenalty 寄り道の距離による減点
+func (r relevance) detourPenalty(candidate Candidate) float64 {
+	return candidate.DetourMeters / 1000 * r.weights.Detour
+}
+
+// maxScore すべての検索語で見つかり、各タグ定義のtype_weightsのすべてのタイプを持つ候補のスコア
+func (r relevance) maxScore(searchTerms []models.WeightedTag) float64 {
+	score := 0.0
+	for _, term := range searchTerms {
+		score += term.Weight * r.weights.MatchedTag
+		tag, ok := r.tagRegistry.Lookup(term.ID)
+		if !ok {
+			continue
+		}
+		for _, weight := range tag.TypeWeights {
+			score += term.Weight * weight * r.weights.TypeMatch
+		}
+	}
+	return score
+}
+
+func (r relevance) MinScore() float64 { return r.minScore }
+
+// termWeight 検索語nameの重みを返す（検索語に含まれない場合は1）
+func termWeight(searchTerms []models.WeightedTag, name string) float64 {
+	for _, term := range searchTerms {
+		if term.ID == name {
+			return term.Weight
+		}
+	}
+	return 1
+}
+
+// TagScorer 評価をそのまま加点する
+// 評価件数を考慮しないため、評価件数の少ない高評価の候補が上位になりやすい
+type TagScorer struct {
+	relevance
+}
+
+// Score 検索語との一致によるスコア + 評価 × Rating − 寄り道の距離による減点
+func (s *TagScorer) Score(candidate Candidate, searchTerms []models.WeightedTag) float64 {
+	return s.score(candidate.PlaceResult, searchTerms) + candidate.Rating*s.weights.Rating - s.detourPenalty(candidate)
+}
+
+// MaxScore 検索語との一致によるスコアの最大値 + 評価の最大値 × Rating
+func (s *TagScorer) MaxScore(searchTerms []models.WeightedTag) float64 {
+	return s.maxScore(searchTerms) + MaxRating*s.weights.Rating
+}
+
+// BayesianScorer 評価を評価件数で補正（ベイズ平均）して加点する
+// 評価件数が少ない候補の評価は事前の評価（priorRating）に近づけるため、
+// 評価5.0・2件の候補より評価4.6・3,000件の候補を高く評価する
+type BayesianScorer struct {
+	relevance
+	priorRating  float64 // 事前の評価（評価件数が少ない候補の評価はこの値に近づく）
+	priorReviews float64 // 事前の評価件数（評価件数がこの値と同じ場合、評価と事前の評価を半々にする）
+}
+
+// Score 検索語との一致によるスコア + ベイズ平均の評価 × Rating − 寄り道の距離による減点
+func (s *BayesianScorer) Score(candidate Candidate, searchTerms []models.WeightedTag) float64 {
+	return s.score(candidate.PlaceResult, searchTerms) + s.rating(candidate.PlaceResult)*s.weights.Rating - s.detourPenalty(candidate)
+}
+
+// MaxScore 検索語との一致によるスコアの最大値 + 評価の最大値 × Rating
+func (s *BayesianScorer) MaxScore(searchTerms []models.WeightedTag) float64 {
+	return s.maxScore(searchTerms) + MaxRating*s.weights.Rating
+}
+
+// rating ベイズ平均の評価 (v×R + m×C) / (v + m)
+// Rは候補の評価、vは評価件数、Cは事前の評価、mは事前の評価件数
+// 評価がない候補は事前の評価とする
+func (s *BayesianScorer) rating(candidate service.PlaceResult) float64 {
+	v := float64(candidate.UserRatingsTotal)
+	if candidate.Rating == 0 {
+		v = 0
+	}
+	if v+s.priorReviews == 0 {
+		return s.priorRating
+	}
+	return (v*candidate.Rating + s.priorReviews*s.priorRating) / (v + s.priorReviews)
+}
@@ -0,0 +1,111 @@
+package scoring
+
+import (
+	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/infra/tags"
+	"fukuoka-ai-api/models"
+	"math"
+	"testing"
+)
+
+// testRegistry cafeタグ（type_weights: cafe=2）だけを定義したタグ定義
+func testRegistry(t *testing.T) *tags.Registry {
+	t.Helper()
+	registry, err := tags.Parse([]byte(`{"tags": [{"id": "cafe", "labels": {"ja": "カフェ"}, "type_weights": {"cafe": 2}}]}`))
+	if err != nil {
+		t.Fatalf("tags.Parse: %v", err)
+	}
+	return registry
+}
+
+func TestBayesianRating(t *testing.T) {
+	tests := []struct {
+		name         string
+		priorReviews float64
+		rating       float64
+		reviews      int
+		want         float64
+	}{
+		{"評価なし", 50, 0, 0, 4.0},
+		{"評価件数0", 50, 4.8, 0, 4.0},
+		{"評価件数があっても評価が0の場合は評価なし", 50, 0, 12, 4.0},
+		{"事前の評価件数0で評価なし", 0, 0, 0, 4.0},
+		{"事前の評価件数0は評価をそのまま使う", 0, 4.8, 3, 4.8},
+		{"評価件数が事前の評価件数と同じなら半々", 50, 5.0, 50, 4.5},
+		{"評価件数が多いほど評価に近づく", 50, 4.6, 3000, (3000*4.6 + 50*4.0) / 3050},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &BayesianScorer{priorRating: 4.0, priorReviews: tt.priorReviews}
+			got := s.rating(service.PlaceResult{Rating: tt.rating, UserRatingsTotal: tt.reviews})
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("rating = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScorers(t *testing.T) {
+	cfg := Config{
+		Weights:      Weights{MatchedTag: 10, TypeMatch: 1, Rating: 1, Detour: 2},
+		PriorRating:  4.0,
+		PriorReviews: 50,
+		MinScore:     5,
+	}
+	searchTerms := []models.WeightedTag{{ID: "cafe", Weight: 0.5}, {ID: "古民家", Weight: 1}}
+	fewReviews := Candidate{PlaceResult: service.PlaceResult{
+		MatchedTags: []string{"cafe"}, Types: []string{"cafe"}, Rating: 5.0, UserRatingsTotal: 2,
+	}}
+	manyReviews := Candidate{PlaceResult: service.PlaceResult{
+		MatchedTags: []string{"cafe"}, Types: []string{"cafe"}, Rating: 4.6, UserRatingsTotal: 3000,
+	}}
+	noRatings := Candidate{PlaceResult: service.PlaceResult{MatchedTags: []string{"古民家"}}, DetourMeters: 1500}
+
+	// 検索語との一致によるスコア: cafe 0.5×10 + type_weights 0.5×2×1 = 6、古民家 1×10 = 10（寄り道1.5kmで3の減点）
+	// 最大値: cafe 0.5×10 + 0.5×2×1 + 古民家 1×10 + 評価の最大値 5×1 = 21
+	const maxScore = 21.0
+	tests := []struct {
+		name      string
+		strategy  string
+		candidate Candidate
+		want      float64
+	}{
+		{"評価をそのまま加点する", StrategyTag, fewReviews, 6 + 5.0},
+		{"評価件数を考慮しない", StrategyTag, manyReviews, 6 + 4.6},
+		{"評価なしは加点しない", StrategyTag, noRatings, 10 - 3},
+		{"評価件数の少ない評価は事前の評価に近づける", StrategyBayesian, fewReviews, 6 + (2*5.0+50*4.0)/52},
+		{"評価件数の多い評価はほぼそのまま使う", StrategyBayesian, manyReviews, 6 + (3000*4.6+50*4.0)/3050},
+		{"評価なしは事前の評価を加点する", StrategyBayesian, noRatings, 10 + 4.0 - 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			cfg.Strategy = tt.strategy
+			scorer, err := New(cfg, testRegistry(t))
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			if got := scorer.Score(tt.candidate, searchTerms); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score = %v, want %v", got, tt.want)
+			}
+			if got := scorer.MaxScore(searchTerms); got != maxScore {
+				t.Errorf("MaxScore = %v, want %v", got, maxScore)
+			}
+			if got := scorer.MinScore(); got != cfg.MinScore {
+				t.Errorf("MinScore = %v, want %v", got, cfg.MinScore)
+			}
+		})
+	}
+
+	// 評価件数の少ない高評価の候補は、評価件数の多い候補より低くする
+	bayesian, _ := New(Config{Strategy: StrategyBayesian, Weights: cfg.Weights, PriorRating: 4.0, PriorReviews: 50}, testRegistry(t))
+	if few, many := bayesian.Score(fewReviews, searchTerms), bayesian.Score(manyReviews, searchTerms); few >= many {
+		t.Errorf("Score(5.0×2件) = %v, want below Score(4.6×3000件) = %v", few, many)
+	}
+}
+
+func TestNewUnknownStrategy(t *testing.T) {
+	if _, err := New(Config{Strategy: "unknown"}, testRegistry(t)); err == nil {
+		t.Error("expected error")
+	}
+}
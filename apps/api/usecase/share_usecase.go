@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"fukuoka-ai-api/infra/repository"
+	"fukuoka-ai-api/models"
+)
+
+// IShareUsecase 旅程共有機能のユースケースインターフェース
+type IShareUsecase interface {
+	CreateShare(tripID string, userID string) (*models.Share, error)
+	RevokeShare(tripID string, userID string) error
+	GetShare(shareID string) (*models.ShareResponse, error)
+}
+
+// ShareUsecase 旅程共有機能のユースケース実装
+type ShareUsecase struct {
+	tripRepository repository.ITripRepository
+}
+
+// NewShareUsecase 新しいShareUsecaseを作成
+func NewShareUsecase(tripRepository repository.ITripRepository) IShareUsecase {
+	return &ShareUsecase{
+		tripRepository: tripRepository,
+	}
+}
+
+// CreateShare 旅程の共有IDを発行する
+// 既に共有IDがある場合は失効させて新しいIDを発行する（以前のリンクは無効になる）
+func (u *ShareUsecase) CreateShare(tripID string, userID string) (*models.Share, error) {
+	if err := u.authorizeShare(tripID, userID); err != nil {
+		return nil, err
+	}
+
+	existing, err := u.tripRepository.GetShareByTripID(tripID)
+	if err == nil {
+		if err := u.tripRepository.DeleteShare(existing.ShareID); err != nil {
+			return nil, fmt.Errorf("共有情報の削除に失敗しました: %w", err)
+		}
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("共有情報の取得に失敗しました: %w", err)
+	}
+
+	share := &models.Share{TripID: tripID}
+	if err := u.tripRepository.CreateShare(share); err != nil {
+		return nil, fmt.Errorf("共有情報の保存に失敗しました: %w", err)
+	}
+	return share, nil
+}
+
+// RevokeShare 旅程の共有を停止する
+func (u *ShareUsecase) RevokeShare(tripID string, userID string) error {
+	if err := u.authorizeShare(tripID, userID); err != nil {
+		return err
+	}
+
+	share, err := u.tripRepository.GetShareByTripID(tripID)
+	if err != nil {
+		return fmt.Errorf("共有情報の取得に失敗しました (trip_id: %s): %w", tripID, err)
+	}
+	if err := u.tripRepository.DeleteShare(share.ShareID); err != nil {
+		return fmt.Errorf("共有情報の削除に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// GetShare 共有IDから旅程を取得する（ログイン不要）
+func (u *ShareUsecase) GetShare(shareID string) (*models.ShareResponse, error) {
+	share, err := u.tripRepository.GetShare(shareID)
+	if err != nil {
+		return nil, fmt.Errorf("共有された旅程の取得に失敗しました: %w", err)
+	}
+
+	trip, err := u.tripRepository.GetTrip(share.TripID)
+	if err != nil {
+		return nil, fmt.Errorf("共有された旅程の取得に失敗しました: %w", err)
+	}
+
+	places, err := u.tripRepository.ListTripPlaces(share.TripID)
+	if err != nil {
+		return nil, fmt.Errorf("旅程のスポット取得に失敗しました: %w", err)
+	}
+
+	route, err := u.tripRepository.GetTripRoute(share.TripID)
+	if err != nil {
+		return nil, fmt.Errorf("旅程のルート取得に失敗しました: %w", err)
+	}
+	decodeRoutePaths(route)
+	endTime, err := applySchedule(trip.StartTime, places, route)
+	if err != nil {
+		return nil, err
+	}
+	// 旅程IDは含めない（共有を停止した後も旅程を取得できてしまうため）
+	for i := range places {
+		places[i].TripID = ""
+	}
+
+	return &models.ShareResponse{
+		Trip: models.TripHeader{
+			Title:     trip.Title,
+			StartTime: trip.StartTime,
+		},
+		Itinerary: places,
+		Route:     route,
+		EndTime:   endTime,
+	}, nil
+}
+
+// authorizeShare 共有を操作できるのはユーザーに紐づく旅程の所有者のみ
+// 所有者がいない旅程は旅程IDを知っている誰でも操作できてしまい、共有を停止できないため共有しない
+func (u *ShareUsecase) authorizeShare(tripID string, userID string) error {
+	trip, err := authorizeTrip(u.tripRepository, tripID, userID)
+	if err != nil {
+		return err
+	}
+	if trip.UserID == "" {
+		return fmt.Errorf("ユーザーに紐づかない旅程は共有できません: %w", ErrForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Package solver 出発地点とゴール地点を固定した巡回順序（ハミルトン路）の最適化
+//
+// コスト行列cost[i][j]は地点iから地点jへの移動コスト（距離・所要時間など）を表す。
+// 非対称な行列（行きと帰りでコストが異なる）にも対応する。
+// 経由地点がExactLimit以下の場合はHeld-Karp法（動的計画法）で厳密解を求め、
+// それを超える場合は最近傍法の初期解を2-opt法とOr-opt法で改善する。
+// 移動できない組み合わせは+Infのコストで表し、全ての順序が+Infになる場合も
+// 最近傍法と2-opt法・Or-opt法の順序を返す（総コストは+Inf）。
+package solver
+
+import (
+	"fmt"
+	"math"
+)
+
+// ExactLimit Held-Karp法で厳密に解く経由地点数の上限
+// 計算量はO(2^n × n^2)のため、12地点（約60万状態）程度に抑える
+const ExactLimit = 12
+
+// SolvePath startからendまで全ての地点を1回ずつ訪問する最小コストの順序を求める
+// 戻り値はstartで始まりendで終わる地点インデックスの列と、その総コスト
+// startとendが同じ場合は、startから出発してstartに戻る巡回路を求める
+func SolvePath(cost [][]float64, start, end int) ([]int, float64, error) {
+	n := len(cost)
+	if n == 0 {
+		return nil, 0, fmt.Errorf("cost matrix is empty")
+	}
+	for i, row := range cost {
+		if len(row) != n {
+			return nil, 0, fmt.Errorf("cost matrix is not square: row %d has %d columns", i, len(row))
+		}
+	}
+	if start < 0 || start >= n || end < 0 || end >= n {
+		return nil, 0, fmt.Errorf("start/end index out of range: start=%d end=%d n=%d", start, end, n)
+	}
+
+	// 出発地点・ゴール地点以外の経由地点
+	var inner []int
+	for i := 0; i < n; i++ {
+		if i != start && i != end {
+			inner = append(inner, i)
+		}
+	}
+
+	var middle []int
+	exact := false
+	if len(inner) <= ExactLimit {
+		middle, exact = heldKarp(cost, start, end, inner)
+	}
+	if !exact {
+		middle = nearestNeighbor(cost, start, inner)
+		middle = improve(cost, start, end, middle)
+	}
+
+	path := make([]int, 0, len(middle)+2)
+	path = append(path, start)
+	path = append(path, middle...)
+	if end != start || n > 1 {
+		path = append(path, end)
+	}
+	return path, PathCost(cost, path), nil
+}
+
+// PathCost 訪問順序の総コストを計算
+func PathCost(cost [][]float64, path []int) float64 {
+	total := 0.0
+	for i := 0; i+1 < len(path); i++ {
+		total += cost[path[i]][path[i+1]]
+	}
+	return total
+}
+
+// heldKarp 動的計画法で経由地点の最適な訪問順序を求める
+// dp[mask][j] = startから出発し、maskに含まれる経由地点を全て訪問してjで終わる最小コスト
+// 総コストが有限の順序がない場合はokがfalseになる
+func heldKarp(cost [][]float64, start, end int, inner []int) (order []int, ok bool) {
+	k := len(inner)
+	if k == 0 {
+		return nil, true
+	}
+
+	full := 1 << k
+	dp := make([][]float64, full)
+	parent := make([][]int, full)
+	for mask := range dp {
+		dp[mask] = make([]float64, k)
+		parent[mask] = make([]int, k)
+		for j := range dp[mask] {
+			dp[mask][j] = math.Inf(1)
+			parent[mask][j] = -1
+		}
+	}
+	for j := 0; j < k; j++ {
+		dp[1<<j][j] = cost[start][inner[j]]
+	}
+
+	for mask := 1; mask < full; mask++ {
+		for j := 0; j < k; j++ {
+			if mask&(1<<j) == 0 || math.IsInf(dp[mask][j], 1) {
+				continue
+			}
+			for next := 0; next < k; next++ {
+				if mask&(1<<next) != 0 {
+					continue
+				}
+				nextMask := mask | 1<<next
+				c := dp[mask][j] + cost[inner[j]][inner[next]]
+				if c < dp[nextMask][next] {
+					dp[nextMask][next] = c
+					parent[nextMask][next] = j
+				}
+			}
+		}
+	}
+
+	// 最後の経由地点からゴール地点までのコストを加えて最小のものを選ぶ
+	last := 0
+	best := math.Inf(1)
+	for j := 0; j < k; j++ {
+		c := dp[full-1][j] + cost[inner[j]][end]
+		if c < best {
+			best = c
+			last = j
+		}
+	}
+	if math.IsInf(best, 1) {
+		return nil, false
+	}
+
+	// 親をたどって順序を復元
+	order = make([]int, k)
+	mask := full - 1
+	for pos := k - 1; pos >= 0; pos-- {
+		order[pos] = inner[last]
+		prev := parent[mask][last]
+		mask &^= 1 << last
+		last = prev
+	}
+	return order, true
+}
+
+// nearestNeighbor 最近傍法で初期解を作成（現在地から最もコストの小さい未訪問地点へ進む）
+func nearestNeighbor(cost [][]float64, start int, inner []int) []int {
+	visited := make([]bool, len(inner))
+	order := make([]int, 0, len(inner))
+	current := start
+	for len(order) < len(inner) {
+		bestIdx := -1
+		best := math.Inf(1)
+		for i, node := range inner {
+			if !visited[i] && cost[current][node] < best {
+				best = cost[current][node]
+				bestIdx = i
+			}
+		}
+		if bestIdx < 0 {
+			// 全てのコストが+Infの場合は残りを元の順序で追加
+			for i, node := range inner {
+				if !visited[i] {
+					visited[i] = true
+					order = append(order, node)
+				}
+			}
+			break
+		}
+		visited[bestIdx] = true
+		order = append(order, inner[bestIdx])
+		current = inner[bestIdx]
+	}
+	return order
+}
+
+// improve 2-opt法とOr-opt法を改善がなくなるまで交互に適用する
+func improve(cost [][]float64, start, end int, middle []int) []int {
+	best := append([]int(nil), middle...)
+	bestCost := middleCost(cost, start, end, best)
+
+	for improved := true; improved; {
+		improved = false
+
+		// 2-opt: 区間[i, j]を反転する
+		for i := 0; i < len(best)-1; i++ {
+			for j := i + 1; j < len(best); j++ {
+				candidate := append([]int(nil), best...)
+				reverse(candidate[i : j+1])
+				if c := middleCost(cost, start, end, candidate); c < bestCost-1e-9 {
+					best, bestCost, improved = candidate, c, true
+				}
+			}
+		}
+
+		// Or-opt: 長さ1〜3の区間を別の位置に移動する
+		for segLen := 1; segLen <= 3; segLen++ {
+			for i := 0; i+segLen <= len(best); i++ {
+				for pos := 0; pos <= len(best)-segLen; pos++ {
+					if pos == i {
+						continue
+					}
+					candidate := moveSegment(best, i, segLen, pos)
+					if c := middleCost(cost, start, end, candidate); c < bestCost-1e-9 {
+						best, bestCost, improved = candidate, c, true
+					}
+				}
+			}
+		}
+	}
+	return best
+}
+
+// middleCost 経由地点の順序に出発地点・ゴール地点を加えた総コスト
+func middleCost(cost [][]float64, start, end int, middle []int) float64 {
+	if len(middle) == 0 {
+		return cost[start][end]
+	}
+	total := cost[start][middle[0]]
+	for i := 0; i+1 < len(middle); i++ {
+		total += cost[middle[i]][middle[i+1]]
+	}
+	return total + cost[middle[len(middle)-1]][end]
+}
+
+// reverse スライスを反転
+func reverse(s []int) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
+
+// moveSegment 位置iから長さsegLenの区間を取り出し、残りの列の位置posに挿入した新しい列を返す
+func moveSegment(s []int, i, segLen, pos int) []int {
+	segment := s[i : i+segLen]
+	rest := make([]int, 0, len(s)-segLen)
+	rest = append(rest, s[:i]...)
+	rest = append(rest, s[i+segLen:]...)
+
+	result := make([]int, 0, len(s))
+	result = append(result, rest[:pos]...)
+	result = append(result, segment...)
+	result = append(result, rest[pos:]...)
+	return result
+}
@@ -0,0 +1,277 @@
+package solver
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+var inf = math.Inf(1)
+
+// randomMatrix 乱数で非対称なコスト行列を作成（同じseedなら同じ行列）
+func randomMatrix(n int, seed int64) [][]float64 {
+	r := rand.New(rand.NewSource(seed))
+	cost := make([][]float64, n)
+	for i := range cost {
+		cost[i] = make([]float64, n)
+		for j := range cost[i] {
+			if i != j {
+				cost[i][j] = float64(1 + r.Intn(100))
+			}
+		}
+	}
+	return cost
+}
+
+// linePoints 数直線上の座標xsの距離行列
+func linePoints(xs []float64) [][]float64 {
+	cost := make([][]float64, len(xs))
+	for i := range cost {
+		cost[i] = make([]float64, len(xs))
+		for j := range cost[i] {
+			cost[i][j] = math.Abs(xs[i] - xs[j])
+		}
+	}
+	return cost
+}
+
+// bruteForce 経由地点の全ての順列を試して最小の総コストを求める
+func bruteForce(cost [][]float64, start, end int) float64 {
+	var inner []int
+	for i := range cost {
+		if i != start && i != end {
+			inner = append(inner, i)
+		}
+	}
+	best := inf
+	var permute func(k int)
+	permute = func(k int) {
+		if k == len(inner) {
+			path := append(append([]int{start}, inner...), end)
+			best = math.Min(best, PathCost(cost, path))
+			return
+		}
+		for i := k; i < len(inner); i++ {
+			inner[k], inner[i] = inner[i], inner[k]
+			permute(k + 1)
+			inner[k], inner[i] = inner[i], inner[k]
+		}
+	}
+	permute(0)
+	return best
+}
+
+// assertValidPath pathがstartで始まりendで終わり、全ての地点を1回ずつ含むことを確認する
+func assertValidPath(t *testing.T, path []int, n, start, end int) {
+	t.Helper()
+	if len(path) == 0 || path[0] != start || path[len(path)-1] != end {
+		t.Fatalf("path %v must start with %d and end with %d", path, start, end)
+	}
+	seen := make(map[int]bool)
+	for _, node := range path {
+		seen[node] = true
+	}
+	if len(seen) != n {
+		t.Fatalf("path %v must visit all %d nodes", path, n)
+	}
+	want := n + 1
+	if start != end {
+		want = n
+	}
+	if n == 1 {
+		want = 1
+	}
+	if len(path) != want {
+		t.Fatalf("path %v has %d entries, want %d", path, len(path), want)
+	}
+}
+
+func TestSolvePathMatchesBruteForce(t *testing.T) {
+	tests := []struct {
+		name       string
+		n          int
+		seed       int64
+		start, end int
+	}{
+		{"2地点", 2, 1, 0, 1},
+		{"3地点", 3, 2, 0, 2},
+		{"5地点", 5, 3, 0, 4},
+		{"7地点・ゴールが先頭以外", 7, 4, 3, 1},
+		{"8地点・巡回", 8, 5, 0, 0},
+		{"9地点", 9, 6, 2, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost := randomMatrix(tt.n, tt.seed)
+			path, total, err := SolvePath(cost, tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("SolvePath: %v", err)
+			}
+			assertValidPath(t, path, tt.n, tt.start, tt.end)
+			if total != PathCost(cost, path) {
+				t.Errorf("total = %v, want PathCost %v", total, PathCost(cost, path))
+			}
+			if want := bruteForce(cost, tt.start, tt.end); total != want {
+				t.Errorf("total = %v, want brute force %v", total, want)
+			}
+		})
+	}
+}
+
+func TestSolvePathDeterministic(t *testing.T) {
+	cost := randomMatrix(10, 42)
+	first, _, err := SolvePath(cost, 0, 9)
+	if err != nil {
+		t.Fatalf("SolvePath: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		path, _, _ := SolvePath(cost, 0, 9)
+		for j := range path {
+			if path[j] != first[j] {
+				t.Fatalf("run %d: path %v differs from %v", i, path, first)
+			}
+		}
+	}
+}
+
+func TestSolvePathFixedEndpoints(t *testing.T) {
+	tests := []struct {
+		name       string
+		n          int
+		start, end int
+	}{
+		{"1地点", 1, 0, 0},
+		{"出発地点とゴール地点のみ", 2, 1, 0},
+		{"出発地点が末尾", 6, 5, 2},
+		{"巡回", 6, 2, 2},
+		{"経由地点がExactLimit超の巡回", ExactLimit + 4, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, _, err := SolvePath(randomMatrix(tt.n, int64(tt.n)), tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("SolvePath: %v", err)
+			}
+			assertValidPath(t, path, tt.n, tt.start, tt.end)
+		})
+	}
+}
+
+func TestSolvePathInvalidInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		cost       [][]float64
+		start, end int
+	}{
+		{"空の行列", nil, 0, 0},
+		{"正方行列でない", [][]float64{{0, 1}, {1}}, 0, 1},
+		{"範囲外の出発地点", randomMatrix(3, 1), -1, 2},
+		{"範囲外のゴール地点", randomMatrix(3, 1), 0, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := SolvePath(tt.cost, tt.start, tt.end); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSolvePathHeuristic(t *testing.T) {
+	// 数直線上の地点は座標の順にたどるのが最適
+	xs := []float64{0, 7, 3, 15, 1, 11, 5, 13, 2, 9, 4, 14, 6, 12, 8, 10, 16}
+	cost := linePoints(xs)
+	path, total, err := SolvePath(cost, 0, len(xs)-1)
+	if err != nil {
+		t.Fatalf("SolvePath: %v", err)
+	}
+	assertValidPath(t, path, len(xs), 0, len(xs)-1)
+	if total != 16 {
+		t.Errorf("total = %v, want 16 (path %v)", total, path)
+	}
+}
+
+func TestImprove(t *testing.T) {
+	xs := []float64{0, 7, 3, 15, 1, 11, 5, 13, 2, 9, 4, 14, 6, 12, 8, 10, 16}
+	cost := linePoints(xs)
+	end := len(xs) - 1
+
+	// 最近傍法を使わず、入力の順（座標がばらばらの順）から改善する
+	var scrambled []int
+	for i := 1; i < end; i++ {
+		scrambled = append(scrambled, i)
+	}
+	before := middleCost(cost, 0, end, scrambled)
+	improved := improve(cost, 0, end, scrambled)
+	after := middleCost(cost, 0, end, improved)
+	if after >= before {
+		t.Errorf("improve did not reduce cost: before %v after %v", before, after)
+	}
+	if after != 16 {
+		t.Errorf("cost after improve = %v, want 16 (order %v)", after, improved)
+	}
+	if len(improved) != len(scrambled) {
+		t.Errorf("improve changed the number of stops: %v", improved)
+	}
+}
+
+func TestSolvePathUnreachable(t *testing.T) {
+	// 経由地点1と2の間が到達不能なため、全ての順序が+Infになる
+	unreachablePair := [][]float64{
+		{0, 1, 2, 3},
+		{1, 0, inf, 1},
+		{2, inf, 0, 1},
+		{3, 1, 1, 0},
+	}
+	// 経由地点1と2の間が到達不能でも、3を挟めば有限のコストで訪問できる
+	detour := [][]float64{
+		{0, 1, 2, 3, 4},
+		{1, 0, inf, 1, 2},
+		{2, inf, 0, 1, 1},
+		{3, 1, 1, 0, 2},
+		{4, 2, 1, 2, 0},
+	}
+	allUnreachable := [][]float64{
+		{0, inf, inf, inf},
+		{inf, 0, inf, inf},
+		{inf, inf, 0, inf},
+		{inf, inf, inf, 0},
+	}
+	large := randomMatrix(ExactLimit+5, 7)
+	for i := range large {
+		for j := range large[i] {
+			if i != j && (i+j)%5 == 0 {
+				large[i][j] = inf
+			}
+		}
+	}
+	largeUnreachable := randomMatrix(ExactLimit+5, 8)
+	for i := range largeUnreachable {
+		largeUnreachable[0][i] = inf
+	}
+
+	tests := []struct {
+		name      string
+		cost      [][]float64
+		wantTotal float64 // 負の値の場合は比較しない
+	}{
+		{"1組が到達不能で全ての順序が+Inf", unreachablePair, inf},
+		{"1組が到達不能で迂回できる", detour, bruteForce(detour, 0, 4)},
+		{"全ての組が到達不能", allUnreachable, inf},
+		{"経由地点がExactLimit超で一部が到達不能", large, -1},
+		{"経由地点がExactLimit超で出発地点から到達不能", largeUnreachable, inf},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := len(tt.cost)
+			path, total, err := SolvePath(tt.cost, 0, n-1)
+			if err != nil {
+				t.Fatalf("SolvePath: %v", err)
+			}
+			assertValidPath(t, path, n, 0, n-1)
+			if tt.wantTotal >= 0 && total != tt.wantTotal {
+				t.Errorf("total = %v, want %v (path %v)", total, tt.wantTotal, path)
+			}
+		})
+	}
+}
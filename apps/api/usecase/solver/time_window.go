@@ -0,0 +1,340 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// Window 滞在できる時間帯（秒）
+// 滞在の開始から終了までがOpen以上Close以下に収まる必要がある
+type Window struct {
+	Open  float64
+	Close float64
+}
+
+// TimeWindowProblem 時間枠付きの訪問順序問題（TSPTW）
+type TimeWindowProblem struct {
+	Cost      [][]float64 // Cost[i][j] 地点iから地点jへの移動時間（秒）
+	Service   []float64   // 各地点の滞在時間（秒）
+	Windows   [][]Window  // 各地点の滞在できる時間帯（nilは制約なし、空のスライスは終日訪問不可）
+	StartTime float64     // 出発地点を出発する時刻（秒）
+	Start     int
+	End       int
+}
+
+// TimeWindowResult 時間枠付きの訪問順序問題の解
+type TimeWindowResult struct {
+	Path       []int     // startで始まりendで終わる訪問順序（訪問できない地点は含まない）
+	Begins     []float64 // Pathの各地点で滞在を開始する時刻（秒、待ち時間を含む）
+	Unvisited  []int     // 時間枠内に訪問できない経由地点
+	FinishTime float64   // ゴール地点での滞在を終える時刻（秒）
+}
+
+// SolvePathWithTimeWindows 時間枠を守って訪問できる経由地点の数を最大化し、その中でゴール到着が最も早い順序を求める
+// 時間枠の開始より早く着いた場合は開始まで待つ
+// 経由地点がExactLimit以下の場合は動的計画法で厳密解を求め、
+// それを超える場合は締め切りの早い順に挿入する貪欲法の解を移動近傍で改善する
+func SolvePathWithTimeWindows(p TimeWindowProblem) (*TimeWindowResult, error) {
+	n := len(p.Cost)
+	if n == 0 {
+		return nil, fmt.Errorf("cost matrix is empty")
+	}
+	for i, row := range p.Cost {
+		if len(row) != n {
+			return nil, fmt.Errorf("cost matrix is not square: row %d has %d columns", i, len(row))
+		}
+	}
+	if len(p.Service) != n || len(p.Windows) != n {
+		return nil, fmt.Errorf("service/windows length mismatch: n=%d service=%d windows=%d", n, len(p.Service), len(p.Windows))
+	}
+	if p.Start < 0 || p.Start >= n || p.End < 0 || p.End >= n || p.Start == p.End {
+		return nil, fmt.Errorf("invalid start/end index: start=%d end=%d n=%d", p.Start, p.End, n)
+	}
+
+	var inner []int
+	for i := 0; i < n; i++ {
+		if i != p.Start && i != p.End {
+			inner = append(inner, i)
+		}
+	}
+
+	var middle []int
+	if len(inner) <= ExactLimit {
+		middle = p.exact(inner)
+	} else {
+		middle = p.heuristic(inner)
+	}
+
+	path := make([]int, 0, len(middle)+2)
+	path = append(path, p.Start)
+	path = append(path, middle...)
+	path = append(path, p.End)
+
+	begins, finish, ok := p.evaluate(middle)
+	if !ok {
+		return nil, fmt.Errorf("no feasible path: start or goal cannot be reached within its time window")
+	}
+
+	visited := make(map[int]bool, len(middle))
+	for _, node := range middle {
+		visited[node] = true
+	}
+	var unvisited []int
+	for _, node := range inner {
+		if !visited[node] {
+			unvisited = append(unvisited, node)
+		}
+	}
+
+	return &TimeWindowResult{
+		Path:       path,
+		Begins:     begins,
+		Unvisited:  unvisited,
+		FinishTime: finish,
+	}, nil
+}
+
+// EarliestBegin 到着時刻arrivalから、滞在時間serviceが時間枠に収まる最も早い滞在開始時刻を求める
+func EarliestBegin(arrival, service float64, windows []Window) (float64, bool) {
+	if windows == nil {
+		return arrival, true
+	}
+	best := math.Inf(1)
+	for _, w := range windows {
+		begin := math.Max(arrival, w.Open)
+		if begin+service <= w.Close && begin < best {
+			best = begin
+		}
+	}
+	return best, !math.IsInf(best, 1)
+}
+
+// evaluate 経由地点の順序middleで出発地点からゴール地点まで移動したときの各地点の滞在開始時刻を求める
+// いずれかの地点が時間枠に収まらない場合はokがfalseになる
+func (p TimeWindowProblem) evaluate(middle []int) (begins []float64, finish float64, ok bool) {
+	begin, ok := EarliestBegin(p.StartTime, p.Service[p.Start], p.Windows[p.Start])
+	if !ok {
+		return nil, 0, false
+	}
+	begins = make([]float64, 0, len(middle)+2)
+	begins = append(begins, begin)
+
+	current := p.Start
+	departure := begin + p.Service[p.Start]
+	for _, node := range append(append([]int(nil), middle...), p.End) {
+		arrival := departure + p.Cost[current][node]
+		if math.IsInf(arrival, 1) {
+			return nil, 0, false
+		}
+		begin, ok := EarliestBegin(arrival, p.Service[node], p.Windows[node])
+		if !ok {
+			return nil, 0, false
+		}
+		begins = append(begins, begin)
+		departure = begin + p.Service[node]
+		current = node
+	}
+	return begins, departure, true
+}
+
+// exact 動的計画法で訪問できる経由地点数が最大かつゴール到着が最も早い順序を求める
+// dp[mask][j] = maskに含まれる経由地点を全て時間枠内に訪問し、jを出発する最も早い時刻
+// 待ちが許される場合は早く出発できるほど有利なため、最早時刻だけを保持すればよい
+func (p TimeWindowProblem) exact(inner []int) []int {
+	k := len(inner)
+	if k == 0 {
+		return nil
+	}
+
+	startBegin, ok := EarliestBegin(p.StartTime, p.Service[p.Start], p.Windows[p.Start])
+	if !ok {
+		return nil
+	}
+	startDeparture := startBegin + p.Service[p.Start]
+
+	full := 1 << k
+	dp := make([][]float64, full)
+	parent := make([][]int, full)
+	for mask := range dp {
+		dp[mask] = make([]float64, k)
+		parent[mask] = make([]int, k)
+		for j := range dp[mask] {
+			dp[mask][j] = math.Inf(1)
+			parent[mask][j] = -1
+		}
+	}
+	for j := 0; j < k; j++ {
+		if begin, ok := EarliestBegin(startDeparture+p.Cost[p.Start][inner[j]], p.Service[inner[j]], p.Windows[inner[j]]); ok {
+			dp[1<<j][j] = begin + p.Service[inner[j]]
+		}
+	}
+
+	for mask := 1; mask < full; mask++ {
+		for j := 0; j < k; j++ {
+			if mask&(1<<j) == 0 || math.IsInf(dp[mask][j], 1) {
+				continue
+			}
+			for next := 0; next < k; next++ {
+				if mask&(1<<next) != 0 {
+					continue
+				}
+				arrival := dp[mask][j] + p.Cost[inner[j]][inner[next]]
+				begin, ok := EarliestBegin(arrival, p.Service[inner[next]], p.Windows[inner[next]])
+				if !ok {
+					continue
+				}
+				nextMask := mask | 1<<next
+				if departure := begin + p.Service[inner[next]]; departure < dp[nextMask][next] {
+					dp[nextMask][next] = departure
+					parent[nextMask][next] = j
+				}
+			}
+		}
+	}
+
+	// 訪問数が最も多く、ゴール地点での滞在を最も早く終えられる状態を選ぶ
+	bestMask, bestLast := 0, -1
+	bestCount := -1
+	bestFinish := math.Inf(1)
+	if _, finish, ok := p.evaluate(nil); ok {
+		bestCount, bestFinish = 0, finish
+	}
+	for mask := 1; mask < full; mask++ {
+		count := bits.OnesCount(uint(mask))
+		if count < bestCount {
+			continue
+		}
+		for j := 0; j < k; j++ {
+			if math.IsInf(dp[mask][j], 1) {
+				continue
+			}
+			// ゴール地点へ移動できない状態（+Inf）は訪問数が多くても選ばない
+			begin, ok := EarliestBegin(dp[mask][j]+p.Cost[inner[j]][p.End], p.Service[p.End], p.Windows[p.End])
+			if !ok || math.IsInf(begin, 1) {
+				continue
+			}
+			finish := begin + p.Service[p.End]
+			if count > bestCount || finish < bestFinish {
+				bestMask, bestLast, bestCount, bestFinish = mask, j, count, finish
+			}
+		}
+	}
+	if bestLast < 0 {
+		return nil
+	}
+
+	order := make([]int, bits.OnesCount(uint(bestMask)))
+	mask, last := bestMask, bestLast
+	for pos := len(order) - 1; pos >= 0; pos-- {
+		order[pos] = inner[last]
+		prev := parent[mask][last]
+		mask &^= 1 << last
+		last = prev
+	}
+	return order
+}
+
+// heuristic 時間枠の締め切りが早い地点から順に、ゴール到着が最も早くなる位置へ挿入する
+// 挿入できない地点は訪問不可とし、最後に移動近傍で改善する
+func (p TimeWindowProblem) heuristic(inner []int) []int {
+	// 時間枠を無視した最適順序の位置を同順位の比較に使う
+	base, _, err := SolvePath(p.Cost, p.Start, p.End)
+	if err != nil {
+		base = nil
+	}
+	position := make(map[int]int, len(base))
+	for i, node := range base {
+		position[node] = i
+	}
+
+	candidates := append([]int(nil), inner...)
+	deadline := func(node int) float64 {
+		latest := math.Inf(1)
+		if p.Windows[node] != nil {
+			latest = math.Inf(-1)
+			for _, w := range p.Windows[node] {
+				latest = math.Max(latest, w.Close)
+			}
+		}
+		return latest
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		da, db := deadline(candidates[a]), deadline(candidates[b])
+		if da != db {
+			return da < db
+		}
+		return position[candidates[a]] < position[candidates[b]]
+	})
+
+	var middle []int
+	var pending []int
+	for _, node := range candidates {
+		if next, ok := p.bestInsertion(middle, node); ok {
+			middle = next
+		} else {
+			pending = append(pending, node)
+		}
+	}
+
+	middle = p.relocate(middle)
+
+	// 改善後の順序に対して、挿入できなかった地点を再度試す
+	for _, node := range pending {
+		if next, ok := p.bestInsertion(middle, node); ok {
+			middle = next
+		}
+	}
+
+	// 時間枠を無視した順序がそのまま成立する場合は、より早く終わる方を採用する
+	if len(base) == len(inner)+2 {
+		baseMiddle := base[1 : len(base)-1]
+		if _, baseFinish, ok := p.evaluate(baseMiddle); ok {
+			if _, finish, ok := p.evaluate(middle); !ok || len(middle) < len(inner) || baseFinish < finish {
+				return p.relocate(append([]int(nil), baseMiddle...))
+			}
+		}
+	}
+	return middle
+}
+
+// bestInsertion middleのいずれかの位置にnodeを挿入し、時間枠を守れる中でゴール到着が最も早い列を返す
+func (p TimeWindowProblem) bestInsertion(middle []int, node int) ([]int, bool) {
+	var best []int
+	bestFinish := math.Inf(1)
+	for pos := 0; pos <= len(middle); pos++ {
+		candidate := make([]int, 0, len(middle)+1)
+		candidate = append(candidate, middle[:pos]...)
+		candidate = append(candidate, node)
+		candidate = append(candidate, middle[pos:]...)
+		if _, finish, ok := p.evaluate(candidate); ok && finish < bestFinish {
+			best, bestFinish = candidate, finish
+		}
+	}
+	return best, best != nil
+}
+
+// relocate 1地点ずつ別の位置へ移動し、時間枠を守ったままゴール到着が早くなる限り繰り返す
+func (p TimeWindowProblem) relocate(middle []int) []int {
+	best := middle
+	_, bestFinish, ok := p.evaluate(best)
+	if !ok {
+		return middle
+	}
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(best); i++ {
+			for pos := 0; pos < len(best); pos++ {
+				if pos == i {
+					continue
+				}
+				candidate := moveSegment(best, i, 1, pos)
+				if _, finish, ok := p.evaluate(candidate); ok && finish < bestFinish-1e-9 {
+					best, bestFinish, improved = candidate, finish, true
+				}
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,175 @@
+package solver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEarliestBegin(t *testing.T) {
+	tests := []struct {
+		name      string
+		arrival   float64
+		service   float64
+		windows   []Window
+		wantBegin float64
+		wantOK    bool
+	}{
+		{"制約なし", 100, 60, nil, 100, true},
+		{"終日訪問不可", 100, 60, []Window{}, 0, false},
+		{"時間枠内に到着", 100, 60, []Window{{0, 200}}, 100, true},
+		{"開始前に到着して待つ", 100, 60, []Window{{150, 300}}, 150, true},
+		{"滞在中に終了する", 100, 60, []Window{{0, 150}}, 0, false},
+		{"次の時間枠まで待つ", 100, 60, []Window{{0, 120}, {200, 300}}, 200, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			begin, ok := EarliestBegin(tt.arrival, tt.service, tt.windows)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && begin != tt.wantBegin {
+				t.Errorf("begin = %v, want %v", begin, tt.wantBegin)
+			}
+		})
+	}
+}
+
+func TestSolvePathWithTimeWindows(t *testing.T) {
+	// 0: 出発地点、1〜2: 経由地点、3: ゴール地点（移動はどこへも10秒）
+	uniform := [][]float64{
+		{0, 10, 10, 10},
+		{10, 0, 10, 10},
+		{10, 10, 0, 10},
+		{10, 10, 10, 0},
+	}
+	// 2から1への移動だけ遠回り
+	ordered := [][]float64{
+		{0, 10, 10, 10},
+		{10, 0, 10, 10},
+		{10, 20, 0, 10},
+		{10, 10, 10, 0},
+	}
+	// 1からゴール地点へは移動できず、1から2へも移動できない
+	unreachable := [][]float64{
+		{0, 10, 10, 10},
+		{10, 0, inf, inf},
+		{10, 10, 0, 10},
+		{10, 10, 10, 0},
+	}
+	service := []float64{0, 100, 100, 0}
+
+	tests := []struct {
+		name          string
+		cost          [][]float64
+		windows       [][]Window
+		wantPath      []int
+		wantUnvisited []int
+		wantFinish    float64
+	}{
+		{
+			name:       "制約なし",
+			cost:       ordered,
+			windows:    [][]Window{nil, nil, nil, nil},
+			wantPath:   []int{0, 1, 2, 3},
+			wantFinish: 230,
+		},
+		{
+			name:       "締め切りの早い地点を先に訪問する",
+			cost:       uniform,
+			windows:    [][]Window{nil, nil, {{0, 150}}, nil},
+			wantPath:   []int{0, 2, 1, 3},
+			wantFinish: 230,
+		},
+		{
+			name:       "開始前に着いた場合は待つ",
+			cost:       uniform,
+			windows:    [][]Window{nil, {{500, 1000}}, nil, nil},
+			wantPath:   []int{0, 2, 1, 3},
+			wantFinish: 610,
+		},
+		{
+			name:          "終日訪問できない地点は除く",
+			cost:          uniform,
+			windows:       [][]Window{nil, {}, nil, nil},
+			wantPath:      []int{0, 2, 3},
+			wantUnvisited: []int{1},
+			wantFinish:    120,
+		},
+		{
+			name:          "ゴール地点へ移動できない地点は訪問数が多くても選ばない",
+			cost:          unreachable,
+			windows:       [][]Window{nil, nil, nil, nil},
+			wantPath:      []int{0, 2, 3},
+			wantUnvisited: []int{1},
+			wantFinish:    120,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := SolvePathWithTimeWindows(TimeWindowProblem{
+				Cost:    tt.cost,
+				Service: service,
+				Windows: tt.windows,
+				Start:   0,
+				End:     3,
+			})
+			if err != nil {
+				t.Fatalf("SolvePathWithTimeWindows: %v", err)
+			}
+			if !reflect.DeepEqual(result.Path, tt.wantPath) {
+				t.Errorf("Path = %v, want %v", result.Path, tt.wantPath)
+			}
+			if !reflect.DeepEqual(result.Unvisited, tt.wantUnvisited) {
+				t.Errorf("Unvisited = %v, want %v", result.Unvisited, tt.wantUnvisited)
+			}
+			if result.FinishTime != tt.wantFinish {
+				t.Errorf("FinishTime = %v, want %v", result.FinishTime, tt.wantFinish)
+			}
+		})
+	}
+}
+
+func TestSolvePathWithTimeWindowsHeuristic(t *testing.T) {
+	xs := []float64{0, 7, 3, 15, 1, 11, 5, 13, 2, 9, 4, 14, 6, 12, 8, 10, 16}
+	n := len(xs)
+	windows := make([][]Window, n)
+	windows[3] = []Window{} // 終日訪問不可
+	result, err := SolvePathWithTimeWindows(TimeWindowProblem{
+		Cost:    linePoints(xs),
+		Service: make([]float64, n),
+		Windows: windows,
+		Start:   0,
+		End:     n - 1,
+	})
+	if err != nil {
+		t.Fatalf("SolvePathWithTimeWindows: %v", err)
+	}
+	if !reflect.DeepEqual(result.Unvisited, []int{3}) {
+		t.Errorf("Unvisited = %v, want [3]", result.Unvisited)
+	}
+	if len(result.Path) != n-1 || result.Path[0] != 0 || result.Path[len(result.Path)-1] != n-1 {
+		t.Errorf("Path = %v must visit all other %d nodes from 0 to %d", result.Path, n-1, n-1)
+	}
+	if result.FinishTime != 16 {
+		t.Errorf("FinishTime = %v, want 16", result.FinishTime)
+	}
+}
+
+func TestSolvePathWithTimeWindowsInvalid(t *testing.T) {
+	cost := [][]float64{{0, 1}, {1, 0}}
+	tests := []struct {
+		name string
+		p    TimeWindowProblem
+	}{
+		{"出発地点とゴール地点が同じ", TimeWindowProblem{Cost: cost, Service: []float64{0, 0}, Windows: make([][]Window, 2), Start: 0, End: 0}},
+		{"滞在時間の数が一致しない", TimeWindowProblem{Cost: cost, Service: []float64{0}, Windows: make([][]Window, 2), Start: 0, End: 1}},
+		{"ゴール地点が時間枠外", TimeWindowProblem{Cost: cost, Service: []float64{0, 0}, Windows: [][]Window{nil, {}}, Start: 0, End: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SolvePathWithTimeWindows(tt.p); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/pkg/apperror"
+	"fukuoka-ai-api/usecase/solver"
+	"time"
+)
+
+// jst 旅程の日付・営業時間の基準となるタイムゾーン（日本標準時、夏時間なし）
+var jst = time.FixedZone("Asia/Tokyo", 9*60*60)
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+// parseTripDate "YYYY-MM-DD"形式の旅行日を日本時間の0時として解釈（空の場合は今日）
+func parseTripDate(date string) (time.Time, error) {
+	if date == "" {
+		now := time.Now().In(jst)
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, jst), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", date, jst)
+	if err != nil {
+		return time.Time{}, apperror.New(apperror.ErrInvalidInput, "旅行日の形式が不正です (例: \"2025-04-01\"): %s", date).WithDetails("date")
+	}
+	return t, nil
+}
+
+// tripDepartureTime 旅行日（日本時間の0時）と開始時刻（0時からの秒数）から、ルート計算に渡す出発時刻を求める
+// 過去の時刻は交通状況を考慮したルート計算に使えないため、その場合は現在から1時間後とする
+func tripDepartureTime(tripDate time.Time, startSeconds int, now time.Time) time.Time {
+	departure := tripDate.Add(time.Duration(startSeconds) * time.Second)
+	if !departure.After(now) {
+		return now.Add(1 * time.Hour)
+	}
+	return departure
+}
+
+// openingWindows 営業時間から旅行日に滞在できる時間帯を求める（旅行日の0時からの秒数）
+// 営業時間の情報がない場合（営業時間帯が1つもない場合を含む）や24時間営業の場合はnil（制約なし）、定休日の場合は空のスライスを返す
+// 前日から続く深夜営業と、日付をまたいだ翌日の営業も含める
+func openingWindows(hours *models.OpeningHours, date time.Time) []solver.Window {
+	if hours == nil || hours.AlwaysOpen || len(hours.Periods) == 0 {
+		return nil
+	}
+
+	dayStart := int(date.Weekday()) * minutesPerDay
+	windows := []solver.Window{}
+	for _, period := range hours.Periods {
+		open := period.OpenDay*minutesPerDay + period.OpenMinute
+		closeAt := period.CloseDay*minutesPerDay + period.CloseMinute
+		if closeAt <= open {
+			// 土曜日から日曜日にまたがる場合など、週をまたぐ営業時間帯
+			closeAt += minutesPerWeek
+		}
+		for _, shift := range []int{-minutesPerWeek, 0, minutesPerWeek} {
+			start := open + shift - dayStart
+			end := closeAt + shift - dayStart
+			if end <= 0 || start >= 2*minutesPerDay {
+				continue
+			}
+			windows = append(windows, solver.Window{
+				Open:  float64(start * 60),
+				Close: float64(end * 60),
+			})
+		}
+	}
+	return windows
+}
+
+// isClosedAllDay 旅行日に営業していないか（前日からの深夜営業を除く）
+func isClosedAllDay(windows []solver.Window) bool {
+	if windows == nil {
+		return false
+	}
+	for _, w := range windows {
+		if w.Close > 0 && w.Open < minutesPerDay*60 {
+			return false
+		}
+	}
+	return true
+}
+
+// estimatedSpeed ルート行列が取得できない場合に直線距離から所要時間を見積もる速度（メートル/秒）
+func estimatedSpeed(travelMode string) float64 {
+	switch travelMode {
+	case service.TravelModeWalk:
+		return 4.0 * 1000 / 3600
+	case service.TravelModeBicycle:
+		return 12.0 * 1000 / 3600
+	case service.TravelModeTransit:
+		return 20.0 * 1000 / 3600
+	default:
+		return 25.0 * 1000 / 3600
+	}
+}
+
+// scheduleWaits 実際の区間の所要時間で旅程をたどり、各スポットで営業開始を待つ時間（分）を求める
+// 滞在中に閉店してしまうスポットのインデックスをlateとして返す（待ち時間は0とする）
+func scheduleWaits(startSeconds int, stayMinutes []int, legs []models.RouteLeg, windows [][]solver.Window) (waits []int, late []int) {
+	waits = make([]int, len(stayMinutes))
+	current := float64(startSeconds)
+	for i, stay := range stayMinutes {
+		if i > 0 && i-1 < len(legs) {
+			current += float64(parseRouteDuration(legs[i-1].Duration))
+		}
+		staySeconds := float64(stay * 60)
+		if begin, ok := solver.EarliestBegin(current, staySeconds, windows[i]); ok {
+			waits[i] = int(begin-current+59) / 60
+			current += float64(waits[i] * 60)
+		} else {
+			late = append(late, i)
+		}
+		current += staySeconds
+	}
+	return waits, late
+}
@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/usecase/solver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 曜日（0=日曜日〜6=土曜日）と時刻（時・分）から営業時間帯を作成
+func period(openDay, openHour, openMinute, closeDay, closeHour, closeMinute int) models.OpeningPeriod {
+	return models.OpeningPeriod{
+		OpenDay:     openDay,
+		OpenMinute:  openHour*60 + openMinute,
+		CloseDay:    closeDay,
+		CloseMinute: closeHour*60 + closeMinute,
+	}
+}
+
+// hours 旅行日の0時からの時間（時）を秒に変換
+func hours(h float64) float64 {
+	return h * 3600
+}
+
+func TestOpeningWindows(t *testing.T) {
+	// 2025-04-04は金曜日、04-05は土曜日、04-06は日曜日
+	friday, _ := parseTripDate("2025-04-04")
+	saturday, _ := parseTripDate("2025-04-05")
+	sunday, _ := parseTripDate("2025-04-06")
+
+	overnight := &models.OpeningHours{Periods: []models.OpeningPeriod{period(5, 18, 0, 6, 2, 0)}}
+	weekWrap := &models.OpeningHours{Periods: []models.OpeningPeriod{period(6, 20, 0, 0, 3, 0)}}
+	weekdays := &models.OpeningHours{Periods: []models.OpeningPeriod{
+		period(1, 10, 0, 1, 17, 0),
+		period(2, 10, 0, 2, 17, 0),
+		period(3, 10, 0, 3, 17, 0),
+		period(4, 10, 0, 4, 17, 0),
+		period(5, 10, 0, 5, 17, 0),
+	}}
+
+	tests := []struct {
+		name       string
+		hours      *models.OpeningHours
+		date       time.Time
+		want       []solver.Window
+		wantClosed bool
+	}{
+		{
+			name:  "営業時間の情報なし",
+			hours: nil,
+			date:  saturday,
+			want:  nil,
+		},
+		{
+			name:  "営業時間帯なし",
+			hours: &models.OpeningHours{Periods: []models.OpeningPeriod{}},
+			date:  saturday,
+			want:  nil,
+		},
+		{
+			name:  "24時間営業",
+			hours: &models.OpeningHours{AlwaysOpen: true},
+			date:  saturday,
+			want:  nil,
+		},
+		{
+			name:  "営業日",
+			hours: weekdays,
+			date:  friday,
+			want:  []solver.Window{{Open: hours(10), Close: hours(17)}},
+		},
+		{
+			name:       "定休日",
+			hours:      weekdays,
+			date:       saturday,
+			want:       []solver.Window{},
+			wantClosed: true,
+		},
+		{
+			name:  "深夜営業の当日",
+			hours: overnight,
+			date:  friday,
+			want:  []solver.Window{{Open: hours(18), Close: hours(26)}},
+		},
+		{
+			name:  "前日から続く深夜営業",
+			hours: overnight,
+			date:  saturday,
+			want:  []solver.Window{{Open: hours(-6), Close: hours(2)}},
+		},
+		{
+			name:  "土曜日から日曜日にまたがる営業の土曜日",
+			hours: weekWrap,
+			date:  saturday,
+			want:  []solver.Window{{Open: hours(20), Close: hours(27)}},
+		},
+		{
+			name:  "土曜日から日曜日にまたがる営業の日曜日",
+			hours: weekWrap,
+			date:  sunday,
+			want:  []solver.Window{{Open: hours(-4), Close: hours(3)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := openingWindows(tt.hours, tt.date)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("openingWindows = %v, want %v", got, tt.want)
+			}
+			if closed := isClosedAllDay(got); closed != tt.wantClosed {
+				t.Errorf("isClosedAllDay = %v, want %v", closed, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func TestScheduleWaits(t *testing.T) {
+	leg := func(d string) models.RouteLeg { return models.RouteLeg{Duration: d} }
+	tests := []struct {
+		name      string
+		start     int
+		stay      []int
+		legs      []models.RouteLeg
+		windows   [][]solver.Window
+		wantWaits []int
+		wantLate  []int
+	}{
+		{
+			name:      "制約なし",
+			start:     int(hours(10)),
+			stay:      []int{0, 60, 0},
+			legs:      []models.RouteLeg{leg("600s"), leg("600s")},
+			windows:   [][]solver.Window{nil, nil, nil},
+			wantWaits: []int{0, 0, 0},
+		},
+		{
+			name:      "営業開始まで待つ",
+			start:     int(hours(9)),
+			stay:      []int{0, 60, 0},
+			legs:      []models.RouteLeg{leg("1800s"), leg("600s")},
+			windows:   [][]solver.Window{nil, {{Open: hours(10), Close: hours(17)}}, nil},
+			wantWaits: []int{0, 30, 0},
+		},
+		{
+			name:      "待ち時間は分単位で切り上げる",
+			start:     int(hours(9)),
+			stay:      []int{0, 60, 0},
+			legs:      []models.RouteLeg{leg("3570s"), leg("600s")},
+			windows:   [][]solver.Window{nil, {{Open: hours(10), Close: hours(17)}}, nil},
+			wantWaits: []int{0, 1, 0},
+		},
+		{
+			name:      "待ち時間が後のスポットの到着時刻に反映される",
+			start:     int(hours(9)),
+			stay:      []int{0, 60, 60, 0},
+			legs:      []models.RouteLeg{leg("0s"), leg("0s"), leg("0s")},
+			windows:   [][]solver.Window{nil, {{Open: hours(10), Close: hours(17)}}, {{Open: hours(11), Close: hours(12)}}, nil},
+			wantWaits: []int{0, 60, 0, 0},
+		},
+		{
+			name:      "滞在中に閉店する",
+			start:     int(hours(16)),
+			stay:      []int{0, 60, 0},
+			legs:      []models.RouteLeg{leg("1800s"), leg("600s")},
+			windows:   [][]solver.Window{nil, {{Open: hours(10), Close: hours(17)}}, nil},
+			wantWaits: []int{0, 0, 0},
+			wantLate:  []int{1},
+		},
+		{
+			name:      "定休日",
+			start:     int(hours(10)),
+			stay:      []int{0, 60, 0},
+			legs:      []models.RouteLeg{leg("600s"), leg("600s")},
+			windows:   [][]solver.Window{nil, {}, nil},
+			wantWaits: []int{0, 0, 0},
+			wantLate:  []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits, late := scheduleWaits(tt.start, tt.stay, tt.legs, tt.windows)
+			if !reflect.DeepEqual(waits, tt.wantWaits) {
+				t.Errorf("waits = %v, want %v", waits, tt.wantWaits)
+			}
+			if !reflect.DeepEqual(late, tt.wantLate) {
+				t.Errorf("late = %v, want %v", late, tt.wantLate)
+			}
+		})
+	}
+}
+
+func TestTripDepartureTime(t *testing.T) {
+	date, _ := parseTripDate("2025-04-05")
+	tenAM := int(hours(10))
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"旅行日が未来", time.Date(2025, 4, 1, 12, 0, 0, 0, jst), time.Date(2025, 4, 5, 10, 0, 0, 0, jst)},
+		{"開始時刻が過ぎている", time.Date(2025, 4, 5, 11, 0, 0, 0, jst), time.Date(2025, 4, 5, 12, 0, 0, 0, jst)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tripDepartureTime(date, tenAM, tt.now); !got.Equal(tt.want) {
+				t.Errorf("tripDepartureTime = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,441 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fukuoka-ai-api/infra/repository"
+	"fukuoka-ai-api/infra/service"
+	"fukuoka-ai-api/models"
+	"fukuoka-ai-api/pkg/apperror"
+	"fukuoka-ai-api/usecase/solver"
+	"strings"
+	"time"
+)
+
+// ErrForbidden 旅程の所有者以外による操作
+var ErrForbidden = errors.New("forbidden")
+
+// ITripUsecase 保存済み旅程のユースケースインターフェース
+type ITripUsecase interface {
+	CreateTrip(ctx context.Context, req *models.CreateTripRequest) (*models.CreateTripResponse, error)
+	GetTrip(tripID string, userID string) (*models.TripResponse, error)
+	RecomputeTrip(ctx context.Context, tripID string, req *models.RecomputeRequest) (*models.RecomputeResponse, error)
+}
+
+// TripUsecase 保存済み旅程のユースケース実装
+type TripUsecase struct {
+	tripRepository      repository.ITripRepository
+	recommendUsecase    IRecommendUsecase
+	resultUsecase       IResultUsecase
+	routeService        service.IRouteService
+	placeDetailsService service.IPlaceDetailsService
+}
+
+// NewTripUsecase 新しいTripUsecaseを作成
+func NewTripUsecase(
+	tripRepository repository.ITripRepository,
+	recommendUsecase IRecommendUsecase,
+	resultUsecase IResultUsecase,
+	routeService service.IRouteService,
+	placeDetailsService service.IPlaceDetailsService,
+) ITripUsecase {
+	return &TripUsecase{
+		tripRepository:      tripRepository,
+		recommendUsecase:    recommendUsecase,
+		resultUsecase:       resultUsecase,
+		routeService:        routeService,
+		placeDetailsService: placeDetailsService,
+	}
+}
+
+// CreateTrip リコメンドとルート計算を1回の呼び出しで行い、旅程を生成する
+// 1. RecommendUsecaseで候補を検索し、出発地点・寄りたい場所・ゴール地点を旅程として保存
+// 2. 保存したスポットをResultUsecaseに渡して順序を最適化し、旅程を更新
+// 3. 共有IDを発行
+// リコメンド・ルート計算の一部の処理に失敗した場合は、レスポンスとともにその失敗（apperror.ErrPartialResult）を返す
+func (u *TripUsecase) CreateTrip(ctx context.Context, req *models.CreateTripRequest) (*models.CreateTripResponse, error) {
+	if req.StartTime != "" {
+		if _, err := parseClock(req.StartTime); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := parseTripDate(req.Date); err != nil {
+		return nil, err
+	}
+
+	recommendResp, err := u.recommendUsecase.Recommend(ctx, &models.RecommendRequest{
+		MustPlaces:   req.MustPlaces,
+		InterestTags: req.InterestTags,
+		FreeText:     req.FreeText,
+		StartPlace:   req.StartPlace,
+		GoalPlace:    req.GoalPlace,
+		SearchPages:  req.SearchPages,
+		TravelMode:   req.TravelMode,
+		StartTime:    req.StartTime,
+		Date:         req.Date,
+		UserID:       req.UserID,
+	})
+	if err != nil && !apperror.IsPartial(err) {
+		return nil, err
+	}
+	// 一部の処理の失敗は旅程の生成を続け、レスポンスとともに返す
+	partials := []error{err}
+	tripID := recommendResp.TripID
+
+	savedPlaces, err := u.tripRepository.ListTripPlaces(tripID)
+	if err != nil {
+		return nil, fmt.Errorf("旅程のスポット取得に失敗しました (trip_id: %s): %w", tripID, err)
+	}
+
+	// 出発地点 → 寄りたい場所 → ゴール地点の順にplace_idを並べる
+	// ゴール地点が保存されていない場合は出発地点に戻る
+	var startPlaceID, goalPlaceID string
+	var mustPlaceIDs []string
+	for _, p := range savedPlaces {
+		switch p.Kind {
+		case models.TripPlaceKindStart:
+			startPlaceID = p.PlaceID
+		case models.TripPlaceKindGoal:
+			goalPlaceID = p.PlaceID
+		default:
+			mustPlaceIDs = append(mustPlaceIDs, p.PlaceID)
+		}
+	}
+	if startPlaceID == "" {
+		return nil, apperror.New(apperror.ErrPlaceNotFound, "出発地点の座標取得に失敗しました").WithCode("GEOCODING_ERROR").WithDetails("start_place")
+	}
+	if goalPlaceID == "" {
+		goalPlaceID = startPlaceID
+	}
+	placeIDs := append([]string{startPlaceID}, mustPlaceIDs...)
+	placeIDs = append(placeIDs, goalPlaceID)
+
+	resultResp, err := u.resultUsecase.ComputeOptimizedRoute(ctx, &models.ResultRequest{
+		Places:     placeIDs,
+		TripID:     tripID,
+		UserID:     req.UserID,
+		StartTime:  req.StartTime,
+		TravelMode: req.TravelMode,
+		Date:       req.Date,
+	})
+	if err != nil && !apperror.IsPartial(err) {
+		return nil, err
+	}
+	partials = append(partials, err)
+
+	itinerary, err := u.tripRepository.ListTripPlaces(tripID)
+	if err != nil {
+		return nil, fmt.Errorf("旅程のスポット取得に失敗しました (trip_id: %s): %w", tripID, err)
+	}
+	if _, err := applySchedule(resultResp.StartTime, itinerary, &resultResp.Route); err != nil {
+		return nil, err
+	}
+
+	// 共有はユーザーに紐づく旅程のみ（所有者がいない旅程の共有は停止できないため）
+	var shareID string
+	if req.UserID != "" {
+		share, err := u.ensureShare(tripID)
+		if err != nil {
+			return nil, err
+		}
+		shareID = share.ShareID
+	}
+
+	candidates := recommendResp.Places
+	if candidates == nil {
+		candidates = []models.Place{}
+	}
+
+	return &models.CreateTripResponse{
+		TripID:      tripID,
+		ShareID:     shareID,
+		Itinerary:   itinerary,
+		Candidates:  candidates,
+		Route:       resultResp.Route,
+		StartTime:   resultResp.StartTime,
+		EndTime:     resultResp.EndTime,
+		Unvisitable: resultResp.Unvisitable,
+	}, errors.Join(partials...)
+}
+
+// ensureShare 旅程の共有情報を取得し、存在しない場合は作成する
+func (u *TripUsecase) ensureShare(tripID string) (*models.Share, error) {
+	share, err := u.tripRepository.GetShareByTripID(tripID)
+	if err == nil {
+		return share, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("共有情報の取得に失敗しました: %w", err)
+	}
+
+	share = &models.Share{TripID: tripID}
+	if err := u.tripRepository.CreateShare(share); err != nil {
+		return nil, fmt.Errorf("共有情報の保存に失敗しました: %w", err)
+	}
+	return share, nil
+}
+
+// GetTrip 保存済みの旅程と順序付きのスポット一覧を取得
+// ユーザーに紐づく旅程は所有者のみ取得できる
+func (u *TripUsecase) GetTrip(tripID string, userID string) (*models.TripResponse, error) {
+	trip, err := authorizeTrip(u.tripRepository, tripID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	places, err := u.tripRepository.ListTripPlaces(tripID)
+	if err != nil {
+		return nil, fmt.Errorf("旅程のスポット取得に失敗しました (trip_id: %s): %w", tripID, err)
+	}
+
+	route, err := u.tripRepository.GetTripRoute(tripID)
+	if err != nil {
+		return nil, fmt.Errorf("旅程のルート取得に失敗しました (trip_id: %s): %w", tripID, err)
+	}
+	decodeRoutePaths(route)
+	endTime, err := applySchedule(trip.StartTime, places, route)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TripResponse{
+		Trip:      *trip,
+		Itinerary: places,
+		Route:     route,
+		EndTime:   endTime,
+	}, nil
+}
+
+// RecomputeTrip ユーザーが指定した順序・滞在時間で旅程を再計算する
+// 経由地順の最適化は行わず、ordered_place_idsの順序をそのまま維持する
+// ordered_place_idsに含まれないスポットは旅程から除外する
+// 旅行日の営業時間から各スポットの待ち時間を求め、その順序では営業時間内に滞在できないスポットを併せて返す
+// 営業時間を取得できなかったスポットがある場合は、レスポンスとともにその失敗（apperror.ErrPartialResult）を返す
+func (u *TripUsecase) RecomputeTrip(ctx context.Context, tripID string, req *models.RecomputeRequest) (*models.RecomputeResponse, error) {
+	trip, err := authorizeTrip(u.tripRepository, tripID, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := trip.StartTime
+	if req.StartTime != "" {
+		startTime = req.StartTime
+	}
+	startSeconds, err := parseClock(startTime)
+	if err != nil {
+		return nil, err
+	}
+	date := trip.Date
+	if req.Date != "" {
+		date = req.Date
+	}
+	tripDate, err := parseTripDate(date)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.OrderedPlaceIDs) < 2 {
+		return nil, apperror.New(apperror.ErrInvalidInput, "ルート計算には最低2つの場所が必要です").WithDetails("ordered_place_ids")
+	}
+
+	saved, err := u.tripRepository.ListTripPlaces(tripID)
+	if err != nil {
+		return nil, fmt.Errorf("旅程のスポット取得に失敗しました (trip_id: %s): %w", tripID, err)
+	}
+	// 移動手段は省略時、前回計算したルートと同じものを使用
+	travelMode := req.TravelMode
+	if travelMode == "" {
+		if savedRoute, err := u.tripRepository.GetTripRoute(tripID); err == nil && savedRoute != nil {
+			travelMode = savedRoute.TravelMode
+		}
+	}
+	travelMode, err = service.NormalizeTravelMode(travelMode)
+	if err != nil {
+		return nil, apperror.New(apperror.ErrInvalidInput, "移動手段が不正です: %s", req.TravelMode).WithDetails("travel_mode")
+	}
+
+	savedByID := make(map[string]models.TripPlace, len(saved))
+	for _, p := range saved {
+		savedByID[p.ID] = p
+	}
+
+	for id, minutes := range req.StayMinutesMap {
+		if _, ok := savedByID[id]; !ok {
+			return nil, apperror.New(apperror.ErrInvalidInput, "旅程に含まれないスポットの滞在時間が指定されています (id: %s)", id).WithDetails("stay_minutes_map")
+		}
+		if minutes < 0 {
+			return nil, apperror.New(apperror.ErrInvalidInput, "滞在時間は0以上で指定してください (id: %s)", id).WithDetails("stay_minutes_map")
+		}
+	}
+
+	// 指定された順序でスポットを並べ、滞在時間を反映
+	seen := make(map[string]bool, len(req.OrderedPlaceIDs))
+	itinerary := make([]models.TripPlace, 0, len(req.OrderedPlaceIDs))
+	for _, id := range req.OrderedPlaceIDs {
+		p, ok := savedByID[id]
+		if !ok {
+			return nil, apperror.New(apperror.ErrInvalidInput, "旅程に含まれないスポットが指定されています (id: %s)", id).WithDetails("ordered_place_ids")
+		}
+		if seen[id] {
+			return nil, apperror.New(apperror.ErrInvalidInput, "同じスポットが複数回指定されています (id: %s)", id).WithDetails("ordered_place_ids")
+		}
+		seen[id] = true
+		// 訪問できずに除外していたスポットも、指定された場合は訪問するスポットとして扱う
+		p.UnvisitableReason = ""
+		if minutes, ok := req.StayMinutesMap[id]; ok {
+			p.StayMinutes = minutes
+		}
+		itinerary = append(itinerary, p)
+	}
+
+	windows, weekdayTexts, partial := u.loadOpeningWindows(ctx, itinerary, tripDate)
+	if partial != nil && !apperror.IsPartial(partial) {
+		return nil, partial
+	}
+
+	// 経由地順を最適化せずにルートを計算
+	origin := itinerary[0]
+	destination := itinerary[len(itinerary)-1]
+	var intermediates []service.Waypoint
+	for _, p := range itinerary[1 : len(itinerary)-1] {
+		intermediates = append(intermediates, service.Waypoint{
+			PlaceID:     p.PlaceID,
+			Lat:         p.Lat,
+			Lng:         p.Lng,
+			StayMinutes: p.StayMinutes,
+		})
+	}
+
+	// 営業時間の判定と同じ旅行日・開始時刻の交通状況で所要時間を求める
+	departureTime := tripDepartureTime(tripDate, startSeconds, time.Now())
+	routeResp, err := u.routeService.ComputeRoute(
+		ctx,
+		origin.Lat, origin.Lng,
+		destination.Lat, destination.Lng,
+		intermediates,
+		false,
+		travelMode,
+		&departureTime,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ルート計算に失敗しました: %w", err)
+	}
+	if len(routeResp.Routes) == 0 {
+		return nil, errNoRoutes()
+	}
+	route := buildRoute(ctx, routeResp, len(intermediates))
+	route.TravelMode = travelMode
+
+	// 指定された順序のまま営業開始までの待ち時間を求め、滞在中に閉店する（定休日を含む）スポットを返す
+	stayMinutes := make([]int, len(itinerary))
+	for i, p := range itinerary {
+		stayMinutes[i] = p.StayMinutes
+	}
+	waits, late := scheduleWaits(startSeconds, stayMinutes, route.Legs, windows)
+	for _, wait := range waits {
+		if wait > 0 {
+			route.WaitMinutes = waits
+			break
+		}
+	}
+	unvisitable := make([]models.UnvisitablePlace, 0, len(late))
+	for _, idx := range late {
+		reason := models.UnvisitableReasonClosesBeforeStay
+		if isClosedAllDay(windows[idx]) {
+			reason = models.UnvisitableReasonClosed
+		}
+		unvisitable = append(unvisitable, models.UnvisitablePlace{
+			ID:           itinerary[idx].ID,
+			PlaceID:      itinerary[idx].PlaceID,
+			Name:         itinerary[idx].Name,
+			Reason:       reason,
+			OpeningHours: weekdayTexts[idx],
+		})
+	}
+
+	if err := u.tripRepository.ReplaceTripPlaces(tripID, itinerary); err != nil {
+		return nil, fmt.Errorf("旅程の保存に失敗しました: %w", err)
+	}
+	if err := u.tripRepository.SaveTripRoute(tripID, &route); err != nil {
+		return nil, fmt.Errorf("ルートの保存に失敗しました: %w", err)
+	}
+	if startTime != trip.StartTime {
+		if err := u.tripRepository.UpdateTripStartTime(tripID, startTime); err != nil {
+			return nil, fmt.Errorf("旅程の保存に失敗しました: %w", err)
+		}
+	}
+	if date != trip.Date {
+		if err := u.tripRepository.UpdateTripDate(tripID, date); err != nil {
+			return nil, fmt.Errorf("旅程の保存に失敗しました: %w", err)
+		}
+	}
+
+	endTime, err := applySchedule(startTime, itinerary, &route)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RecomputeResponse{
+		Itinerary:   itinerary,
+		Route:       route,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Unvisitable: unvisitable,
+	}, partial
+}
+
+// loadOpeningWindows 旅程の各スポットの営業時間から、旅行日に滞在できる時間帯と表示用の営業時間を取得する
+// 出発地点・ゴール地点は営業時間の制約を受けない
+// 営業時間を取得できなかったスポットは制約なしとし、その失敗（apperror.ErrPartialResult）を返す
+// 呼び出し元のcontextがキャンセルされた場合はエラーを返す
+func (u *TripUsecase) loadOpeningWindows(ctx context.Context, itinerary []models.TripPlace, tripDate time.Time) ([][]solver.Window, [][]string, error) {
+	windows := make([][]solver.Window, len(itinerary))
+	weekdayTexts := make([][]string, len(itinerary))
+	var errs []error
+	for i := 1; i < len(itinerary)-1; i++ {
+		details, err := u.placeDetailsService.GetPlaceDetails(ctx, itinerary[i].PlaceID, "", service.PlaceDetailsFieldsWithOpeningHours)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, nil, fmt.Errorf("営業時間の取得を中断しました: %w", ctxErr)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		if details.OpeningHours != nil {
+			weekdayTexts[i] = details.OpeningHours.WeekdayText
+			windows[i] = openingWindows(details.OpeningHours, tripDate)
+		}
+	}
+	if len(errs) > 0 {
+		return windows, weekdayTexts, apperror.Partial("OPENING_HOURS_UNAVAILABLE", errs[0], "%d/%d件のスポットで営業時間を取得できなかったため、営業時間を考慮せずに計算しました", len(errs), len(itinerary)-2)
+	}
+	return windows, weekdayTexts, nil
+}
+
+// authorizeTrip 旅程の所有者であることを確認して旅程を返す
+// 匿名で作成された旅程（user_idなし）は誰でも操作できる
+func authorizeTrip(tripRepository repository.ITripRepository, tripID string, userID string) (*models.Trip, error) {
+	trip, err := tripRepository.GetTrip(tripID)
+	if err != nil {
+		return nil, fmt.Errorf("旅程の取得に失敗しました (trip_id: %s): %w", tripID, err)
+	}
+	if trip.UserID != "" && trip.UserID != userID {
+		return nil, fmt.Errorf("この旅程を操作する権限がありません: %w", ErrForbidden)
+	}
+	return trip, nil
+}
+
+// buildTripTitle スポット名から旅程タイトルを生成（例: "太宰府天満宮・糸島"）
+func buildTripTitle(names []string) string {
+	var parts []string
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			parts = append(parts, name)
+		}
+	}
+	if len(parts) == 0 {
+		return "福岡の旅"
+	}
+	return strings.Join(parts, "・")
+}
@@ -33,6 +33,18 @@ class RecommendRequest(BaseModel):
     free_text: Optional[str] = None
 
 
+class InterestTag(BaseModel):
+    id: str
+    label: str = ""
+
+
+class ExtractInterestsRequest(BaseModel):
+    text: str
+    tags: List[InterestTag]
+    max_tags: int = 3
+    max_keywords: int = 2
+
+
 class RecomputeRouteRequest(BaseModel):
     start: str
     waypoints: List[Dict[str, float]]
@@ -122,6 +134,26 @@ async def recommend(request: RecommendRequest):
         raise HTTPException(status_code=500, detail=str(e))
 
 
+@app.post("/extract-interests")
+async def extract_interests(request: ExtractInterestsRequest):
+    """自由記述から興味タグと検索キーワードを推定する（APIサーバーのfree_textの解釈に使用）
+
+    推定できない場合は503を返し、APIサーバーは辞書による推定に切り替える
+    """
+    result = await llm_service.extract_interests(
+        text=request.text,
+        tags=[t.dict() for t in request.tags],
+        max_tags=request.max_tags,
+        max_keywords=request.max_keywords
+    )
+    if result is None:
+        raise HTTPException(
+            status_code=503,
+            detail="興味を推定できませんでした"
+        )
+    return result
+
+
 @app.post("/recompute-route")
 async def recompute_route(request: RecomputeRouteRequest):
     try:
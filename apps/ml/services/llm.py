@@ -1,5 +1,5 @@
 import os
-from typing import List, Dict, Any
+from typing import List, Dict, Any, Optional
 from openai import OpenAI
 
 OPENAI_API_KEY = os.getenv("OPENAI_API_KEY", "")
@@ -112,3 +112,59 @@ JSON形式で返してください。各スポットについて以下の形式:
             ]
 
 
+
+    async def extract_interests(
+        self,
+        text: str,
+        tags: List[Dict[str, str]],
+        max_tags: int = 3,
+        max_keywords: int = 2
+    ) -> Optional[Dict[str, Any]]:
+        """自由記述から興味タグ（tagsのid）と重み、追加の検索キーワードを推定する
+
+        推定できない場合（APIキーがない、LLMのエラー、パース失敗）はNoneを返す
+        """
+        if not self.client:
+            return None
+
+        tag_list = "\n".join([f"- {t.get('id', '')}: {t.get('label', '')}" for t in tags])
+        prompt = f"""福岡観光のユーザーの希望から、興味タグと検索キーワードを推定してください。
+
+ユーザーの希望: {text}
+
+興味タグの候補（id: 表示名）:
+{tag_list}
+
+以下のJSON形式で返してください。
+{{
+  "tags": [{{"id": "候補のid", "weight": 0〜1の重み（希望との関連の強さ）}}],
+  "keywords": ["興味タグでは表せない検索キーワード（例: 古民家）"]
+}}
+tagsは最大{max_tags}件、keywordsは最大{max_keywords}件とし、候補にないidは使わないでください。
+"""
+
+        try:
+            response = self.client.chat.completions.create(
+                model=self.model,
+                messages=[
+                    {"role": "system", "content": "あなたは福岡観光の専門家です。JSONのみを返してください。"},
+                    {"role": "user", "content": prompt}
+                ],
+                temperature=0,
+                max_tokens=300,
+            )
+
+            content = response.choices[0].message.content
+            import json
+            start = content.find('{')
+            end = content.rfind('}') + 1
+            if start < 0 or end <= start:
+                return None
+            result = json.loads(content[start:end])
+            return {
+                "tags": result.get("tags", [])[:max_tags],
+                "keywords": result.get("keywords", [])[:max_keywords],
+            }
+        except Exception as e:
+            print(f"LLM error: {e}")
+            return None
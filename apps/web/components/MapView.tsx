@@ -11,6 +11,8 @@ interface RouteLeg {
   end_location: { lat: number; lng: number }
   distance_meters: number
   duration: string
+  polyline?: string
+  path?: Array<{ lat: number; lng: number }>
 }
 
 interface Route {
@@ -18,6 +20,8 @@ interface Route {
   distance_meters: number
   duration: string
   optimized_order: number[]
+  polyline?: string
+  path?: Array<{ lat: number; lng: number }>
 }
 
 interface MapViewProps {
@@ -53,9 +57,12 @@ export default function MapView({ itinerary, route }: MapViewProps) {
     }
   }
 
-  // ルートの経路を生成（各legのstart_locationとend_locationを結ぶ）
+  // ルートの経路を生成（APIがデコード済みの経路を返す場合はそれを使い、
+  // ない場合は各legのstart_locationとend_locationを直線で結ぶ）
   const routePath: Array<{ lat: number; lng: number }> = []
-  if (route?.legs && route.legs.length > 0) {
+  if (route?.path && route.path.length > 0) {
+    routePath.push(...route.path)
+  } else if (route?.legs && route.legs.length > 0) {
     route.legs.forEach((leg, index) => {
       if (index === 0) {
         routePath.push(leg.start_location)
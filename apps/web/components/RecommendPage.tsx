@@ -67,6 +67,7 @@ export default function RecommendPage() {
           interest_tags: data.interest_tags,
           start_place: data.start_place,
           goal_place: data.goal_place,
+          free_text: data.free_text,
         }),
       })
 
@@ -89,6 +90,7 @@ export default function RecommendPage() {
           category: place.category,
           address: place.address,
           relevance_score: place.relevance_score,
+          detour: place.detour,
         }))
 
       setRecommendedPlaces(places)
@@ -404,6 +406,11 @@ export default function RecommendPage() {
                             ⭐ {place.rating}
                           </span>
                         )}
+                        {place.detour && (
+                          <span className="px-2 py-1 bg-gray-100 text-xs text-gray-700 rounded">
+                            寄り道 {place.detour.source === 'haversine' ? '約' : ''}+{Math.max(1, Math.round(place.detour.duration_seconds / 60))}分
+                          </span>
+                        )}
                       </div>
                     </div>
 
@@ -1,7 +1,7 @@
 'use client'
 
 // @ts-ignore - モジュールは存在するが、型定義が見つからない場合がある
-import { useState, type ChangeEvent } from 'react'
+import { useEffect, useState, type ChangeEvent } from 'react'
 // @ts-ignore
 import { useForm } from 'react-hook-form'
 // @ts-ignore
@@ -11,15 +11,12 @@ import { z } from 'zod'
 // @ts-ignore
 import { Card } from 'react-bootstrap'
 
-const interestTags = [
-  'カフェ',
-  '屋台',
-  '景色',
-  '寺社',
-  '買い物',
-  'グルメ',
-  '自然',
-]
+// 興味タグ（GET /tags のレスポンス）
+// 選択肢はバックエンドのタグ定義から取得し、リクエストにはタグのidを送る
+interface InterestTag {
+  id: string
+  labels: { ja: string; en: string }
+}
 
 const schema = z.object({
   must_places: z.array(z.string())
@@ -45,6 +42,24 @@ interface TripFormProps {
 
 export default function TripForm({ onSubmit, loading }: TripFormProps) {
   const [mustPlaces, setMustPlaces] = useState<string[]>([''])
+  const [interestTags, setInterestTags] = useState<InterestTag[]>([])
+  const [tagsError, setTagsError] = useState<string | null>(null)
+
+  useEffect(() => {
+    const apiUrl = (typeof process !== 'undefined' && process.env?.NEXT_PUBLIC_API_URL) || 'http://localhost:8080'
+    fetch(`${apiUrl}/tags`)
+      .then((response) => {
+        if (!response.ok) {
+          throw new Error(`HTTP ${response.status}`)
+        }
+        return response.json()
+      })
+      .then((data: { tags: InterestTag[] }) => setInterestTags(data.tags || []))
+      .catch((err) => {
+        console.error('Failed to load interest tags:', err)
+        setTagsError('タグの一覧を取得できませんでした')
+      })
+  }, [])
 
   const {
     register,
@@ -212,19 +227,24 @@ export default function TripForm({ onSubmit, loading }: TripFormProps) {
           <div className="d-flex flex-wrap gap-2 justify-content-center w-100">
             {interestTags.map((tag) => (
               <button
-                key={tag}
+                key={tag.id}
                 type="button"
-                onClick={() => toggleTag(tag)}
+                onClick={() => toggleTag(tag.id)}
                 className={`px-4 py-2 rounded border transition-colors duration-200 text-base ${
-                  selectedTags.includes(tag)
+                  selectedTags.includes(tag.id)
                     ? 'bg-blue-600 text-white border-blue-600'
                     : 'bg-white text-gray-700 border-gray-300 hover:bg-gray-100'
                 }`}
               >
-                {tag}
+                {tag.labels.ja}
               </button>
             ))}
           </div>
+          {tagsError && (
+            <p className="text-sm mt-2 text-red-600 text-center w-100">
+              {tagsError}
+            </p>
+          )}
           {errors.interest_tags && (
             <p className="text-sm mt-2 text-red-600 text-center w-100">
               少なくとも1つのタグを選択してください
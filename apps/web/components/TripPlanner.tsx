@@ -24,6 +24,13 @@ export interface Place {
   address?: string
   travel_time_from_previous?: string // 前の地点からの移動時間
   relevance_score?: number // 関連性スコア
+  detour?: Detour // 旅程の地点の間に立ち寄る場合の寄り道（リコメンド時のみ）
+}
+
+export interface Detour {
+  distance_meters: number // 増える移動距離（メートル）
+  duration_seconds: number // 増える所要時間（秒）
+  source: 'haversine' | 'road' // haversine: 直線距離 / road: 道路の距離
 }
 
 export interface Route {
@@ -74,6 +81,7 @@ export default function TripPlanner() {
           interest_tags: data.interest_tags,
           start_place: data.start_place,
           goal_place: data.goal_place,
+          free_text: data.free_text,
         }),
       })
 